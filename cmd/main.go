@@ -11,11 +11,16 @@ import (
 
 	"cinema-booking-system/internal/config"
 	"cinema-booking-system/internal/database"
+	"cinema-booking-system/internal/gateway"
 	"cinema-booking-system/internal/handler"
+	"cinema-booking-system/internal/i18n"
+	"cinema-booking-system/internal/mailer"
 	"cinema-booking-system/internal/middleware"
+	"cinema-booking-system/internal/openapi"
 	"cinema-booking-system/internal/repository"
 	"cinema-booking-system/internal/router"
 	"cinema-booking-system/internal/service"
+	"cinema-booking-system/internal/signing"
 	"cinema-booking-system/internal/utils"
 	"cinema-booking-system/pkg/logger"
 
@@ -54,38 +59,102 @@ func main() {
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
 	cinemaRepo := repository.NewCinemaRepository(db)
+	movieRepo := repository.NewMovieRepository(db)
+	showtimeRepo := repository.NewShowtimeRepository(db)
 	bookingRepo := repository.NewBookingRepository(db)
 	paymentRepo := repository.NewPaymentRepository(db)
+	idempotencyRepo := repository.NewIdempotencyRepository(db)
+	loginThrottleRepo := repository.NewLoginThrottleRepository(db)
+
+	// Initialize payment gateways
+	gateways := gateway.NewRegistry(
+		gateway.NewMockGateway(gateway.MockProvider, cfg.Payment.WebhookSecret, false),
+		gateway.NewMockGateway(gateway.MockAsyncProvider, cfg.Payment.WebhookSecret, true),
+	)
+
+	// Initialize mailer
+	mail, err := mailer.New(cfg, log)
+	if err != nil {
+		log.Fatal("Failed to initialize mailer", zap.Error(err))
+	}
+
+	// Initialize JWT signing keys
+	signingKeys, err := signing.Load(cfg.JWT)
+	if err != nil {
+		log.Fatal("Failed to load JWT signing keys", zap.Error(err))
+	}
+	if kid := signingKeys.ActiveKeyID(); kid != "" {
+		log.Info("Signing tokens with asymmetric key", zap.String("kid", kid))
+	} else {
+		log.Warn("Signing tokens with legacy HS256 secret; set JWT_SIGNING_KEYS_DIR to use asymmetric keys")
+	}
 
 	// Initialize services
-	authService := service.NewAuthService(userRepo, cfg, log)
+	authService := service.NewAuthService(userRepo, bookingRepo, loginThrottleRepo, signingKeys, mail, cfg, log)
 	cinemaService := service.NewCinemaService(cinemaRepo, log)
-	bookingService := service.NewBookingService(bookingRepo, cinemaRepo, paymentRepo, log)
+	movieService := service.NewMovieService(movieRepo, showtimeRepo, log)
+	showtimeService := service.NewShowtimeService(showtimeRepo, movieRepo, cinemaRepo, log)
+	bookingService := service.NewBookingService(bookingRepo, cinemaRepo, showtimeRepo, paymentRepo, gateways, cfg, log)
 	paymentService := service.NewPaymentService(paymentRepo, log)
+	userService := service.NewUserService(userRepo, cinemaRepo, loginThrottleRepo, log)
+	idempotencyService := service.NewIdempotencyService(idempotencyRepo, cfg, log)
 
 	// Initialize validator
-	validator := utils.NewValidator()
+	passwordPolicy, err := utils.LoadPasswordPolicy(cfg.Password)
+	if err != nil {
+		log.Fatal("Failed to load password policy", zap.Error(err))
+	}
+	log.Info("Password policy loaded", zap.Int("breached_passwords", passwordPolicy.BreachedCount()))
+	validator, err := utils.NewValidator(passwordPolicy, cfg.App.MaxBodyBytes)
+	if err != nil {
+		log.Fatal("Failed to initialize validator", zap.Error(err))
+	}
 
 	// Initialize handlers
 	authHandler := handler.NewAuthHandler(authService, validator, log)
-	cinemaHandler := handler.NewCinemaHandler(cinemaService, log)
+	cinemaHandler := handler.NewCinemaHandler(cinemaService, validator, log)
+	movieHandler := handler.NewMovieHandler(movieService, validator, log)
+	showtimeHandler := handler.NewShowtimeHandler(showtimeService, validator, log)
 	bookingHandler := handler.NewBookingHandler(bookingService, validator, log)
 	paymentHandler := handler.NewPaymentHandler(paymentService, log)
+	userHandler := handler.NewUserHandler(userService, validator, log)
+	docsHandler := handler.NewDocsHandler()
 
 	// Initialize middlewares
-	authMiddleware := middleware.NewAuthMiddleware(authService, log)
+	authMiddleware := middleware.NewAuthMiddleware(authService, cfg, log)
+	realIPMiddleware, err := middleware.NewRealIPMiddleware(cfg)
+	if err != nil {
+		log.Fatal("Failed to configure trusted proxies", zap.Error(err))
+	}
 	loggingMiddleware := middleware.NewLoggingMiddleware(log)
+	if !i18n.IsSupported(cfg.App.DefaultLocale) {
+		log.Fatal("Unsupported default locale", zap.String("locale", cfg.App.DefaultLocale))
+	}
+	localeMiddleware := middleware.NewLocaleMiddleware(cfg)
+	idempotencyMiddleware := middleware.NewIdempotencyMiddleware(idempotencyService, cfg, log)
 
 	// Setup router
 	r := router.SetupRouter(
 		authHandler,
 		cinemaHandler,
+		movieHandler,
+		showtimeHandler,
 		bookingHandler,
 		paymentHandler,
+		userHandler,
+		docsHandler,
 		authMiddleware,
+		realIPMiddleware,
 		loggingMiddleware,
+		localeMiddleware,
+		idempotencyMiddleware,
 	)
 
+	// Every route must be described in the OpenAPI document
+	if err := openapi.CheckRoutes(r); err != nil {
+		log.Fatal("OpenAPI document is out of date", zap.Error(err))
+	}
+
 	// Create HTTP server
 	srv := &http.Server{
 		Addr:         ":" + cfg.App.Port,
@@ -103,6 +172,23 @@ func main() {
 		}
 	}()
 
+	// Start background worker that releases lapsed seat holds and prunes
+	// stale login throttles
+	holdSweeper := service.NewHoldSweeper(
+		bookingRepo,
+		loginThrottleRepo,
+		cfg.GetBookingSweepInterval(),
+		cfg.GetBookingProcessingGrace(),
+		cfg.GetLoginAttemptWindow(),
+		log,
+	)
+	sweeperCtx, stopSweeper := context.WithCancel(context.Background())
+	sweeperDone := make(chan struct{})
+	go func() {
+		defer close(sweeperDone)
+		holdSweeper.Run(sweeperCtx)
+	}()
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -118,5 +204,9 @@ func main() {
 		log.Fatal("Server forced to shutdown", zap.Error(err))
 	}
 
+	// Stop background workers
+	stopSweeper()
+	<-sweeperDone
+
 	log.Info("Server exited gracefully")
 }
@@ -0,0 +1,162 @@
+// Package apperror defines the domain errors returned by repositories and
+// services. Each error carries a stable Code that handlers translate into an
+// HTTP status and that clients can switch on, while its message stays human
+// readable.
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Code identifies a kind of failure
+type Code string
+
+const (
+	CodeBadRequest      Code = "bad_request"
+	CodeValidation      Code = "validation_failed"
+	CodeUnauthorized    Code = "unauthorized"
+	CodePaymentDeclined Code = "payment_declined"
+	CodeForbidden       Code = "forbidden"
+	CodeNotFound        Code = "not_found"
+	CodeConflict        Code = "conflict"
+	CodeTooLarge        Code = "payload_too_large"
+	CodeUnsupportedType Code = "unsupported_media_type"
+	CodeUnprocessable   Code = "unprocessable"
+	CodeRateLimited     Code = "rate_limited"
+	CodeInternal        Code = "internal_error"
+	CodeUnavailable     Code = "service_unavailable"
+	CodeTimeout         Code = "timeout"
+)
+
+// Error is a domain error with a code and a message safe to show to clients.
+// Message is kept apart from its Args so it can be looked up in a message
+// catalogue before being formatted.
+type Error struct {
+	Code    Code
+	Message string
+	Args    []interface{}
+
+	// RetryAfter tells the client when to try again, if known
+	RetryAfter time.Duration
+
+	// Err is the underlying cause; it is never shown to clients
+	Err error
+}
+
+// Error returns the client-facing message
+func (e *Error) Error() string {
+	if len(e.Args) > 0 {
+		return fmt.Sprintf(e.Message, e.Args...)
+	}
+	return e.Message
+}
+
+// Unwrap returns the underlying cause
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// New creates an error with the given code
+func New(code Code, message string) *Error {
+	return &Error{Code: code, Message: message}
+}
+
+// Newf creates an error with the given code and a formatted message
+func Newf(code Code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: format, Args: args}
+}
+
+// Wrapf creates an error with the given code and a formatted message that
+// keeps err as its cause
+func Wrapf(err error, code Code, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: format, Args: args, Err: err}
+}
+
+// BadRequest creates an error for a request that cannot be processed as sent
+func BadRequest(message string) *Error {
+	return New(CodeBadRequest, message)
+}
+
+// Validation creates an error for input that breaks a business rule
+func Validation(message string) *Error {
+	return New(CodeValidation, message)
+}
+
+// Unauthorized creates an error for missing or invalid credentials
+func Unauthorized(message string) *Error {
+	return New(CodeUnauthorized, message)
+}
+
+// PaymentDeclined creates an error for a charge the payment provider refused
+func PaymentDeclined(message string) *Error {
+	return New(CodePaymentDeclined, message)
+}
+
+// Forbidden creates an error for an action the user may not perform
+func Forbidden(message string) *Error {
+	return New(CodeForbidden, message)
+}
+
+// NotFound creates an error for a resource that does not exist
+func NotFound(message string) *Error {
+	return New(CodeNotFound, message)
+}
+
+// Conflict creates an error for an action that clashes with the current state
+func Conflict(message string) *Error {
+	return New(CodeConflict, message)
+}
+
+// TooLarge creates an error for a request body over the size limit
+func TooLarge(message string) *Error {
+	return New(CodeTooLarge, message)
+}
+
+// UnsupportedType creates an error for a request body in a format the API
+// does not accept
+func UnsupportedType(message string) *Error {
+	return New(CodeUnsupportedType, message)
+}
+
+// Unprocessable creates an error for a well-formed request that cannot be
+// applied, such as one that reuses a key meant for a different request
+func Unprocessable(message string) *Error {
+	return New(CodeUnprocessable, message)
+}
+
+// RateLimited creates an error for an action attempted again too soon
+func RateLimited(retryAfter time.Duration, format string, args ...interface{}) *Error {
+	return &Error{Code: CodeRateLimited, Message: format, Args: args, RetryAfter: retryAfter}
+}
+
+// Internal creates an error for an unexpected failure
+func Internal(message string) *Error {
+	return New(CodeInternal, message)
+}
+
+// Unavailable creates an error for a dependency that cannot be used right now
+func Unavailable(message string) *Error {
+	return New(CodeUnavailable, message)
+}
+
+// Timeout creates an error for a dependency that did not answer in time
+func Timeout(message string) *Error {
+	return New(CodeTimeout, message)
+}
+
+// CodeOf returns the code of the first *Error in err's chain, or
+// CodeInternal when there is none
+func CodeOf(err error) Code {
+	var appErr *Error
+	if errors.As(err, &appErr) {
+		return appErr.Code
+	}
+	return CodeInternal
+}
+
+// Is reports whether err carries the given code
+func Is(err error, code Code) bool {
+	return err != nil && CodeOf(err) == code
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/spf13/viper"
@@ -9,17 +10,35 @@ import (
 
 // Config holds all configuration for the application
 type Config struct {
-	App      AppConfig
-	Database DatabaseConfig
-	JWT      JWTConfig
-	Log      LogConfig
+	App         AppConfig
+	Database    DatabaseConfig
+	JWT         JWTConfig
+	Auth        AuthConfig
+	Password    PasswordConfig
+	Mail        MailConfig
+	Booking     BookingConfig
+	Payment     PaymentConfig
+	Idempotency IdempotencyConfig
+	Log         LogConfig
 }
 
 // AppConfig holds application-specific configuration
 type AppConfig struct {
-	Name string
-	Port string
-	Env  string
+	Name    string
+	Port    string
+	Env     string
+	BaseURL string
+
+	// DefaultLocale is the language of responses when neither the user nor
+	// the Accept-Language header asks for a supported one
+	DefaultLocale string
+
+	// MaxBodyBytes caps the size of JSON request bodies
+	MaxBodyBytes int64
+
+	// TrustedProxies lists the reverse proxies, as IP addresses or CIDR
+	// ranges, whose X-Forwarded-For and X-Real-IP headers are believed
+	TrustedProxies []string
 }
 
 // DatabaseConfig holds database connection configuration
@@ -34,8 +53,68 @@ type DatabaseConfig struct {
 
 // JWTConfig holds JWT authentication configuration
 type JWTConfig struct {
-	Secret          string
-	ExpirationHours int
+	Secret             string
+	AccessTokenMinutes int
+	RefreshTokenDays   int
+	SessionMaxDays     int
+	SigningKeysDir     string
+	ActiveKeyID        string
+	LegacyHS256Until   time.Time
+}
+
+// AuthConfig holds account security configuration
+type AuthConfig struct {
+	PasswordResetMinutes      int
+	EmailVerificationHours    int
+	VerificationResendSeconds int
+	RequireVerifiedEmail      bool
+	LoginMaxAttempts          int
+	LoginMaxAttemptsPerIP     int
+	LoginAttemptWindowMinutes int
+	LoginLockoutSeconds       int
+	LoginMaxLockoutMinutes    int
+	TwoFactorIssuer           string
+	TwoFactorChallengeMinutes int
+}
+
+// PasswordConfig holds the password policy applied to new passwords
+type PasswordConfig struct {
+	MinLength            int
+	RequireUpper         bool
+	RequireLower         bool
+	RequireDigit         bool
+	RequireSymbol        bool
+	DisallowPersonalInfo bool
+	BreachedListFile     string
+}
+
+// MailConfig holds outgoing email configuration
+type MailConfig struct {
+	Driver  string
+	From    string
+	FileDir string
+}
+
+// BookingConfig holds seat reservation configuration
+type BookingConfig struct {
+	HoldMinutes          int
+	SweepIntervalSeconds int
+	CancelCutoffHours    int
+
+	// ProcessingGraceMinutes is how long past its hold a booking awaiting
+	// asynchronous payment confirmation keeps its seats
+	ProcessingGraceMinutes int
+}
+
+// PaymentConfig holds payment gateway configuration
+type PaymentConfig struct {
+	GatewayTimeoutSeconds int
+	WebhookSecret         string
+}
+
+// IdempotencyConfig holds Idempotency-Key configuration
+type IdempotencyConfig struct {
+	TTLHours int
 }
 
 // LogConfig holds logging configuration
@@ -56,11 +135,23 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to read config file: %w", err)
 	}
 
+	// Defaults for settings whose zero value is meaningful
+	viper.SetDefault("PASSWORD_REQUIRE_UPPER", true)
+	viper.SetDefault("PASSWORD_REQUIRE_LOWER", true)
+	viper.SetDefault("PASSWORD_REQUIRE_DIGIT", true)
+	viper.SetDefault("PASSWORD_DISALLOW_PERSONAL_INFO", true)
+	viper.SetDefault("PASSWORD_BREACHED_LIST_FILE", "./data/common-passwords.txt")
+
 	config := &Config{
 		App: AppConfig{
-			Name: viper.GetString("APP_NAME"),
-			Port: viper.GetString("APP_PORT"),
-			Env:  viper.GetString("APP_ENV"),
+			Name:          viper.GetString("APP_NAME"),
+			Port:          viper.GetString("APP_PORT"),
+			Env:           viper.GetString("APP_ENV"),
+			BaseURL:       viper.GetString("APP_BASE_URL"),
+			DefaultLocale: viper.GetString("APP_DEFAULT_LOCALE"),
+			MaxBodyBytes:  viper.GetInt64("APP_MAX_BODY_BYTES"),
+
+			TrustedProxies: splitList(viper.GetString("APP_TRUSTED_PROXIES")),
 		},
 		Database: DatabaseConfig{
 			Host:     viper.GetString("DB_HOST"),
@@ -71,8 +162,54 @@ func Load() (*Config, error) {
 			SSLMode:  viper.GetString("DB_SSLMODE"),
 		},
 		JWT: JWTConfig{
-			Secret:          viper.GetString("JWT_SECRET"),
-			ExpirationHours: viper.GetInt("JWT_EXPIRATION_HOURS"),
+			Secret:             viper.GetString("JWT_SECRET"),
+			AccessTokenMinutes: viper.GetInt("JWT_ACCESS_TOKEN_MINUTES"),
+			RefreshTokenDays:   viper.GetInt("JWT_REFRESH_TOKEN_DAYS"),
+			SessionMaxDays:     viper.GetInt("JWT_SESSION_MAX_DAYS"),
+			SigningKeysDir:     viper.GetString("JWT_SIGNING_KEYS_DIR"),
+			ActiveKeyID:        viper.GetString("JWT_ACTIVE_KEY_ID"),
+			LegacyHS256Until:   viper.GetTime("JWT_LEGACY_HS256_UNTIL"),
+		},
+		Auth: AuthConfig{
+			PasswordResetMinutes:      viper.GetInt("AUTH_PASSWORD_RESET_MINUTES"),
+			EmailVerificationHours:    viper.GetInt("AUTH_EMAIL_VERIFICATION_HOURS"),
+			VerificationResendSeconds: viper.GetInt("AUTH_VERIFICATION_RESEND_SECONDS"),
+			RequireVerifiedEmail:      viper.GetBool("AUTH_REQUIRE_VERIFIED_EMAIL"),
+			LoginMaxAttempts:          viper.GetInt("AUTH_LOGIN_MAX_ATTEMPTS"),
+			LoginMaxAttemptsPerIP:     viper.GetInt("AUTH_LOGIN_MAX_ATTEMPTS_PER_IP"),
+			LoginAttemptWindowMinutes: viper.GetInt("AUTH_LOGIN_ATTEMPT_WINDOW_MINUTES"),
+			LoginLockoutSeconds:       viper.GetInt("AUTH_LOGIN_LOCKOUT_SECONDS"),
+			LoginMaxLockoutMinutes:    viper.GetInt("AUTH_LOGIN_MAX_LOCKOUT_MINUTES"),
+			TwoFactorIssuer:           viper.GetString("AUTH_2FA_ISSUER"),
+			TwoFactorChallengeMinutes: viper.GetInt("AUTH_2FA_CHALLENGE_MINUTES"),
+		},
+		Password: PasswordConfig{
+			MinLength:            viper.GetInt("PASSWORD_MIN_LENGTH"),
+			RequireUpper:         viper.GetBool("PASSWORD_REQUIRE_UPPER"),
+			RequireLower:         viper.GetBool("PASSWORD_REQUIRE_LOWER"),
+			RequireDigit:         viper.GetBool("PASSWORD_REQUIRE_DIGIT"),
+			RequireSymbol:        viper.GetBool("PASSWORD_REQUIRE_SYMBOL"),
+			DisallowPersonalInfo: viper.GetBool("PASSWORD_DISALLOW_PERSONAL_INFO"),
+			BreachedListFile:     viper.GetString("PASSWORD_BREACHED_LIST_FILE"),
+		},
+		Mail: MailConfig{
+			Driver:  viper.GetString("MAIL_DRIVER"),
+			From:    viper.GetString("MAIL_FROM"),
+			FileDir: viper.GetString("MAIL_FILE_DIR"),
+		},
+		Booking: BookingConfig{
+			HoldMinutes:          viper.GetInt("BOOKING_HOLD_MINUTES"),
+			SweepIntervalSeconds: viper.GetInt("BOOKING_SWEEP_INTERVAL_SECONDS"),
+			CancelCutoffHours:    viper.GetInt("BOOKING_CANCEL_CUTOFF_HOURS"),
+
+			ProcessingGraceMinutes: viper.GetInt("BOOKING_PROCESSING_GRACE_MINUTES"),
+		},
+		Payment: PaymentConfig{
+			GatewayTimeoutSeconds: viper.GetInt("PAYMENT_GATEWAY_TIMEOUT_SECONDS"),
+			WebhookSecret:         viper.GetString("PAYMENT_WEBHOOK_SECRET"),
+		},
+		Idempotency: IdempotencyConfig{
+			TTLHours: viper.GetInt("IDEMPOTENCY_TTL_HOURS"),
 		},
 		Log: LogConfig{
 			Level:    viper.GetString("LOG_LEVEL"),
@@ -84,8 +221,86 @@ func Load() (*Config, error) {
 	if config.App.Port == "" {
 		config.App.Port = "8080"
 	}
-	if config.JWT.ExpirationHours == 0 {
-		config.JWT.ExpirationHours = 24
+	if config.JWT.AccessTokenMinutes == 0 {
+		config.JWT.AccessTokenMinutes = 15
+	}
+	if config.JWT.RefreshTokenDays == 0 {
+		config.JWT.RefreshTokenDays = 30
+	}
+	if config.JWT.SessionMaxDays == 0 {
+		config.JWT.SessionMaxDays = 90
+	}
+	if config.App.BaseURL == "" {
+		config.App.BaseURL = "http://localhost:" + config.App.Port
+	}
+	if config.App.DefaultLocale == "" {
+		config.App.DefaultLocale = "en"
+	}
+	if config.App.MaxBodyBytes == 0 {
+		config.App.MaxBodyBytes = 1 << 20
+	}
+	if config.Auth.PasswordResetMinutes == 0 {
+		config.Auth.PasswordResetMinutes = 60
+	}
+	if config.Auth.EmailVerificationHours == 0 {
+		config.Auth.EmailVerificationHours = 24
+	}
+	if config.Auth.VerificationResendSeconds == 0 {
+		config.Auth.VerificationResendSeconds = 60
+	}
+	if config.Auth.LoginMaxAttempts == 0 {
+		config.Auth.LoginMaxAttempts = 5
+	}
+	if config.Auth.LoginMaxAttemptsPerIP == 0 {
+		config.Auth.LoginMaxAttemptsPerIP = 20
+	}
+	if config.Auth.LoginAttemptWindowMinutes == 0 {
+		config.Auth.LoginAttemptWindowMinutes = 15
+	}
+	if config.Auth.LoginLockoutSeconds == 0 {
+		config.Auth.LoginLockoutSeconds = 30
+	}
+	if config.Auth.LoginMaxLockoutMinutes == 0 {
+		config.Auth.LoginMaxLockoutMinutes = 60
+	}
+	if config.Auth.TwoFactorIssuer == "" {
+		config.Auth.TwoFactorIssuer = config.App.Name
+	}
+	if config.Auth.TwoFactorIssuer == "" {
+		config.Auth.TwoFactorIssuer = "Cinema Booking System"
+	}
+	if config.Auth.TwoFactorChallengeMinutes == 0 {
+		config.Auth.TwoFactorChallengeMinutes = 5
+	}
+	if config.Password.MinLength == 0 {
+		config.Password.MinLength = 8
+	}
+	if config.Mail.Driver == "" {
+		config.Mail.Driver = "log"
+	}
+	if config.Mail.From == "" {
+		config.Mail.From = "no-reply@cinema-booking.local"
+	}
+	if config.Mail.FileDir == "" {
+		config.Mail.FileDir = "./tmp/mail"
+	}
+	if config.Booking.HoldMinutes == 0 {
+		config.Booking.HoldMinutes = 15
+	}
+	if config.Booking.SweepIntervalSeconds == 0 {
+		config.Booking.SweepIntervalSeconds = 60
+	}
+	if config.Booking.CancelCutoffHours == 0 {
+		config.Booking.CancelCutoffHours = 2
+	}
+	if config.Booking.ProcessingGraceMinutes == 0 {
+		config.Booking.ProcessingGraceMinutes = 30
+	}
+	if config.Payment.GatewayTimeoutSeconds == 0 {
+		config.Payment.GatewayTimeoutSeconds = 15
+	}
+	if config.Idempotency.TTLHours == 0 {
+		config.Idempotency.TTLHours = 24
 	}
 	if config.Log.Level == "" {
 		config.Log.Level = "info"
@@ -94,9 +309,47 @@ func Load() (*Config, error) {
 		config.Log.Encoding = "json"
 	}
 
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
 	return config, nil
 }
 
+// exampleWebhookSecret is the placeholder webhook secret of .env.example
+const exampleWebhookSecret = "change-this-webhook-secret"
+
+// validate rejects settings that are only safe during development
+func (c *Config) validate() error {
+	if c.IsDevelopment() {
+		return nil
+	}
+
+	// Anyone who knows the secret can mark bookings paid through the mock
+	// gateways' webhook
+	if c.Payment.WebhookSecret == "" || c.Payment.WebhookSecret == exampleWebhookSecret {
+		return fmt.Errorf("PAYMENT_WEBHOOK_SECRET must be set to a random value when APP_ENV is %q", c.App.Env)
+	}
+
+	return nil
+}
+
+// IsDevelopment reports whether the app runs in the development environment
+func (c *Config) IsDevelopment() bool {
+	return c.App.Env == "development"
+}
+
+// splitList splits a comma-separated setting, dropping blank entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // GetDatabaseDSN returns PostgreSQL connection string
 func (c *Config) GetDatabaseDSN() string {
 	return fmt.Sprintf(
@@ -110,7 +363,92 @@ func (c *Config) GetDatabaseDSN() string {
 	)
 }
 
-// GetJWTExpiration returns JWT expiration duration
-func (c *Config) GetJWTExpiration() time.Duration {
-	return time.Duration(c.JWT.ExpirationHours) * time.Hour
+// GetAccessTokenTTL returns how long an access token (JWT) is valid
+func (c *Config) GetAccessTokenTTL() time.Duration {
+	return time.Duration(c.JWT.AccessTokenMinutes) * time.Minute
+}
+
+// GetRefreshTokenTTL returns how long a refresh token is valid
+func (c *Config) GetRefreshTokenTTL() time.Duration {
+	return time.Duration(c.JWT.RefreshTokenDays) * 24 * time.Hour
+}
+
+// GetSessionMaxLifetime returns how long a session may be kept alive by
+// refreshing, counted from login
+func (c *Config) GetSessionMaxLifetime() time.Duration {
+	return time.Duration(c.JWT.SessionMaxDays) * 24 * time.Hour
+}
+
+// GetPasswordResetTTL returns how long a password reset link stays valid
+func (c *Config) GetPasswordResetTTL() time.Duration {
+	return time.Duration(c.Auth.PasswordResetMinutes) * time.Minute
+}
+
+// GetEmailVerificationTTL returns how long an email verification link stays valid
+func (c *Config) GetEmailVerificationTTL() time.Duration {
+	return time.Duration(c.Auth.EmailVerificationHours) * time.Hour
+}
+
+// GetVerificationResendInterval returns the minimum time between verification emails
+func (c *Config) GetVerificationResendInterval() time.Duration {
+	return time.Duration(c.Auth.VerificationResendSeconds) * time.Second
+}
+
+// GetLoginAttemptWindow returns how long a failed login counts towards a lockout
+func (c *Config) GetLoginAttemptWindow() time.Duration {
+	return time.Duration(c.Auth.LoginAttemptWindowMinutes) * time.Minute
+}
+
+// GetLoginLockout returns how long logins are locked after the given number of
+// consecutive failures, doubling with each failure past the threshold and
+// capped at the configured maximum. It returns zero below the threshold.
+func (c *Config) GetLoginLockout(failures, threshold int) time.Duration {
+	if failures < threshold {
+		return 0
+	}
+
+	lockout := time.Duration(c.Auth.LoginLockoutSeconds) * time.Second
+	maxLockout := time.Duration(c.Auth.LoginMaxLockoutMinutes) * time.Minute
+	for i := threshold; i < failures && lockout < maxLockout; i++ {
+		lockout *= 2
+	}
+
+	return min(lockout, maxLockout)
+}
+
+// GetTwoFactorChallengeTTL returns how long a login has to complete the
+// two-factor step
+func (c *Config) GetTwoFactorChallengeTTL() time.Duration {
+	return time.Duration(c.Auth.TwoFactorChallengeMinutes) * time.Minute
+}
+
+// GetBookingHoldTTL returns how long an unpaid reservation holds its seats
+func (c *Config) GetBookingHoldTTL() time.Duration {
+	return time.Duration(c.Booking.HoldMinutes) * time.Minute
+}
+
+// GetBookingProcessingGrace returns how long past its hold a booking awaiting
+// payment confirmation is kept before it expires
+func (c *Config) GetBookingProcessingGrace() time.Duration {
+	return time.Duration(c.Booking.ProcessingGraceMinutes) * time.Minute
+}
+
+// GetBookingSweepInterval returns how often expired holds are released
+func (c *Config) GetBookingSweepInterval() time.Duration {
+	return time.Duration(c.Booking.SweepIntervalSeconds) * time.Second
+}
+
+// GetBookingCancelCutoff returns how long before a showtime cancellation closes
+func (c *Config) GetBookingCancelCutoff() time.Duration {
+	return time.Duration(c.Booking.CancelCutoffHours) * time.Hour
+}
+
+// GetPaymentGatewayTimeout returns how long to wait for a payment gateway
+func (c *Config) GetPaymentGatewayTimeout() time.Duration {
+	return time.Duration(c.Payment.GatewayTimeoutSeconds) * time.Second
+}
+
+// GetIdempotencyTTL returns how long a stored idempotent response is replayed
+func (c *Config) GetIdempotencyTTL() time.Duration {
+	return time.Duration(c.Idempotency.TTLHours) * time.Hour
 }
@@ -1,24 +1,82 @@
 package dto
 
+import (
+	"time"
+
+	"cinema-booking-system/internal/models"
+)
+
 // RegisterRequest represents user registration input
 type RegisterRequest struct {
-	Username string `json:"username" validate:"required,min=3,max=50"`
+	Username string `json:"username" validate:"required,min=3,max=50,not_reserved"`
 	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required,min=6"`
+	Password string `json:"password" validate:"required,password"`
 	FullName string `json:"full_name" validate:"omitempty,max=100"`
 }
 
 // LoginRequest represents user login input
 type LoginRequest struct {
-	Username string `json:"username" validate:"required"`
+	Username string `json:"username" validate:"required,max=50"`
 	Password string `json:"password" validate:"required"`
 }
 
 // LoginResponse represents successful login output
 type LoginResponse struct {
-	Token     string `json:"token"`
-	ExpiresAt string `json:"expires_at"`
-	User      UserResponse
+	Token            string       `json:"token"`
+	ExpiresAt        string       `json:"expires_at"`
+	RefreshToken     string       `json:"refresh_token"`
+	RefreshExpiresAt string       `json:"refresh_expires_at"`
+	User             UserResponse `json:"user"`
+}
+
+// TwoFactorChallengeResponse is returned by login instead of a session when
+// the account has two-factor authentication enabled
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+	ExpiresAt         string `json:"expires_at"`
+}
+
+// TwoFactorLoginRequest completes a login with a TOTP or recovery code
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code" validate:"required,max=20"`
+}
+
+// TwoFactorSetupResponse carries a pending TOTP secret for enrolment
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURI string `json:"otpauth_uri"`
+}
+
+// TwoFactorCodeRequest represents a TOTP or recovery code
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required,max=20"`
+}
+
+// DisableTwoFactorRequest represents turning off two-factor authentication
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required,max=20"`
+}
+
+// RecoveryCodesResponse lists newly generated recovery codes; they are only
+// ever shown once
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// RefreshTokenRequest represents token refresh input
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// TokenResponse represents a newly issued token pair
+type TokenResponse struct {
+	Token            string `json:"token"`
+	ExpiresAt        string `json:"expires_at"`
+	RefreshToken     string `json:"refresh_token"`
+	RefreshExpiresAt string `json:"refresh_expires_at"`
 }
 
 // UserResponse represents user information in responses
@@ -27,15 +85,128 @@ type UserResponse struct {
 	Username string `json:"username"`
 	Email    string `json:"email"`
 	FullName string `json:"full_name,omitempty"`
+	Role     string `json:"role"`
+}
+
+// ProfileResponse represents the logged-in user's own account
+type ProfileResponse struct {
+	ID                int        `json:"id"`
+	Username          string     `json:"username"`
+	Email             string     `json:"email"`
+	EmailVerifiedAt   *time.Time `json:"email_verified_at"`
+	PendingEmail      string     `json:"pending_email,omitempty"`
+	TwoFactorEnabled  bool       `json:"two_factor_enabled"`
+	RecoveryCodesLeft *int       `json:"recovery_codes_left,omitempty"`
+	FullName          string     `json:"full_name"`
+	Role              string     `json:"role"`
+	Locale            string     `json:"locale"`
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+}
+
+// UpdateProfileRequest represents a partial profile update. Changing the
+// email requires the current password and only takes effect once the new
+// address is verified. An empty locale clears the language preference.
+type UpdateProfileRequest struct {
+	FullName        *string `json:"full_name" validate:"omitempty,max=100"`
+	Email           *string `json:"email" validate:"omitempty,email,max=100"`
+	Locale          *string `json:"locale" validate:"omitempty,locale"`
+	CurrentPassword string  `json:"current_password" validate:"required_with=Email"`
+}
+
+// ChangePasswordRequest represents a logged-in user changing their password
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,password"`
+}
+
+// AccountExportResponse is the personal data archive of a user
+type AccountExportResponse struct {
+	ExportedAt time.Time               `json:"exported_at"`
+	Profile    *ProfileResponse        `json:"profile"`
+	Sessions   []*SessionResponse      `json:"sessions"`
+	Bookings   []*models.BookingDetail `json:"bookings"`
+}
+
+// DeleteAccountRequest represents a user confirming deletion of their account
+type DeleteAccountRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+// ForgotPasswordRequest represents a password reset request
+type ForgotPasswordRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ResetPasswordRequest represents setting a new password with a reset token
+type ResetPasswordRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,password"`
+}
+
+// SessionResponse represents an active login session
+type SessionResponse struct {
+	ID        int       `json:"id"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	IPAddress string    `json:"ip_address,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Current   bool      `json:"current"`
+}
+
+// UpdateUserRoleRequest represents an admin changing a user's role
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" validate:"required,oneof=customer staff cinema_manager admin"`
+}
+
+// CinemaManagerRequest represents assigning a manager to a cinema
+type CinemaManagerRequest struct {
+	UserID int `json:"user_id" validate:"required"`
+}
+
+// CinemaRequest represents cinema creation and update input
+type CinemaRequest struct {
+	Name        string `json:"name" validate:"required,max=100"`
+	Location    string `json:"location" validate:"required,max=255"`
+	Description string `json:"description" validate:"omitempty"`
+}
+
+// SeatLayoutRequest represents a bulk seat layout definition
+type SeatLayoutRequest struct {
+	Rows []SeatRowRange `json:"rows" validate:"required,min=1,max=26,dive"`
+}
+
+// SeatRowRange defines the seats of a range of rows, e.g. rows A to C
+type SeatRowRange struct {
+	FromRow     string  `json:"from_row" validate:"required,len=1,uppercase,alpha"`
+	ToRow       string  `json:"to_row" validate:"required,len=1,uppercase,alpha"`
+	SeatsPerRow int     `json:"seats_per_row" validate:"required,min=1,max=50"`
+	SeatType    string  `json:"seat_type" validate:"required,oneof=regular vip"`
+	Price       float64 `json:"price" validate:"required,gt=0"`
+}
+
+// CreateMovieRequest represents movie creation input
+type CreateMovieRequest struct {
+	Title           string `json:"title" validate:"required,max=200"`
+	Description     string `json:"description" validate:"omitempty"`
+	DurationMinutes int    `json:"duration_minutes" validate:"required,min=1,max=600"`
+	Rating          string `json:"rating" validate:"required,oneof=SU 13+ 17+ 21+"`
+	Language        string `json:"language" validate:"required,max=50"`
+}
+
+// CreateShowtimeRequest represents showtime scheduling input
+type CreateShowtimeRequest struct {
+	MovieID  int    `json:"movie_id" validate:"required"`
+	CinemaID int    `json:"cinema_id" validate:"required"`
+	Date     string `json:"date" validate:"required,datetime=2006-01-02,not_past_date"` // Format: YYYY-MM-DD
+	Time     string `json:"time" validate:"required,datetime=15:04"`                    // Format: HH:MM
 }
 
 // BookingRequest represents seat booking input
 type BookingRequest struct {
-	CinemaID      int    `json:"cinema_id" validate:"required"`
-	SeatID        int    `json:"seat_id" validate:"required"`
-	Date          string `json:"date" validate:"required"` // YYYY-MM-DD
-	Time          string `json:"time" validate:"required"` // HH:MM
-	PaymentMethod int    `json:"payment_method" validate:"required"`
+	ShowtimeID    int   `json:"showtime_id" validate:"required"`
+	SeatIDs       []int `json:"seat_ids" validate:"required,min=1,max=10,unique,dive,required"`
+	PaymentMethod int   `json:"payment_method" validate:"required"`
 }
 
 // PaymentRequest represents payment processing input
@@ -45,6 +216,12 @@ type PaymentRequest struct {
 	PaymentDetails map[string]interface{} `json:"payment_details,omitempty"`
 }
 
+// CancelBookingResponse represents the result of a booking cancellation
+type CancelBookingResponse struct {
+	Booking *models.Booking `json:"booking"`
+	Refund  *models.Payment `json:"refund,omitempty"`
+}
+
 // PaginationParams represents pagination query parameters
 type PaginationParams struct {
 	Page     int `validate:"omitempty,min=1"`
@@ -53,8 +230,8 @@ type PaginationParams struct {
 
 // PaginatedResponse represents paginated API response
 type PaginatedResponse struct {
-	Data       interface{}      `json:"data"`
-	Pagination PaginationMeta   `json:"pagination"`
+	Data       interface{}    `json:"data"`
+	Pagination PaginationMeta `json:"pagination"`
 }
 
 // PaginationMeta contains pagination metadata
@@ -73,9 +250,21 @@ type Response struct {
 	Error   string      `json:"error,omitempty"`
 }
 
-// ErrorResponse represents an error API response
+// ErrorResponse represents an error API response. Code is one of the
+// apperror codes and is stable, unlike the Error text.
 type ErrorResponse struct {
-	Success bool   `json:"success"`
-	Error   string `json:"error"`
-	Message string `json:"message,omitempty"`
+	Success bool         `json:"success"`
+	Code    string       `json:"code"`
+	Error   string       `json:"error"`
+	Message string       `json:"message,omitempty"`
+	Fields  []FieldError `json:"fields,omitempty"`
+}
+
+// FieldError describes a request field that failed validation. Field is the
+// JSON path of the field and Rule the validation tag that failed.
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
 }
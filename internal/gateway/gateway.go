@@ -0,0 +1,82 @@
+package gateway
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Status represents the outcome of a gateway operation
+type Status string
+
+const (
+	// StatusSucceeded means the money movement completed
+	StatusSucceeded Status = "succeeded"
+	// StatusPending means the provider will confirm the outcome asynchronously
+	StatusPending Status = "pending"
+	// StatusDeclined means the provider refused the operation
+	StatusDeclined Status = "declined"
+	// StatusFailed means the operation could not be completed
+	StatusFailed Status = "failed"
+)
+
+// ErrTimeout is returned when the provider does not answer in time
+var ErrTimeout = errors.New("payment gateway timed out")
+
+// ChargeRequest describes a payment to collect from the customer
+type ChargeRequest struct {
+	BookingID int
+	Amount    float64
+	Currency  string
+	Details   map[string]interface{}
+}
+
+// RefundRequest describes money to return for a previous charge
+type RefundRequest struct {
+	BookingID       int
+	ChargeReference string
+	Amount          float64
+	Reason          string
+}
+
+// Result is the provider's answer to a charge, refund or status query
+type Result struct {
+	Reference     string
+	Status        Status
+	FailureReason string
+}
+
+// PaymentGateway is implemented by every payment provider integration
+type PaymentGateway interface {
+	// Name returns the provider code stored in payment_methods.provider
+	Name() string
+	// Charge collects a payment
+	Charge(ctx context.Context, req ChargeRequest) (*Result, error)
+	// Refund returns money for a previous charge
+	Refund(ctx context.Context, req RefundRequest) (*Result, error)
+	// QueryStatus retrieves the current state of a charge or refund
+	QueryStatus(ctx context.Context, reference string) (*Result, error)
+}
+
+// Registry resolves payment gateways by provider code
+type Registry struct {
+	gateways map[string]PaymentGateway
+}
+
+// NewRegistry creates a registry containing the given gateways
+func NewRegistry(gateways ...PaymentGateway) *Registry {
+	registry := &Registry{gateways: make(map[string]PaymentGateway)}
+	for _, gw := range gateways {
+		registry.gateways[gw.Name()] = gw
+	}
+	return registry
+}
+
+// Get returns the gateway registered for a provider code
+func (r *Registry) Get(provider string) (PaymentGateway, error) {
+	gw, ok := r.gateways[provider]
+	if !ok {
+		return nil, fmt.Errorf("payment provider %q is not configured", provider)
+	}
+	return gw, nil
+}
@@ -0,0 +1,178 @@
+package gateway
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+)
+
+const (
+	// MockProvider is the provider code of the synchronous mock gateway
+	MockProvider = "mock"
+	// MockAsyncProvider is the provider code of the asynchronous mock gateway,
+	// used for methods such as e-wallets where the customer pays elsewhere
+	MockAsyncProvider = "mock_async"
+	// MockSignatureHeader carries the hex HMAC-SHA256 of a mock webhook payload
+	MockSignatureHeader = "X-Mock-Signature"
+)
+
+// MockGateway is an offline payment gateway for development and testing.
+//
+// The outcome of a charge is driven by the "simulate" key of the payment
+// details: "decline", "timeout", "pending" or anything else for the default
+// outcome, which is success for synchronous gateways and pending for
+// asynchronous ones. Pending charges are settled by a signed webhook.
+type MockGateway struct {
+	name          string
+	async         bool
+	webhookSecret []byte
+
+	mu      sync.Mutex
+	results map[string]*Result
+}
+
+// mockWebhookPayload is the JSON body of a mock webhook notification
+type mockWebhookPayload struct {
+	EventID       string `json:"event_id"`
+	Reference     string `json:"reference"`
+	Status        string `json:"status"`
+	FailureReason string `json:"failure_reason,omitempty"`
+}
+
+// NewMockGateway creates a new mock gateway registered under name. Webhooks
+// are signed with webhookSecret; async gateways leave charges pending.
+func NewMockGateway(name, webhookSecret string, async bool) *MockGateway {
+	return &MockGateway{
+		name:          name,
+		async:         async,
+		webhookSecret: []byte(webhookSecret),
+		results:       make(map[string]*Result),
+	}
+}
+
+// Name returns the provider code of the mock gateway
+func (g *MockGateway) Name() string {
+	return g.name
+}
+
+// Charge simulates collecting a payment
+func (g *MockGateway) Charge(ctx context.Context, req ChargeRequest) (*Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, ErrTimeout
+	}
+
+	simulate, _ := req.Details["simulate"].(string)
+
+	var result *Result
+	switch simulate {
+	case "timeout":
+		return nil, ErrTimeout
+	case "decline":
+		result = &Result{Status: StatusDeclined, FailureReason: "card declined by issuer"}
+	case "pending":
+		result = &Result{Status: StatusPending}
+	default:
+		if g.async {
+			result = &Result{Status: StatusPending}
+		} else {
+			result = &Result{Status: StatusSucceeded}
+		}
+	}
+
+	result.Reference = g.newReference("ch")
+	g.store(result)
+
+	return result, nil
+}
+
+// Refund simulates returning money for a previous charge
+func (g *MockGateway) Refund(ctx context.Context, req RefundRequest) (*Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, ErrTimeout
+	}
+
+	result := &Result{Reference: g.newReference("re"), Status: StatusSucceeded}
+	if req.ChargeReference == "" {
+		result.Status = StatusFailed
+		result.FailureReason = "missing charge reference"
+	}
+	g.store(result)
+
+	return result, nil
+}
+
+// QueryStatus returns the stored outcome of a previous operation
+func (g *MockGateway) QueryStatus(ctx context.Context, reference string) (*Result, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	result, ok := g.results[reference]
+	if !ok {
+		return nil, fmt.Errorf("payment %q not found", reference)
+	}
+
+	copied := *result
+	return &copied, nil
+}
+
+// ParseWebhook verifies the HMAC signature of a mock webhook and decodes it
+func (g *MockGateway) ParseWebhook(payload []byte, header http.Header) (*WebhookEvent, error) {
+	signature, err := hex.DecodeString(header.Get(MockSignatureHeader))
+	if err != nil || len(g.webhookSecret) == 0 || !hmac.Equal(signature, g.sign(payload)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var body mockWebhookPayload
+	if err := json.Unmarshal(payload, &body); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+	if body.EventID == "" || body.Reference == "" {
+		return nil, fmt.Errorf("invalid webhook payload: event_id and reference are required")
+	}
+
+	event := &WebhookEvent{
+		ID:            body.EventID,
+		Reference:     body.Reference,
+		Status:        Status(body.Status),
+		FailureReason: body.FailureReason,
+	}
+
+	// Keep QueryStatus consistent with what the provider reported
+	g.store(&Result{Reference: event.Reference, Status: event.Status, FailureReason: event.FailureReason})
+
+	return event, nil
+}
+
+// SignPayload returns the hex signature the mock provider sends with a payload
+func (g *MockGateway) SignPayload(payload []byte) string {
+	return hex.EncodeToString(g.sign(payload))
+}
+
+// sign computes the HMAC-SHA256 of a payload with the webhook secret
+func (g *MockGateway) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, g.webhookSecret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+// store records an operation outcome for later status queries
+func (g *MockGateway) store(result *Result) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	copied := *result
+	g.results[result.Reference] = &copied
+}
+
+// newReference generates a random provider reference with the given prefix
+func (g *MockGateway) newReference(prefix string) string {
+	b := make([]byte, 12)
+	rand.Read(b)
+	return fmt.Sprintf("mock_%s_%s", prefix, hex.EncodeToString(b))
+}
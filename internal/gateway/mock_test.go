@@ -0,0 +1,92 @@
+package gateway
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+const testWebhookSecret = "test-webhook-secret"
+
+// signedHeader returns the headers the mock provider sends with payload
+func signedHeader(g *MockGateway, payload []byte) http.Header {
+	header := http.Header{}
+	header.Set(MockSignatureHeader, g.SignPayload(payload))
+	return header
+}
+
+func TestMockParseWebhookAcceptsSignedPayload(t *testing.T) {
+	g := NewMockGateway(MockAsyncProvider, testWebhookSecret, true)
+	payload := []byte(`{"event_id":"evt_1","reference":"mock_ch_1","status":"succeeded"}`)
+
+	event, err := g.ParseWebhook(payload, signedHeader(g, payload))
+	if err != nil {
+		t.Fatalf("ParseWebhook() error = %v", err)
+	}
+
+	want := WebhookEvent{ID: "evt_1", Reference: "mock_ch_1", Status: StatusSucceeded}
+	if *event != want {
+		t.Fatalf("ParseWebhook() = %+v, want %+v", *event, want)
+	}
+
+	result, err := g.QueryStatus(t.Context(), "mock_ch_1")
+	if err != nil {
+		t.Fatalf("QueryStatus() error = %v", err)
+	}
+	if result.Status != StatusSucceeded {
+		t.Errorf("QueryStatus() status = %q, want %q", result.Status, StatusSucceeded)
+	}
+}
+
+func TestMockParseWebhookRejectsBadSignature(t *testing.T) {
+	g := NewMockGateway(MockAsyncProvider, testWebhookSecret, true)
+	payload := []byte(`{"event_id":"evt_1","reference":"mock_ch_1","status":"succeeded"}`)
+	other := NewMockGateway(MockAsyncProvider, "another-secret", true)
+
+	tests := []struct {
+		name    string
+		payload []byte
+		header  http.Header
+	}{
+		{"missing signature", payload, http.Header{}},
+		{"not hex", payload, http.Header{MockSignatureHeader: {"not-hex"}}},
+		{"other secret", payload, signedHeader(other, payload)},
+		{
+			"tampered payload",
+			[]byte(`{"event_id":"evt_1","reference":"mock_ch_2","status":"succeeded"}`),
+			signedHeader(g, payload),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := g.ParseWebhook(tt.payload, tt.header); !errors.Is(err, ErrInvalidSignature) {
+				t.Fatalf("ParseWebhook() error = %v, want ErrInvalidSignature", err)
+			}
+		})
+	}
+}
+
+func TestMockParseWebhookRejectsEverythingWithoutSecret(t *testing.T) {
+	g := NewMockGateway(MockAsyncProvider, "", true)
+	payload := []byte(`{"event_id":"evt_1","reference":"mock_ch_1","status":"succeeded"}`)
+
+	if _, err := g.ParseWebhook(payload, signedHeader(g, payload)); !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("ParseWebhook() error = %v, want ErrInvalidSignature", err)
+	}
+}
+
+func TestMockParseWebhookRequiresEventIDAndReference(t *testing.T) {
+	g := NewMockGateway(MockAsyncProvider, testWebhookSecret, true)
+
+	for _, payload := range [][]byte{
+		[]byte(`{"reference":"mock_ch_1","status":"succeeded"}`),
+		[]byte(`{"event_id":"evt_1","status":"succeeded"}`),
+		[]byte(`not json`),
+	} {
+		_, err := g.ParseWebhook(payload, signedHeader(g, payload))
+		if err == nil || errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("ParseWebhook(%s) error = %v, want an invalid payload error", payload, err)
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package gateway
+
+import (
+	"errors"
+	"net/http"
+)
+
+// ErrInvalidSignature is returned when a webhook payload fails verification
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// WebhookEvent is a verified asynchronous notification from a provider
+type WebhookEvent struct {
+	ID            string
+	Reference     string
+	Status        Status
+	FailureReason string
+}
+
+// WebhookVerifier is implemented by gateways that confirm payments asynchronously
+type WebhookVerifier interface {
+	// ParseWebhook verifies the payload signature and decodes the event
+	ParseWebhook(payload []byte, header http.Header) (*WebhookEvent, error)
+}
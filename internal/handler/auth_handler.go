@@ -3,6 +3,8 @@ package handler
 import (
 	"encoding/json"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"cinema-booking-system/internal/dto"
 	"cinema-booking-system/internal/middleware"
@@ -10,6 +12,7 @@ import (
 	"cinema-booking-system/internal/service"
 	"cinema-booking-system/internal/utils"
 
+	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 )
 
@@ -32,26 +35,18 @@ func NewAuthHandler(authService *service.AuthService, validator *utils.Validator
 // Register handles user registration
 // POST /api/register
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
-	var req dto.RegisterRequest
-
-	// Decode request body
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("Failed to decode register request", zap.Error(err))
-		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
-		return
-	}
-
-	// Validate request
-	if err := h.validator.Validate(req); err != nil {
-		utils.RespondWithValidationError(w, err)
+	// Decode and validate request body
+	req, err := utils.DecodeAndValidate[dto.RegisterRequest](w, r, h.validator)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
 		return
 	}
 
 	// Register user
-	user, err := h.authService.Register(r.Context(), &req)
+	user, err := h.authService.Register(r.Context(), req)
 	if err != nil {
 		h.logger.Error("Failed to register user", zap.Error(err))
-		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		utils.RespondWithAppError(w, r, err)
 		return
 	}
 
@@ -61,38 +56,381 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		Username: user.Username,
 		Email:    user.Email,
 		FullName: user.FullName,
+		Role:     user.Role,
 	}
 
-	utils.RespondWithSuccess(w, http.StatusCreated, userResponse, "User registered successfully")
+	utils.RespondWithSuccess(w, r, http.StatusCreated, userResponse, "User registered successfully")
 }
 
 // Login handles user login
 // POST /api/login
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
-	var req dto.LoginRequest
+	// Decode and validate request body
+	req, err := utils.DecodeAndValidate[dto.LoginRequest](w, r, h.validator)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
 
-	// Decode request body
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("Failed to decode login request", zap.Error(err))
-		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+	// Login user
+	loginResponse, challenge, err := h.authService.Login(r.Context(), req, r.UserAgent(), utils.ClientIP(r))
+	if err != nil {
+		h.logger.Error("Failed to login user", zap.Error(err))
+		utils.RespondWithAppError(w, r, err)
 		return
 	}
 
-	// Validate request
-	if err := h.validator.Validate(req); err != nil {
-		utils.RespondWithValidationError(w, err)
+	if challenge != nil {
+		utils.RespondWithSuccess(w, r, http.StatusOK, challenge, "Two-factor authentication required")
 		return
 	}
 
-	// Login user
-	loginResponse, err := h.authService.Login(r.Context(), &req)
+	utils.RespondWithSuccess(w, r, http.StatusOK, loginResponse, "Login successful")
+}
+
+// LoginTwoFactor completes a login with a TOTP or recovery code
+// POST /api/login/2fa
+func (h *AuthHandler) LoginTwoFactor(w http.ResponseWriter, r *http.Request) {
+	// Decode and validate request body
+	req, err := utils.DecodeAndValidate[dto.TwoFactorLoginRequest](w, r, h.validator)
 	if err != nil {
-		h.logger.Error("Failed to login user", zap.Error(err))
-		utils.RespondWithError(w, http.StatusUnauthorized, err.Error())
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	// Verify code
+	loginResponse, err := h.authService.VerifyTwoFactorLogin(r.Context(), req, r.UserAgent(), utils.ClientIP(r))
+	if err != nil {
+		h.logger.Error("Failed to complete two-factor login", zap.Error(err))
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, loginResponse, "Login successful")
+}
+
+// RefreshToken issues a new token pair for a valid refresh token
+// POST /api/token/refresh
+func (h *AuthHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
+	// Decode and validate request body
+	req, err := utils.DecodeAndValidate[dto.RefreshTokenRequest](w, r, h.validator)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	// Rotate tokens
+	tokens, err := h.authService.RefreshToken(r.Context(), req)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, tokens, "Token refreshed successfully")
+}
+
+// ForgotPassword sends a password reset link
+// POST /api/password/forgot
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	// Decode and validate request body
+	req, err := utils.DecodeAndValidate[dto.ForgotPasswordRequest](w, r, h.validator)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	// Always succeed to avoid revealing which emails are registered
+	h.authService.ForgotPassword(r.Context(), req)
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, nil, "If the email is registered, a reset link has been sent")
+}
+
+// ResetPassword sets a new password using a reset token
+// POST /api/password/reset
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	// Decode and validate request body
+	req, err := utils.DecodeAndValidate[dto.ResetPasswordRequest](w, r, h.validator)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	// Reset password
+	if err := h.authService.ResetPassword(r.Context(), req); err != nil {
+		utils.RespondWithAppError(w, r, err)
 		return
 	}
 
-	utils.RespondWithSuccess(w, http.StatusOK, loginResponse, "Login successful")
+	utils.RespondWithSuccess(w, r, http.StatusOK, nil, "Password has been reset, please log in again")
+}
+
+// VerifyEmail confirms an email address from the link sent by email
+// GET /api/verify-email?token={token}
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Missing verification token")
+		return
+	}
+
+	// Verify email
+	if err := h.authService.VerifyEmail(r.Context(), token); err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, nil, "Email verified successfully")
+}
+
+// ResendVerification sends a new verification email to the logged-in user
+// POST /api/verify-email/resend
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
+	if !ok {
+		utils.RespondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Resend verification
+	if err := h.authService.ResendVerification(r.Context(), user); err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, nil, "Verification email sent")
+}
+
+// GetProfile returns the logged-in user's account
+// GET /api/user/me
+func (h *AuthHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
+	if !ok {
+		utils.RespondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	profile, err := h.authService.GetProfile(r.Context(), user)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, profile, "")
+}
+
+// UpdateProfile changes the logged-in user's full name and/or email
+// PATCH /api/user/me
+func (h *AuthHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
+	if !ok {
+		utils.RespondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Decode and validate request body
+	req, err := utils.DecodeAndValidate[dto.UpdateProfileRequest](w, r, h.validator)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	// Update profile
+	profile, err := h.authService.UpdateProfile(r.Context(), user, req)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	message := "Profile updated successfully"
+	if req.Email != nil && *req.Email != user.Email {
+		message = "Profile updated, check your new email address to confirm the change"
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, profile, message)
+}
+
+// ChangePassword changes the logged-in user's password and logs out their
+// other sessions
+// PUT /api/user/password
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
+	if !ok {
+		utils.RespondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Decode and validate request body
+	req, err := utils.DecodeAndValidate[dto.ChangePasswordRequest](w, r, h.validator)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	// Change password
+	if err := h.authService.ChangePassword(r.Context(), user, bearerToken(r), req); err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, nil, "Password changed successfully")
+}
+
+// ExportAccount returns an archive of the logged-in user's personal data
+// GET /api/user/export
+func (h *AuthHandler) ExportAccount(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
+	if !ok {
+		utils.RespondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	export, err := h.authService.ExportAccount(r.Context(), user, bearerToken(r))
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", `attachment; filename="account-export.json"`)
+	utils.RespondWithSuccess(w, r, http.StatusOK, export, "")
+}
+
+// DeleteAccount anonymises the logged-in user's account
+// DELETE /api/user
+func (h *AuthHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
+	if !ok {
+		utils.RespondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Decode and validate request body
+	req, err := utils.DecodeAndValidate[dto.DeleteAccountRequest](w, r, h.validator)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	// Delete account
+	if err := h.authService.DeleteAccount(r.Context(), user, req); err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, nil, "Account deleted successfully")
+}
+
+// SetupTwoFactor starts two-factor enrolment for the logged-in user
+// POST /api/user/2fa/setup
+func (h *AuthHandler) SetupTwoFactor(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
+	if !ok {
+		utils.RespondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	setup, err := h.authService.SetupTwoFactor(r.Context(), user)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, setup, "Scan the QR code, then confirm with a code from your authenticator app")
+}
+
+// ConfirmTwoFactor enables two-factor authentication with a first code
+// POST /api/user/2fa/confirm
+func (h *AuthHandler) ConfirmTwoFactor(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
+	if !ok {
+		utils.RespondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Decode and validate request body
+	req, err := utils.DecodeAndValidate[dto.TwoFactorCodeRequest](w, r, h.validator)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	// Enable two-factor
+	codes, err := h.authService.ConfirmTwoFactor(r.Context(), user, req)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, codes, "Two-factor authentication enabled, store these recovery codes safely")
+}
+
+// DisableTwoFactor turns off two-factor authentication
+// POST /api/user/2fa/disable
+func (h *AuthHandler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
+	if !ok {
+		utils.RespondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Decode and validate request body
+	req, err := utils.DecodeAndValidate[dto.DisableTwoFactorRequest](w, r, h.validator)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	// Disable two-factor
+	if err := h.authService.DisableTwoFactor(r.Context(), user, req); err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, nil, "Two-factor authentication disabled")
+}
+
+// RegenerateRecoveryCodes replaces the logged-in user's recovery codes
+// POST /api/user/2fa/recovery-codes
+func (h *AuthHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
+	if !ok {
+		utils.RespondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Decode and validate request body
+	req, err := utils.DecodeAndValidate[dto.TwoFactorCodeRequest](w, r, h.validator)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	// Regenerate codes
+	codes, err := h.authService.RegenerateRecoveryCodes(r.Context(), user, req)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, codes, "Recovery codes regenerated")
+}
+
+// JWKS publishes the public keys access tokens are signed with
+// GET /.well-known/jwks.json
+func (h *AuthHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(h.authService.JWKS())
 }
 
 // Logout handles user logout
@@ -101,20 +439,90 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	// Get user from context (set by auth middleware)
 	user, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
 	if !ok {
-		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		utils.RespondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
 	// Extract token from Authorization header
-	authHeader := r.Header.Get("Authorization")
-	token := authHeader[7:] // Remove "Bearer " prefix
+	token := bearerToken(r)
 
 	// Logout user
 	if err := h.authService.Logout(r.Context(), token); err != nil {
 		h.logger.Error("Failed to logout user", zap.Int("user_id", user.ID), zap.Error(err))
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to logout")
+		utils.RespondWithAppError(w, r, err)
 		return
 	}
 
-	utils.RespondWithSuccess(w, http.StatusOK, nil, "Logout successful")
+	utils.RespondWithSuccess(w, r, http.StatusOK, nil, "Logout successful")
+}
+
+// GetSessions lists the active sessions of the logged-in user
+// GET /api/user/sessions
+func (h *AuthHandler) GetSessions(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
+	if !ok {
+		utils.RespondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Get sessions
+	sessions, err := h.authService.GetSessions(r.Context(), user.ID, bearerToken(r))
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, sessions, "")
+}
+
+// RevokeSession logs out one session of the logged-in user
+// DELETE /api/user/sessions/{sessionId}
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
+	if !ok {
+		utils.RespondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Get session ID from URL
+	sessionID, err := strconv.Atoi(chi.URLParam(r, "sessionId"))
+	if err != nil {
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid session ID")
+		return
+	}
+
+	// Revoke session
+	if err := h.authService.RevokeSession(r.Context(), user.ID, sessionID); err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, nil, "Session revoked successfully")
+}
+
+// RevokeAllSessions logs the user out of every session
+// DELETE /api/user/sessions
+func (h *AuthHandler) RevokeAllSessions(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
+	if !ok {
+		utils.RespondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Revoke all sessions
+	if err := h.authService.RevokeAllSessions(r.Context(), user.ID); err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, nil, "Logged out from all sessions")
+}
+
+// bearerToken extracts the token from the Authorization header; the auth
+// middleware has already checked its format
+func bearerToken(r *http.Request) string {
+	return strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
 }
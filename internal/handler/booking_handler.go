@@ -1,8 +1,9 @@
 package handler
 
 import (
-	"encoding/json"
+	"io"
 	"net/http"
+	"strconv"
 
 	"cinema-booking-system/internal/dto"
 	"cinema-booking-system/internal/middleware"
@@ -10,9 +11,13 @@ import (
 	"cinema-booking-system/internal/service"
 	"cinema-booking-system/internal/utils"
 
+	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 )
 
+// maxWebhookBodyBytes caps the size of a payment webhook payload
+const maxWebhookBodyBytes = 64 << 10
+
 // BookingHandler handles booking-related HTTP requests
 type BookingHandler struct {
 	bookingService *service.BookingService
@@ -35,34 +40,26 @@ func (h *BookingHandler) CreateBooking(w http.ResponseWriter, r *http.Request) {
 	// Get user from context (set by auth middleware)
 	user, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
 	if !ok {
-		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		utils.RespondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	var req dto.BookingRequest
-
-	// Decode request body
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("Failed to decode booking request", zap.Error(err))
-		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
-		return
-	}
-
-	// Validate request
-	if err := h.validator.Validate(req); err != nil {
-		utils.RespondWithValidationError(w, err)
+	// Decode and validate request body
+	req, err := utils.DecodeAndValidate[dto.BookingRequest](w, r, h.validator)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
 		return
 	}
 
 	// Create booking
-	booking, err := h.bookingService.CreateBooking(r.Context(), user.ID, &req)
+	booking, err := h.bookingService.CreateBooking(r.Context(), user.ID, req)
 	if err != nil {
 		h.logger.Error("Failed to create booking", zap.Int("user_id", user.ID), zap.Error(err))
-		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		utils.RespondWithAppError(w, r, err)
 		return
 	}
 
-	utils.RespondWithSuccess(w, http.StatusCreated, booking, "Booking created successfully")
+	utils.RespondWithSuccess(w, r, http.StatusCreated, booking, "Booking created successfully")
 }
 
 // GetUserBookings retrieves booking history for the logged-in user
@@ -71,7 +68,7 @@ func (h *BookingHandler) GetUserBookings(w http.ResponseWriter, r *http.Request)
 	// Get user from context (set by auth middleware)
 	user, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
 	if !ok {
-		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		utils.RespondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
@@ -79,11 +76,38 @@ func (h *BookingHandler) GetUserBookings(w http.ResponseWriter, r *http.Request)
 	bookings, err := h.bookingService.GetUserBookings(r.Context(), user.ID)
 	if err != nil {
 		h.logger.Error("Failed to get user bookings", zap.Int("user_id", user.ID), zap.Error(err))
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get bookings")
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, bookings, "")
+}
+
+// GetShowtimeBookings retrieves the active bookings of a showtime
+// GET /api/admin/showtimes/{showtimeId}/bookings
+func (h *BookingHandler) GetShowtimeBookings(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
+	if !ok {
+		utils.RespondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
-	utils.RespondWithSuccess(w, http.StatusOK, bookings, "")
+	// Get showtime ID from URL
+	showtimeID, err := strconv.Atoi(chi.URLParam(r, "showtimeId"))
+	if err != nil {
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid showtime ID")
+		return
+	}
+
+	// Get bookings
+	bookings, err := h.bookingService.GetShowtimeBookings(r.Context(), user, showtimeID)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, bookings, "")
 }
 
 // ProcessPayment processes payment for a booking
@@ -92,32 +116,82 @@ func (h *BookingHandler) ProcessPayment(w http.ResponseWriter, r *http.Request)
 	// Get user from context (set by auth middleware)
 	user, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
 	if !ok {
-		utils.RespondWithError(w, http.StatusUnauthorized, "Unauthorized")
+		utils.RespondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Decode and validate request body
+	req, err := utils.DecodeAndValidate[dto.PaymentRequest](w, r, h.validator)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
 		return
 	}
 
-	var req dto.PaymentRequest
+	// Process payment
+	booking, err := h.bookingService.ProcessPayment(r.Context(), user.ID, req)
+	if err != nil {
+		h.logger.Error("Failed to process payment", zap.Int("user_id", user.ID), zap.Error(err))
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
 
-	// Decode request body
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.logger.Error("Failed to decode payment request", zap.Error(err))
-		utils.RespondWithError(w, http.StatusBadRequest, "Invalid request body")
+	if booking.PaymentStatus == "processing" {
+		utils.RespondWithSuccess(w, r, http.StatusAccepted, booking, "Payment is awaiting confirmation from the provider")
 		return
 	}
 
-	// Validate request
-	if err := h.validator.Validate(req); err != nil {
-		utils.RespondWithValidationError(w, err)
+	utils.RespondWithSuccess(w, r, http.StatusOK, booking, "Payment processed successfully")
+}
+
+// PaymentWebhook receives asynchronous payment notifications from a provider
+// POST /api/payments/webhook/{provider}
+func (h *BookingHandler) PaymentWebhook(w http.ResponseWriter, r *http.Request) {
+	provider := chi.URLParam(r, "provider")
+
+	// Read raw body; the signature covers the exact bytes sent
+	payload, err := io.ReadAll(io.LimitReader(r.Body, maxWebhookBodyBytes))
+	if err != nil {
+		h.logger.Error("Failed to read webhook body", zap.String("provider", provider), zap.Error(err))
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
-	// Process payment
-	booking, err := h.bookingService.ProcessPayment(r.Context(), user.ID, &req)
+	if err := h.bookingService.HandlePaymentWebhook(r.Context(), provider, payload, r.Header); err != nil {
+		// Internal errors answer 500, which makes the provider retry the delivery later
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, nil, "Webhook processed successfully")
+}
+
+// CancelBooking cancels a booking owned by the logged-in user
+// POST /api/bookings/{bookingId}/cancel
+func (h *BookingHandler) CancelBooking(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
+	if !ok {
+		utils.RespondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Get booking ID from URL
+	bookingID, err := strconv.Atoi(chi.URLParam(r, "bookingId"))
 	if err != nil {
-		h.logger.Error("Failed to process payment", zap.Int("user_id", user.ID), zap.Error(err))
-		utils.RespondWithError(w, http.StatusBadRequest, err.Error())
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid booking ID")
+		return
+	}
+
+	// Cancel booking
+	result, err := h.bookingService.CancelBooking(r.Context(), user.ID, bookingID)
+	if err != nil {
+		h.logger.Error("Failed to cancel booking",
+			zap.Int("user_id", user.ID),
+			zap.Int("booking_id", bookingID),
+			zap.Error(err))
+		utils.RespondWithAppError(w, r, err)
 		return
 	}
 
-	utils.RespondWithSuccess(w, http.StatusOK, booking, "Payment processed successfully")
+	utils.RespondWithSuccess(w, r, http.StatusOK, result, "Booking cancelled successfully")
 }
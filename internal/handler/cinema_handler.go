@@ -4,6 +4,9 @@ import (
 	"net/http"
 	"strconv"
 
+	"cinema-booking-system/internal/dto"
+	"cinema-booking-system/internal/middleware"
+	"cinema-booking-system/internal/models"
 	"cinema-booking-system/internal/service"
 	"cinema-booking-system/internal/utils"
 
@@ -14,13 +17,15 @@ import (
 // CinemaHandler handles cinema-related HTTP requests
 type CinemaHandler struct {
 	cinemaService *service.CinemaService
+	validator     *utils.Validator
 	logger        *zap.Logger
 }
 
 // NewCinemaHandler creates a new cinema handler
-func NewCinemaHandler(cinemaService *service.CinemaService, logger *zap.Logger) *CinemaHandler {
+func NewCinemaHandler(cinemaService *service.CinemaService, validator *utils.Validator, logger *zap.Logger) *CinemaHandler {
 	return &CinemaHandler{
 		cinemaService: cinemaService,
+		validator:     validator,
 		logger:        logger,
 	}
 }
@@ -44,7 +49,7 @@ func (h *CinemaHandler) GetAllCinemas(w http.ResponseWriter, r *http.Request) {
 	result, err := h.cinemaService.GetAllCinemas(r.Context(), page, pageSize)
 	if err != nil {
 		h.logger.Error("Failed to get cinemas", zap.Error(err))
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get cinemas")
+		utils.RespondWithAppError(w, r, err)
 		return
 	}
 
@@ -58,7 +63,7 @@ func (h *CinemaHandler) GetCinemaByID(w http.ResponseWriter, r *http.Request) {
 	cinemaIDStr := chi.URLParam(r, "cinemaId")
 	cinemaID, err := strconv.Atoi(cinemaIDStr)
 	if err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, "Invalid cinema ID")
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid cinema ID")
 		return
 	}
 
@@ -66,45 +71,148 @@ func (h *CinemaHandler) GetCinemaByID(w http.ResponseWriter, r *http.Request) {
 	cinema, err := h.cinemaService.GetCinemaByID(r.Context(), cinemaID)
 	if err != nil {
 		h.logger.Error("Failed to get cinema", zap.Int("cinema_id", cinemaID), zap.Error(err))
-		utils.RespondWithError(w, http.StatusNotFound, "Cinema not found")
+		utils.RespondWithAppError(w, r, err)
 		return
 	}
 
-	utils.RespondWithSuccess(w, http.StatusOK, cinema, "")
+	utils.RespondWithSuccess(w, r, http.StatusOK, cinema, "")
 }
 
-// GetSeatsAvailability retrieves seat availability for a specific showtime
-// GET /api/cinemas/{cinemaId}/seats?date={date}&time={time}
-func (h *CinemaHandler) GetSeatsAvailability(w http.ResponseWriter, r *http.Request) {
+// CreateCinema creates a new cinema
+// POST /api/admin/cinemas
+func (h *CinemaHandler) CreateCinema(w http.ResponseWriter, r *http.Request) {
+	// Decode and validate request body
+	req, err := utils.DecodeAndValidate[dto.CinemaRequest](w, r, h.validator)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	// Create cinema
+	cinema, err := h.cinemaService.CreateCinema(r.Context(), req)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusCreated, cinema, "Cinema created successfully")
+}
+
+// UpdateCinema updates cinema details
+// PUT /api/admin/cinemas/{cinemaId}
+func (h *CinemaHandler) UpdateCinema(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
+	if !ok {
+		utils.RespondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
 	// Get cinema ID from URL
-	cinemaIDStr := chi.URLParam(r, "cinemaId")
-	cinemaID, err := strconv.Atoi(cinemaIDStr)
+	cinemaID, err := strconv.Atoi(chi.URLParam(r, "cinemaId"))
 	if err != nil {
-		utils.RespondWithError(w, http.StatusBadRequest, "Invalid cinema ID")
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid cinema ID")
 		return
 	}
 
-	// Get query parameters
-	date := r.URL.Query().Get("date")
-	time := r.URL.Query().Get("time")
+	// Decode and validate request body
+	req, err := utils.DecodeAndValidate[dto.CinemaRequest](w, r, h.validator)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
 
-	// Validate required parameters
-	if date == "" || time == "" {
-		utils.RespondWithError(w, http.StatusBadRequest, "Date and time parameters are required")
+	// Update cinema
+	cinema, err := h.cinemaService.UpdateCinema(r.Context(), user, cinemaID, req)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
 		return
 	}
 
-	// Get seat availability
-	seats, err := h.cinemaService.GetSeatsAvailability(r.Context(), cinemaID, date, time)
+	utils.RespondWithSuccess(w, r, http.StatusOK, cinema, "Cinema updated successfully")
+}
+
+// DeleteCinema deletes a cinema without upcoming bookings
+// DELETE /api/admin/cinemas/{cinemaId}
+func (h *CinemaHandler) DeleteCinema(w http.ResponseWriter, r *http.Request) {
+	// Get cinema ID from URL
+	cinemaID, err := strconv.Atoi(chi.URLParam(r, "cinemaId"))
 	if err != nil {
-		h.logger.Error("Failed to get seat availability",
-			zap.Int("cinema_id", cinemaID),
-			zap.String("date", date),
-			zap.String("time", time),
-			zap.Error(err))
-		utils.RespondWithError(w, http.StatusInternalServerError, err.Error())
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid cinema ID")
+		return
+	}
+
+	// Delete cinema
+	if err := h.cinemaService.DeleteCinema(r.Context(), cinemaID); err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, nil, "Cinema deleted successfully")
+}
+
+// DefineSeatLayout creates or reprices seats for ranges of rows
+// PUT /api/admin/cinemas/{cinemaId}/seats
+func (h *CinemaHandler) DefineSeatLayout(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
+	if !ok {
+		utils.RespondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Get cinema ID from URL
+	cinemaID, err := strconv.Atoi(chi.URLParam(r, "cinemaId"))
+	if err != nil {
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid cinema ID")
+		return
+	}
+
+	// Decode and validate request body
+	req, err := utils.DecodeAndValidate[dto.SeatLayoutRequest](w, r, h.validator)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	// Save layout
+	seats, err := h.cinemaService.DefineSeatLayout(r.Context(), user, cinemaID, req)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, seats, "Seat layout saved successfully")
+}
+
+// DeleteSeat removes a seat from a cinema
+// DELETE /api/admin/cinemas/{cinemaId}/seats/{seatId}
+func (h *CinemaHandler) DeleteSeat(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
+	if !ok {
+		utils.RespondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Get IDs from URL
+	cinemaID, err := strconv.Atoi(chi.URLParam(r, "cinemaId"))
+	if err != nil {
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid cinema ID")
+		return
+	}
+
+	seatID, err := strconv.Atoi(chi.URLParam(r, "seatId"))
+	if err != nil {
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid seat ID")
+		return
+	}
+
+	// Delete seat
+	if err := h.cinemaService.DeleteSeat(r.Context(), user, cinemaID, seatID); err != nil {
+		utils.RespondWithAppError(w, r, err)
 		return
 	}
 
-	utils.RespondWithSuccess(w, http.StatusOK, seats, "")
+	utils.RespondWithSuccess(w, r, http.StatusOK, nil, "Seat deleted successfully")
 }
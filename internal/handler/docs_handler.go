@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"io/fs"
+	"net/http"
+
+	"cinema-booking-system/internal/openapi"
+	"cinema-booking-system/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// DocsHandler serves the API documentation
+type DocsHandler struct{}
+
+// NewDocsHandler creates a new docs handler
+func NewDocsHandler() *DocsHandler {
+	return &DocsHandler{}
+}
+
+// OpenAPI serves the OpenAPI document
+func (h *DocsHandler) OpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(openapi.Spec())
+}
+
+// SwaggerUI serves a Swagger UI page for the OpenAPI document
+func (h *DocsHandler) SwaggerUI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(openapi.SwaggerUI())
+}
+
+// SwaggerAsset serves a script or stylesheet of the Swagger UI page
+func (h *DocsHandler) SwaggerAsset(w http.ResponseWriter, r *http.Request) {
+	assets := openapi.SwaggerAssets()
+	name := chi.URLParam(r, "asset")
+	if _, err := fs.Stat(assets, name); err != nil {
+		utils.RespondWithError(w, r, http.StatusNotFound, "asset not found")
+		return
+	}
+
+	http.ServeFileFS(w, r, assets, name)
+}
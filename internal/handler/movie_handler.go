@@ -0,0 +1,123 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"cinema-booking-system/internal/dto"
+	"cinema-booking-system/internal/service"
+	"cinema-booking-system/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+// MovieHandler handles movie-related HTTP requests
+type MovieHandler struct {
+	movieService *service.MovieService
+	validator    *utils.Validator
+	logger       *zap.Logger
+}
+
+// NewMovieHandler creates a new movie handler
+func NewMovieHandler(movieService *service.MovieService, validator *utils.Validator, logger *zap.Logger) *MovieHandler {
+	return &MovieHandler{
+		movieService: movieService,
+		validator:    validator,
+		logger:       logger,
+	}
+}
+
+// GetAllMovies retrieves all movies with pagination
+// GET /api/movies?page=1&page_size=10
+func (h *MovieHandler) GetAllMovies(w http.ResponseWriter, r *http.Request) {
+	// Get pagination parameters
+	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+	pageSize, _ := strconv.Atoi(r.URL.Query().Get("page_size"))
+
+	// Set defaults
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	// Get movies
+	result, err := h.movieService.GetAllMovies(r.Context(), page, pageSize)
+	if err != nil {
+		h.logger.Error("Failed to get movies", zap.Error(err))
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithJSON(w, http.StatusOK, result)
+}
+
+// GetMovieByID retrieves a specific movie by ID
+// GET /api/movies/{movieId}
+func (h *MovieHandler) GetMovieByID(w http.ResponseWriter, r *http.Request) {
+	// Get movie ID from URL
+	movieID, err := strconv.Atoi(chi.URLParam(r, "movieId"))
+	if err != nil {
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid movie ID")
+		return
+	}
+
+	// Get movie
+	movie, err := h.movieService.GetMovieByID(r.Context(), movieID)
+	if err != nil {
+		h.logger.Error("Failed to get movie", zap.Int("movie_id", movieID), zap.Error(err))
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, movie, "")
+}
+
+// GetMovieShowtimes retrieves the showtimes of a movie across cinemas
+// GET /api/movies/{movieId}/showtimes?date={date}
+func (h *MovieHandler) GetMovieShowtimes(w http.ResponseWriter, r *http.Request) {
+	// Get movie ID from URL
+	movieID, err := strconv.Atoi(chi.URLParam(r, "movieId"))
+	if err != nil {
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid movie ID")
+		return
+	}
+
+	// Optional date filter (YYYY-MM-DD)
+	date := r.URL.Query().Get("date")
+
+	// Get showtimes
+	showtimes, err := h.movieService.GetMovieShowtimes(r.Context(), movieID, date)
+	if err != nil {
+		h.logger.Error("Failed to get movie showtimes",
+			zap.Int("movie_id", movieID),
+			zap.String("date", date),
+			zap.Error(err))
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, showtimes, "")
+}
+
+// CreateMovie adds a new movie to the catalogue
+// POST /api/admin/movies
+func (h *MovieHandler) CreateMovie(w http.ResponseWriter, r *http.Request) {
+	// Decode and validate request body
+	req, err := utils.DecodeAndValidate[dto.CreateMovieRequest](w, r, h.validator)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	// Create movie
+	movie, err := h.movieService.CreateMovie(r.Context(), req)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusCreated, movie, "Movie created successfully")
+}
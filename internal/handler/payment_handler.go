@@ -29,9 +29,9 @@ func (h *PaymentHandler) GetAllPaymentMethods(w http.ResponseWriter, r *http.Req
 	methods, err := h.paymentService.GetAllPaymentMethods(r.Context())
 	if err != nil {
 		h.logger.Error("Failed to get payment methods", zap.Error(err))
-		utils.RespondWithError(w, http.StatusInternalServerError, "Failed to get payment methods")
+		utils.RespondWithAppError(w, r, err)
 		return
 	}
 
-	utils.RespondWithSuccess(w, http.StatusOK, methods, "")
+	utils.RespondWithSuccess(w, r, http.StatusOK, methods, "")
 }
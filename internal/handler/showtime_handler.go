@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"cinema-booking-system/internal/dto"
+	"cinema-booking-system/internal/middleware"
+	"cinema-booking-system/internal/models"
+	"cinema-booking-system/internal/service"
+	"cinema-booking-system/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+// ShowtimeHandler handles showtime-related HTTP requests
+type ShowtimeHandler struct {
+	showtimeService *service.ShowtimeService
+	validator       *utils.Validator
+	logger          *zap.Logger
+}
+
+// NewShowtimeHandler creates a new showtime handler
+func NewShowtimeHandler(showtimeService *service.ShowtimeService, validator *utils.Validator, logger *zap.Logger) *ShowtimeHandler {
+	return &ShowtimeHandler{
+		showtimeService: showtimeService,
+		validator:       validator,
+		logger:          logger,
+	}
+}
+
+// GetShowtimeByID retrieves a specific showtime by ID
+// GET /api/showtimes/{showtimeId}
+func (h *ShowtimeHandler) GetShowtimeByID(w http.ResponseWriter, r *http.Request) {
+	// Get showtime ID from URL
+	showtimeID, err := strconv.Atoi(chi.URLParam(r, "showtimeId"))
+	if err != nil {
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid showtime ID")
+		return
+	}
+
+	// Get showtime
+	showtime, err := h.showtimeService.GetShowtimeByID(r.Context(), showtimeID)
+	if err != nil {
+		h.logger.Error("Failed to get showtime", zap.Int("showtime_id", showtimeID), zap.Error(err))
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, showtime, "")
+}
+
+// GetCinemaShowtimes retrieves the schedule of a cinema
+// GET /api/cinemas/{cinemaId}/showtimes?date={date}
+func (h *ShowtimeHandler) GetCinemaShowtimes(w http.ResponseWriter, r *http.Request) {
+	// Get cinema ID from URL
+	cinemaID, err := strconv.Atoi(chi.URLParam(r, "cinemaId"))
+	if err != nil {
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid cinema ID")
+		return
+	}
+
+	// Optional date filter (YYYY-MM-DD)
+	date := r.URL.Query().Get("date")
+
+	// Get showtimes
+	showtimes, err := h.showtimeService.GetCinemaShowtimes(r.Context(), cinemaID, date)
+	if err != nil {
+		h.logger.Error("Failed to get cinema showtimes",
+			zap.Int("cinema_id", cinemaID),
+			zap.String("date", date),
+			zap.Error(err))
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, showtimes, "")
+}
+
+// GetSeatsAvailability retrieves seat availability for a specific showtime
+// GET /api/showtimes/{showtimeId}/seats
+func (h *ShowtimeHandler) GetSeatsAvailability(w http.ResponseWriter, r *http.Request) {
+	// Get showtime ID from URL
+	showtimeID, err := strconv.Atoi(chi.URLParam(r, "showtimeId"))
+	if err != nil {
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid showtime ID")
+		return
+	}
+
+	// Get seat availability
+	seats, err := h.showtimeService.GetSeatsAvailability(r.Context(), showtimeID)
+	if err != nil {
+		h.logger.Error("Failed to get seat availability",
+			zap.Int("showtime_id", showtimeID),
+			zap.Error(err))
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, seats, "")
+}
+
+// CreateShowtime schedules a movie in a cinema
+// POST /api/admin/showtimes
+func (h *ShowtimeHandler) CreateShowtime(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	user, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
+	if !ok {
+		utils.RespondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Decode and validate request body
+	req, err := utils.DecodeAndValidate[dto.CreateShowtimeRequest](w, r, h.validator)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	// Create showtime
+	showtime, err := h.showtimeService.CreateShowtime(r.Context(), user, req)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusCreated, showtime, "Showtime created successfully")
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+
+	"cinema-booking-system/internal/dto"
+	"cinema-booking-system/internal/middleware"
+	"cinema-booking-system/internal/models"
+	"cinema-booking-system/internal/service"
+	"cinema-booking-system/internal/utils"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+// UserHandler handles user administration HTTP requests
+type UserHandler struct {
+	userService *service.UserService
+	validator   *utils.Validator
+	logger      *zap.Logger
+}
+
+// NewUserHandler creates a new user handler
+func NewUserHandler(userService *service.UserService, validator *utils.Validator, logger *zap.Logger) *UserHandler {
+	return &UserHandler{
+		userService: userService,
+		validator:   validator,
+		logger:      logger,
+	}
+}
+
+// UpdateUserRole changes the role of a user
+// PUT /api/admin/users/{userId}/role
+func (h *UserHandler) UpdateUserRole(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	actor, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
+	if !ok {
+		utils.RespondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Get user ID from URL
+	userID, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	// Decode and validate request body
+	req, err := utils.DecodeAndValidate[dto.UpdateUserRoleRequest](w, r, h.validator)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	// Update role
+	user, err := h.userService.UpdateUserRole(r.Context(), actor.ID, userID, req.Role)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, user, "User role updated successfully")
+}
+
+// UnlockUser lifts a login lockout of a user
+// POST /api/admin/users/{userId}/unlock
+func (h *UserHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	// Get user from context (set by auth middleware)
+	actor, ok := r.Context().Value(middleware.UserContextKey).(*models.User)
+	if !ok {
+		utils.RespondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+		return
+	}
+
+	// Get user ID from URL
+	userID, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	// Unlock user
+	if err := h.userService.UnlockUser(r.Context(), actor.ID, userID); err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, nil, "User unlocked successfully")
+}
+
+// AssignCinemaManager makes a cinema manager responsible for a cinema
+// POST /api/admin/cinemas/{cinemaId}/managers
+func (h *UserHandler) AssignCinemaManager(w http.ResponseWriter, r *http.Request) {
+	// Get cinema ID from URL
+	cinemaID, err := strconv.Atoi(chi.URLParam(r, "cinemaId"))
+	if err != nil {
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid cinema ID")
+		return
+	}
+
+	// Decode and validate request body
+	req, err := utils.DecodeAndValidate[dto.CinemaManagerRequest](w, r, h.validator)
+	if err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	// Assign manager
+	if err := h.userService.AssignCinemaManager(r.Context(), cinemaID, req.UserID); err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, nil, "Cinema manager assigned successfully")
+}
+
+// RemoveCinemaManager removes a manager from a cinema
+// DELETE /api/admin/cinemas/{cinemaId}/managers/{userId}
+func (h *UserHandler) RemoveCinemaManager(w http.ResponseWriter, r *http.Request) {
+	// Get IDs from URL
+	cinemaID, err := strconv.Atoi(chi.URLParam(r, "cinemaId"))
+	if err != nil {
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid cinema ID")
+		return
+	}
+
+	userID, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid user ID")
+		return
+	}
+
+	// Remove manager
+	if err := h.userService.RemoveCinemaManager(r.Context(), cinemaID, userID); err != nil {
+		utils.RespondWithAppError(w, r, err)
+		return
+	}
+
+	utils.RespondWithSuccess(w, r, http.StatusOK, nil, "Cinema manager removed successfully")
+}
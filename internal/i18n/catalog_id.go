@@ -0,0 +1,192 @@
+package i18n
+
+// indonesian holds the Indonesian translations of client-facing messages
+var indonesian = map[string]string{
+	// Generic responses
+	"Internal server error": "Terjadi kesalahan pada server",
+	"Invalid request body":  "Body request tidak valid",
+	"Validation failed":     "Validasi gagal",
+	"Unauthorized":          "Tidak terautentikasi",
+	"asset not found":       "aset tidak ditemukan",
+
+	// Request bodies
+	"Content-Type must be application/json":          "Content-Type harus application/json",
+	"request body must not exceed %d bytes":          "body request tidak boleh melebihi %d byte",
+	"request body is empty":                          "body request kosong",
+	"request body contains malformed JSON":           "body request berisi JSON yang tidak valid",
+	"request body contains unknown field %s":         "body request mengandung field yang tidak dikenal %s",
+	"request body must contain a single JSON object": "body request hanya boleh berisi satu objek JSON",
+	"field %s must be of type %s":                    "field %s harus bertipe %s",
+
+	// Authentication
+	"Missing authorization header":                                           "Header Authorization tidak ditemukan",
+	"Invalid authorization header format":                                    "Format header Authorization tidak valid",
+	"Invalid or expired token":                                               "Token tidak valid atau sudah kedaluwarsa",
+	"Insufficient permissions":                                               "Hak akses tidak mencukupi",
+	"Email address is not verified":                                          "Alamat email belum diverifikasi",
+	"User registered successfully":                                           "Registrasi pengguna berhasil",
+	"Login successful":                                                       "Login berhasil",
+	"Logout successful":                                                      "Logout berhasil",
+	"Logged out from all sessions":                                           "Berhasil logout dari semua sesi",
+	"Token refreshed successfully":                                           "Token berhasil diperbarui",
+	"Session revoked successfully":                                           "Sesi berhasil dicabut",
+	"Password changed successfully":                                          "Password berhasil diubah",
+	"Password has been reset, please log in again":                           "Password telah direset, silakan login kembali",
+	"If the email is registered, a reset link has been sent":                 "Jika email terdaftar, tautan reset telah dikirim",
+	"Missing verification token":                                             "Token verifikasi tidak ditemukan",
+	"Email verified successfully":                                            "Email berhasil diverifikasi",
+	"Verification email sent":                                                "Email verifikasi telah dikirim",
+	"Profile updated successfully":                                           "Profil berhasil diperbarui",
+	"Profile updated, check your new email address to confirm the change":    "Profil diperbarui, periksa alamat email baru Anda untuk mengonfirmasi perubahan",
+	"Account deleted successfully":                                           "Akun berhasil dihapus",
+	"Two-factor authentication required":                                     "Autentikasi dua faktor diperlukan",
+	"Scan the QR code, then confirm with a code from your authenticator app": "Pindai kode QR, lalu konfirmasi dengan kode dari aplikasi autentikator Anda",
+	"Two-factor authentication enabled, store these recovery codes safely":   "Autentikasi dua faktor diaktifkan, simpan kode pemulihan ini dengan aman",
+	"Two-factor authentication disabled":                                     "Autentikasi dua faktor dinonaktifkan",
+	"Recovery codes regenerated":                                             "Kode pemulihan telah dibuat ulang",
+	"invalid username or password":                                           "username atau password salah",
+	"invalid token":                                                          "token tidak valid",
+	"invalid or expired refresh token":                                       "refresh token tidak valid atau sudah kedaluwarsa",
+	"refresh token not found":                                                "refresh token tidak ditemukan",
+	"refresh token reuse detected":                                           "terdeteksi penggunaan ulang refresh token",
+	"invalid or expired reset token":                                         "token reset tidak valid atau sudah kedaluwarsa",
+	"reset token not found or expired":                                       "token reset tidak ditemukan atau sudah kedaluwarsa",
+	"invalid or expired verification token":                                  "token verifikasi tidak valid atau sudah kedaluwarsa",
+	"verification token not found or expired":                                "token verifikasi tidak ditemukan atau sudah kedaluwarsa",
+	"token not found or expired":                                             "token tidak ditemukan atau sudah kedaluwarsa",
+	"invalid or expired challenge token":                                     "challenge token tidak valid atau sudah kedaluwarsa",
+	"invalid two-factor code":                                                "kode dua faktor tidak valid",
+	"no pending two-factor setup":                                            "tidak ada pengaturan dua faktor yang tertunda",
+	"two-factor authentication is already enabled":                           "autentikasi dua faktor sudah aktif",
+	"two-factor authentication is not enabled":                               "autentikasi dua faktor belum aktif",
+	"current password is incorrect":                                          "password saat ini salah",
+	"password is incorrect":                                                  "password salah",
+	"new_password must not contain your username or email":                   "new_password tidak boleh mengandung username atau email Anda",
+	"too many requests, please try again in %d seconds":                      "terlalu banyak permintaan, silakan coba lagi dalam %d detik",
+	"Invalid session ID":                                                     "ID sesi tidak valid",
+	"session not found":                                                      "sesi tidak ditemukan",
+	"username already exists":                                                "username sudah digunakan",
+	"email already exists":                                                   "email sudah digunakan",
+	"email is already used by another account":                               "email sudah digunakan oleh akun lain",
+	"email is already verified":                                              "email sudah diverifikasi",
+	"no profile changes provided":                                            "tidak ada perubahan profil",
+
+	// Users
+	"Invalid user ID":                        "ID pengguna tidak valid",
+	"User role updated successfully":         "Role pengguna berhasil diperbarui",
+	"User unlocked successfully":             "Pengguna berhasil dibuka kuncinya",
+	"user not found":                         "pengguna tidak ditemukan",
+	"you cannot change your own role":        "Anda tidak dapat mengubah role Anda sendiri",
+	"user must have the cinema_manager role": "pengguna harus memiliki role cinema_manager",
+
+	// Movies, cinemas and showtimes
+	"Invalid movie ID":                                "ID film tidak valid",
+	"Invalid cinema ID":                               "ID bioskop tidak valid",
+	"Invalid showtime ID":                             "ID jadwal tayang tidak valid",
+	"Invalid seat ID":                                 "ID kursi tidak valid",
+	"Movie created successfully":                      "Film berhasil dibuat",
+	"Cinema created successfully":                     "Bioskop berhasil dibuat",
+	"Cinema updated successfully":                     "Bioskop berhasil diperbarui",
+	"Cinema deleted successfully":                     "Bioskop berhasil dihapus",
+	"Cinema manager assigned successfully":            "Manajer bioskop berhasil ditugaskan",
+	"Cinema manager removed successfully":             "Manajer bioskop berhasil dihapus",
+	"Seat layout saved successfully":                  "Denah kursi berhasil disimpan",
+	"Seat deleted successfully":                       "Kursi berhasil dihapus",
+	"Showtime created successfully":                   "Jadwal tayang berhasil dibuat",
+	"movie not found":                                 "film tidak ditemukan",
+	"cinema not found":                                "bioskop tidak ditemukan",
+	"cinema manager not found":                        "manajer bioskop tidak ditemukan",
+	"showtime not found":                              "jadwal tayang tidak ditemukan",
+	"seat not found":                                  "kursi tidak ditemukan",
+	"you do not have access to this cinema":           "Anda tidak memiliki akses ke bioskop ini",
+	"cinema has bookings and cannot be deleted":       "bioskop memiliki riwayat pemesanan dan tidak dapat dihapus",
+	"seat has bookings and cannot be deleted":         "kursi memiliki riwayat pemesanan dan tidak dapat dihapus",
+	"cinema already has a showtime in this time slot": "bioskop sudah memiliki jadwal tayang pada slot waktu ini",
+	"showtime must start in the future":               "jadwal tayang harus dimulai di masa depan",
+	"showtime has already started":                    "jadwal tayang sudah dimulai",
+	"invalid date or time format":                     "format tanggal atau waktu tidak valid",
+	"invalid row range %s-%s":                         "rentang baris %s-%s tidak valid",
+	"row %c is defined more than once":                "baris %c didefinisikan lebih dari sekali",
+
+	// Bookings
+	"Invalid booking ID":                                                  "ID pemesanan tidak valid",
+	"Booking created successfully":                                        "Pemesanan berhasil dibuat",
+	"Booking cancelled successfully":                                      "Pemesanan berhasil dibatalkan",
+	"booking not found":                                                   "pemesanan tidak ditemukan",
+	"you do not have access to this booking":                              "Anda tidak memiliki akses ke pemesanan ini",
+	"seat already taken":                                                  "kursi sudah dipesan",
+	"seat already taken for this showtime: %s":                            "kursi sudah dipesan untuk jadwal tayang ini: %s",
+	"seat %s does not belong to the showtime's cinema":                    "kursi %s bukan milik bioskop jadwal tayang ini",
+	"booking cannot be cancelled":                                         "pemesanan tidak dapat dibatalkan",
+	"booking is already %s and cannot be cancelled":                       "pemesanan sudah berstatus %s dan tidak dapat dibatalkan",
+	"bookings cannot be cancelled less than %d hours before the showtime": "pemesanan tidak dapat dibatalkan kurang dari %d jam sebelum jadwal tayang",
+	"booking cannot be paid":                                              "pemesanan tidak dapat dibayar",
+	"booking is already paid":                                             "pemesanan sudah dibayar",
+	"booking is not an active reservation":                                "pemesanan bukan reservasi yang aktif",
+	"booking hold has expired, please book again":                         "waktu penahanan pemesanan sudah habis, silakan pesan kembali",
+
+	// Payments
+	"Payment processed successfully":                     "Pembayaran berhasil diproses",
+	"Payment is awaiting confirmation from the provider": "Pembayaran menunggu konfirmasi dari penyedia",
+	"Webhook processed successfully":                     "Webhook berhasil diproses",
+	"invalid payment method":                             "metode pembayaran tidak valid",
+	"payment method not found":                           "metode pembayaran tidak ditemukan",
+	"payment method is currently unavailable":            "metode pembayaran sedang tidak tersedia",
+	"payment not found":                                  "pembayaran tidak ditemukan",
+	"payment declined":                                   "pembayaran ditolak",
+	"payment declined: %s":                               "pembayaran ditolak: %s",
+	"payment is already being processed":                 "pembayaran sedang diproses",
+	"payment gateway timed out, please try again":        "payment gateway tidak merespons, silakan coba lagi",
+	"unknown payment provider":                           "penyedia pembayaran tidak dikenal",
+	"invalid webhook signature":                          "signature webhook tidak valid",
+	"invalid webhook payload":                            "payload webhook tidak valid",
+
+	// Idempotency
+	"Idempotency-Key is too long":                                  "Idempotency-Key terlalu panjang",
+	"idempotency key not found":                                    "idempotency key tidak ditemukan",
+	"a request with this Idempotency-Key is still being processed": "request dengan Idempotency-Key ini masih diproses",
+	"idempotency key was already used for a different request":     "idempotency key sudah digunakan untuk request lain",
+
+	// Unexpected failures
+	"failed to assign cinema manager":             "gagal menugaskan manajer bioskop",
+	"failed to cancel booking":                    "gagal membatalkan pemesanan",
+	"failed to change password":                   "gagal mengubah password",
+	"failed to check cinema access":               "gagal memeriksa akses bioskop",
+	"failed to count cinemas":                     "gagal menghitung bioskop",
+	"failed to count movies":                      "gagal menghitung film",
+	"failed to create booking":                    "gagal membuat pemesanan",
+	"failed to create cinema":                     "gagal membuat bioskop",
+	"failed to create movie":                      "gagal membuat film",
+	"failed to create showtime":                   "gagal membuat jadwal tayang",
+	"failed to create user":                       "gagal membuat pengguna",
+	"failed to delete account":                    "gagal menghapus akun",
+	"failed to disable two-factor authentication": "gagal menonaktifkan autentikasi dua faktor",
+	"failed to enable two-factor authentication":  "gagal mengaktifkan autentikasi dua faktor",
+	"failed to export account":                    "gagal mengekspor akun",
+	"failed to generate token":                    "gagal membuat token",
+	"failed to get bookings":                      "gagal mengambil pemesanan",
+	"failed to get cinemas":                       "gagal mengambil bioskop",
+	"failed to get movies":                        "gagal mengambil film",
+	"failed to get payment methods":               "gagal mengambil metode pembayaran",
+	"failed to get profile":                       "gagal mengambil profil",
+	"failed to get seat availability":             "gagal mengambil ketersediaan kursi",
+	"failed to get seats":                         "gagal mengambil kursi",
+	"failed to get sessions":                      "gagal mengambil sesi",
+	"failed to get showtimes":                     "gagal mengambil jadwal tayang",
+	"failed to hash password":                     "gagal memproses password",
+	"failed to login":                             "gagal login",
+	"failed to logout":                            "gagal logout",
+	"failed to process idempotency key":           "gagal memproses idempotency key",
+	"failed to process payment":                   "gagal memproses pembayaran",
+	"failed to process webhook":                   "gagal memproses webhook",
+	"failed to regenerate recovery codes":         "gagal membuat ulang kode pemulihan",
+	"failed to release idempotency key":           "gagal melepas idempotency key",
+	"failed to revoke sessions":                   "gagal mencabut sesi",
+	"failed to save seat layout":                  "gagal menyimpan denah kursi",
+	"failed to send verification email":           "gagal mengirim email verifikasi",
+	"failed to set up two-factor authentication":  "gagal menyiapkan autentikasi dua faktor",
+	"failed to store idempotent response":         "gagal menyimpan respons idempoten",
+	"failed to store token":                       "gagal menyimpan token",
+	"failed to unlock user":                       "gagal membuka kunci pengguna",
+	"failed to update profile":                    "gagal memperbarui profil",
+}
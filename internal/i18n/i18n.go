@@ -0,0 +1,105 @@
+// Package i18n localises the messages the API sends to clients. English is
+// the source language: messages are written in English throughout the code and
+// looked up in the catalogue of the requested locale when a response is sent.
+package i18n
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Supported locales
+const (
+	English    = "en"
+	Indonesian = "id"
+)
+
+// Supported lists the locales messages can be served in
+var Supported = []string{English, Indonesian}
+
+// catalogs maps a locale to its translations, keyed by the English message
+var catalogs = map[string]map[string]string{
+	Indonesian: indonesian,
+}
+
+type contextKey struct{}
+
+// IsSupported reports whether messages can be served in locale
+func IsSupported(locale string) bool {
+	for _, supported := range Supported {
+		if locale == supported {
+			return true
+		}
+	}
+	return false
+}
+
+// WithLocale returns a copy of ctx that carries locale
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, contextKey{}, locale)
+}
+
+// FromContext returns the locale stored in ctx, or English when there is none
+func FromContext(ctx context.Context) string {
+	if locale, ok := ctx.Value(contextKey{}).(string); ok {
+		return locale
+	}
+	return English
+}
+
+// T translates an English message into locale and formats it with args.
+// Messages missing from the catalogue are sent in English.
+func T(locale, message string, args ...interface{}) string {
+	if translated, ok := catalogs[locale][message]; ok {
+		message = translated
+	}
+	if len(args) > 0 {
+		return fmt.Sprintf(message, args...)
+	}
+	return message
+}
+
+// Negotiate picks the supported locale the client prefers most from an
+// Accept-Language header, or fallback when none of them is supported
+func Negotiate(acceptLanguage, fallback string) string {
+	type preference struct {
+		locale  string
+		quality float64
+	}
+
+	var preferences []preference
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if !IsSupported(primary) {
+			continue
+		}
+
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			q, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = q
+		}
+		if quality <= 0 {
+			continue
+		}
+
+		preferences = append(preferences, preference{locale: primary, quality: quality})
+	}
+
+	if len(preferences) == 0 {
+		return fallback
+	}
+
+	// Stable so that equally weighted locales keep the client's order
+	sort.SliceStable(preferences, func(i, j int) bool {
+		return preferences[i].quality > preferences[j].quality
+	})
+	return preferences[0].locale
+}
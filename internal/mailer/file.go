@@ -0,0 +1,47 @@
+package mailer
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileMailer writes each email as an .eml file into a directory, so messages
+// can be inspected without an SMTP server
+type FileMailer struct {
+	from string
+	dir  string
+}
+
+// NewFileMailer creates a new file mailer, creating dir if needed
+func NewFileMailer(from, dir string) (*FileMailer, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create mail directory: %w", err)
+	}
+
+	return &FileMailer{
+		from: from,
+		dir:  dir,
+	}, nil
+}
+
+// Send writes the message to a new file
+func (m *FileMailer) Send(ctx context.Context, msg Message) error {
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+
+	now := time.Now()
+	name := fmt.Sprintf("%s-%s.eml", now.Format("20060102T150405"), hex.EncodeToString(suffix))
+	content := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nDate: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n",
+		m.from, msg.To, msg.Subject, now.Format(time.RFC1123Z), msg.Body)
+
+	if err := os.WriteFile(filepath.Join(m.dir, name), []byte(content), 0o600); err != nil {
+		return fmt.Errorf("failed to write email: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package mailer
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// LogMailer writes emails to the application log instead of sending them.
+// Intended for development only, since message bodies may contain secrets.
+type LogMailer struct {
+	from   string
+	logger *zap.Logger
+}
+
+// NewLogMailer creates a new log mailer
+func NewLogMailer(from string, logger *zap.Logger) *LogMailer {
+	return &LogMailer{
+		from:   from,
+		logger: logger,
+	}
+}
+
+// Send logs the message
+func (m *LogMailer) Send(ctx context.Context, msg Message) error {
+	m.logger.Info("Email sent",
+		zap.String("from", m.from),
+		zap.String("to", msg.To),
+		zap.String("subject", msg.Subject),
+		zap.String("body", msg.Body),
+	)
+	return nil
+}
@@ -0,0 +1,34 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+
+	"cinema-booking-system/internal/config"
+
+	"go.uber.org/zap"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers emails to users
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns the mailer selected by MAIL_DRIVER
+func New(cfg *config.Config, logger *zap.Logger) (Mailer, error) {
+	switch cfg.Mail.Driver {
+	case "log":
+		return NewLogMailer(cfg.Mail.From, logger), nil
+	case "file":
+		return NewFileMailer(cfg.Mail.From, cfg.Mail.FileDir)
+	default:
+		return nil, fmt.Errorf("unknown mail driver %q", cfg.Mail.Driver)
+	}
+}
@@ -3,9 +3,14 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"slices"
 	"strings"
 
+	"cinema-booking-system/internal/config"
+	"cinema-booking-system/internal/i18n"
+	"cinema-booking-system/internal/models"
 	"cinema-booking-system/internal/service"
+	"cinema-booking-system/internal/utils"
 
 	"go.uber.org/zap"
 )
@@ -21,13 +26,15 @@ const (
 // AuthMiddleware handles JWT authentication
 type AuthMiddleware struct {
 	authService *service.AuthService
+	config      *config.Config
 	logger      *zap.Logger
 }
 
 // NewAuthMiddleware creates a new auth middleware
-func NewAuthMiddleware(authService *service.AuthService, logger *zap.Logger) *AuthMiddleware {
+func NewAuthMiddleware(authService *service.AuthService, cfg *config.Config, logger *zap.Logger) *AuthMiddleware {
 	return &AuthMiddleware{
 		authService: authService,
+		config:      cfg,
 		logger:      logger,
 	}
 }
@@ -38,14 +45,14 @@ func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 		// Get token from Authorization header
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
-			m.respondWithError(w, http.StatusUnauthorized, "Missing authorization header")
+			utils.RespondWithError(w, r, http.StatusUnauthorized, "Missing authorization header")
 			return
 		}
 
 		// Extract token (format: "Bearer <token>")
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			m.respondWithError(w, http.StatusUnauthorized, "Invalid authorization header format")
+			utils.RespondWithError(w, r, http.StatusUnauthorized, "Invalid authorization header format")
 			return
 		}
 
@@ -55,21 +62,70 @@ func (m *AuthMiddleware) Authenticate(next http.Handler) http.Handler {
 		user, err := m.authService.ValidateToken(r.Context(), token)
 		if err != nil {
 			m.logger.Warn("Invalid token", zap.Error(err))
-			m.respondWithError(w, http.StatusUnauthorized, "Invalid or expired token")
+			utils.RespondWithError(w, r, http.StatusUnauthorized, "Invalid or expired token")
 			return
 		}
 
 		// Add user to context
 		ctx := context.WithValue(r.Context(), UserContextKey, user)
 
+		// A saved language preference wins over Accept-Language
+		if user.Locale != "" {
+			ctx = i18n.WithLocale(ctx, user.Locale)
+			w.Header().Set("Content-Language", user.Locale)
+		}
+
 		// Call next handler
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
-// respondWithError sends an error response
-func (m *AuthMiddleware) respondWithError(w http.ResponseWriter, code int, message string) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
-	w.Write([]byte(`{"success": false, "error": "` + message + `"}`))
+// RequireRole allows the request only when the authenticated user has one of
+// the given roles. It must run after Authenticate.
+func (m *AuthMiddleware) RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, ok := r.Context().Value(UserContextKey).(*models.User)
+			if !ok {
+				utils.RespondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+				return
+			}
+
+			if !slices.Contains(roles, user.Role) {
+				m.logger.Warn("Access denied for role",
+					zap.Int("user_id", user.ID),
+					zap.String("role", user.Role),
+					zap.String("path", r.URL.Path))
+				utils.RespondWithError(w, r, http.StatusForbidden, "Insufficient permissions")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireVerifiedEmail allows the request only when the authenticated user has
+// verified their email address. It is a no-op unless AUTH_REQUIRE_VERIFIED_EMAIL
+// is enabled and must run after Authenticate.
+func (m *AuthMiddleware) RequireVerifiedEmail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !m.config.Auth.RequireVerifiedEmail {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		user, ok := r.Context().Value(UserContextKey).(*models.User)
+		if !ok {
+			utils.RespondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		if user.EmailVerifiedAt == nil {
+			utils.RespondWithError(w, r, http.StatusForbidden, "Email address is not verified")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
 }
@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+
+	"cinema-booking-system/internal/apperror"
+	"cinema-booking-system/internal/config"
+	"cinema-booking-system/internal/models"
+	"cinema-booking-system/internal/service"
+	"cinema-booking-system/internal/utils"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// IdempotencyKeyHeader is the request header carrying the client's key
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader marks responses replayed from storage
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+)
+
+// IdempotencyMiddleware replays stored responses for retried requests
+type IdempotencyMiddleware struct {
+	idempotencyService *service.IdempotencyService
+	maxBodyBytes       int64
+	logger             *zap.Logger
+}
+
+// NewIdempotencyMiddleware creates a new idempotency middleware. Request
+// bodies are read up to the configured APP_MAX_BODY_BYTES, the same limit
+// handlers decode them with.
+func NewIdempotencyMiddleware(idempotencyService *service.IdempotencyService, cfg *config.Config, logger *zap.Logger) *IdempotencyMiddleware {
+	return &IdempotencyMiddleware{
+		idempotencyService: idempotencyService,
+		maxBodyBytes:       cfg.App.MaxBodyBytes,
+		logger:             logger,
+	}
+}
+
+// recordingWriter wraps http.ResponseWriter to capture status code and body
+type recordingWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func (rw *recordingWriter) WriteHeader(code int) {
+	rw.statusCode = code
+	rw.ResponseWriter.WriteHeader(code)
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	rw.body.Write(b)
+	return rw.ResponseWriter.Write(b)
+}
+
+// Handle applies Idempotency-Key semantics per authenticated user. It must
+// run after Authenticate. Requests without the header pass straight through.
+func (m *IdempotencyMiddleware) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			utils.RespondWithError(w, r, http.StatusBadRequest, "Idempotency-Key is too long")
+			return
+		}
+
+		user, ok := r.Context().Value(UserContextKey).(*models.User)
+		if !ok {
+			utils.RespondWithError(w, r, http.StatusUnauthorized, "Unauthorized")
+			return
+		}
+
+		// Read body so it can be fingerprinted and handed on unchanged
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, m.maxBodyBytes))
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				utils.RespondWithAppError(w, r, apperror.Newf(apperror.CodeTooLarge, "request body must not exceed %d bytes", maxBytesErr.Limit))
+				return
+			}
+			utils.RespondWithError(w, r, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		record, err := m.idempotencyService.Begin(r.Context(), user.ID, key, requestHash(r, body))
+		if err != nil {
+			utils.RespondWithAppError(w, r, err)
+			return
+		}
+
+		// Replay the stored response
+		if record != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set(IdempotentReplayedHeader, "true")
+			w.WriteHeader(*record.StatusCode)
+			w.Write(record.ResponseBody)
+			return
+		}
+
+		// Storage must not depend on the client still being connected
+		storeCtx := context.WithoutCancel(r.Context())
+
+		recorder := &recordingWriter{ResponseWriter: w, statusCode: http.StatusOK}
+		defer func() {
+			if rec := recover(); rec != nil {
+				m.idempotencyService.Release(storeCtx, user.ID, key)
+				panic(rec)
+			}
+		}()
+
+		next.ServeHTTP(recorder, r)
+
+		// Server errors are not final; let the client retry with the same key
+		if recorder.statusCode >= http.StatusInternalServerError {
+			m.idempotencyService.Release(storeCtx, user.ID, key)
+			return
+		}
+
+		if err := m.idempotencyService.Complete(storeCtx, user.ID, key, recorder.statusCode, recorder.body.Bytes()); err != nil {
+			m.logger.Error("Failed to store idempotent response",
+				zap.Int("user_id", user.ID),
+				zap.String("idempotency_key", key),
+				zap.Error(err))
+		}
+	})
+}
+
+// requestHash fingerprints the method, path and body of a request
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	h.Write([]byte(r.Method + " " + r.URL.Path + "\n"))
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+
+	"cinema-booking-system/internal/config"
+	"cinema-booking-system/internal/i18n"
+)
+
+// LocaleMiddleware picks the language responses are written in
+type LocaleMiddleware struct {
+	config *config.Config
+}
+
+// NewLocaleMiddleware creates a new locale middleware
+func NewLocaleMiddleware(cfg *config.Config) *LocaleMiddleware {
+	return &LocaleMiddleware{config: cfg}
+}
+
+// Negotiate stores the locale requested in the Accept-Language header in the
+// request context, falling back to APP_DEFAULT_LOCALE. Authenticate replaces
+// it with the user's saved preference, if any.
+func (m *LocaleMiddleware) Negotiate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		locale := i18n.Negotiate(r.Header.Get("Accept-Language"), m.config.App.DefaultLocale)
+
+		w.Header().Set("Content-Language", locale)
+		w.Header().Add("Vary", "Accept-Language")
+
+		next.ServeHTTP(w, r.WithContext(i18n.WithLocale(r.Context(), locale)))
+	})
+}
@@ -0,0 +1,122 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"cinema-booking-system/internal/config"
+)
+
+// RealIPMiddleware resolves the client address of requests that reach the
+// server through a trusted reverse proxy
+type RealIPMiddleware struct {
+	trustedProxies []netip.Prefix
+}
+
+// NewRealIPMiddleware creates a new real IP middleware trusting the proxies
+// listed in APP_TRUSTED_PROXIES
+func NewRealIPMiddleware(cfg *config.Config) (*RealIPMiddleware, error) {
+	prefixes := make([]netip.Prefix, 0, len(cfg.App.TrustedProxies))
+	for _, proxy := range cfg.App.TrustedProxies {
+		prefix, err := parsePrefix(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		prefixes = append(prefixes, prefix)
+	}
+
+	return &RealIPMiddleware{trustedProxies: prefixes}, nil
+}
+
+// Resolve replaces RemoteAddr with the client address from X-Forwarded-For,
+// or X-Real-IP when that is missing, but only for requests whose socket
+// address is a trusted proxy. Anyone else could send those headers to pick
+// their own address, so their socket address is kept.
+func (m *RealIPMiddleware) Resolve(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if ip, ok := m.forwardedIP(r); ok {
+			r.RemoteAddr = ip.String()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// forwardedIP returns the client address reported by a trusted proxy.
+// X-Forwarded-For is read from the right so that only hops appended by
+// trusted proxies are skipped; whatever the client put there itself is
+// ignored.
+func (m *RealIPMiddleware) forwardedIP(r *http.Request) (netip.Addr, bool) {
+	peer, err := parseAddr(r.RemoteAddr)
+	if err != nil || !m.trusted(peer) {
+		return netip.Addr{}, false
+	}
+
+	if forwardedFor := r.Header.Values("X-Forwarded-For"); len(forwardedFor) > 0 {
+		hops := strings.Split(strings.Join(forwardedFor, ","), ",")
+
+		var client netip.Addr
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop, err := parseAddr(hops[i])
+			if err != nil {
+				break
+			}
+			client = hop
+			if !m.trusted(hop) {
+				break
+			}
+		}
+		return client, client.IsValid()
+	}
+
+	realIP, err := parseAddr(r.Header.Get("X-Real-IP"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return realIP, true
+}
+
+// trusted reports whether addr belongs to a trusted proxy
+func (m *RealIPMiddleware) trusted(addr netip.Addr) bool {
+	for _, prefix := range m.trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseAddr parses an IP address with or without a port
+func parseAddr(value string) (netip.Addr, error) {
+	value = strings.TrimSpace(value)
+	if host, _, err := net.SplitHostPort(value); err == nil {
+		value = host
+	}
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	return addr.Unmap(), nil
+}
+
+// parsePrefix parses a CIDR range, treating a bare IP address as a range of
+// one address
+func parsePrefix(value string) (netip.Prefix, error) {
+	if strings.Contains(value, "/") {
+		prefix, err := netip.ParsePrefix(value)
+		if err != nil {
+			return netip.Prefix{}, err
+		}
+		return prefix.Masked(), nil
+	}
+
+	addr, err := netip.ParseAddr(value)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
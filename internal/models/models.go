@@ -2,15 +2,32 @@ package models
 
 import "time"
 
-// User represents a registered customer
+// User roles
+const (
+	RoleCustomer      = "customer"
+	RoleStaff         = "staff"
+	RoleCinemaManager = "cinema_manager"
+	RoleAdmin         = "admin"
+)
+
+// DeletedUsernamePrefix starts the username of every anonymised account.
+// Registration rejects usernames with this prefix so they stay free.
+const DeletedUsernamePrefix = "deleted_user_"
+
+// User represents a registered customer or back-office user
 type User struct {
-	ID           int       `json:"id"`
-	Username     string    `json:"username"`
-	Email        string    `json:"email"`
-	PasswordHash string    `json:"-"` // Never expose password hash in JSON
-	FullName     string    `json:"full_name,omitempty"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID              int        `json:"id"`
+	Username        string     `json:"username"`
+	Email           string     `json:"email"`
+	PasswordHash    string     `json:"-"` // Never expose password hash in JSON
+	FullName        string     `json:"full_name,omitempty"`
+	Role            string     `json:"role"`
+	Locale          string     `json:"locale,omitempty"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at,omitempty"`
+	TOTPSecret      *string    `json:"-"`
+	TOTPEnabledAt   *time.Time `json:"-"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
 // Cinema represents a movie theater
@@ -34,6 +51,39 @@ type Seat struct {
 	Price      float64 `json:"price"`
 }
 
+// Movie represents a film that can be scheduled in cinemas
+type Movie struct {
+	ID              int       `json:"id"`
+	Title           string    `json:"title"`
+	Description     string    `json:"description,omitempty"`
+	DurationMinutes int       `json:"duration_minutes"`
+	Rating          string    `json:"rating"`
+	Language        string    `json:"language"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// Showtime represents a scheduled screening of a movie in a cinema
+type Showtime struct {
+	ID        int       `json:"id"`
+	MovieID   int       `json:"movie_id"`
+	CinemaID  int       `json:"cinema_id"`
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ShowtimeDetail extends Showtime with movie and cinema information
+type ShowtimeDetail struct {
+	Showtime
+	MovieTitle      string `json:"movie_title"`
+	MovieRating     string `json:"movie_rating"`
+	DurationMinutes int    `json:"duration_minutes"`
+	CinemaName      string `json:"cinema_name"`
+	CinemaLocation  string `json:"cinema_location"`
+}
+
 // SeatAvailability represents seat status for a specific showtime
 type SeatAvailability struct {
 	Seat
@@ -45,41 +95,93 @@ type PaymentMethod struct {
 	ID          int       `json:"id"`
 	Name        string    `json:"name"`
 	Description string    `json:"description,omitempty"`
+	Provider    string    `json:"provider"`
 	IsActive    bool      `json:"is_active"`
 	CreatedAt   time.Time `json:"created_at"`
 }
 
 // Booking represents a ticket reservation
 type Booking struct {
-	ID              int       `json:"id"`
-	UserID          int       `json:"user_id"`
-	CinemaID        int       `json:"cinema_id"`
-	SeatID          int       `json:"seat_id"`
-	BookingDate     string    `json:"booking_date"` // YYYY-MM-DD format
-	BookingTime     string    `json:"booking_time"` // HH:MM:SS format
-	PaymentMethodID *int      `json:"payment_method_id,omitempty"`
-	PaymentStatus   string    `json:"payment_status"`
-	TotalAmount     float64   `json:"total_amount"`
-	BookingStatus   string    `json:"booking_status"`
-	CreatedAt       time.Time `json:"created_at"`
-	UpdatedAt       time.Time `json:"updated_at"`
+	ID              int            `json:"id"`
+	UserID          int            `json:"user_id"`
+	CinemaID        int            `json:"cinema_id"`
+	ShowtimeID      int            `json:"showtime_id"`
+	PaymentMethodID *int           `json:"payment_method_id,omitempty"`
+	PaymentStatus   string         `json:"payment_status"`
+	TotalAmount     float64        `json:"total_amount"`
+	BookingStatus   string         `json:"booking_status"`
+	HoldExpiresAt   *time.Time     `json:"hold_expires_at,omitempty"`
+	Seats           []*BookingSeat `json:"seats"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+}
+
+// BookingSeat represents a single seat reserved as part of a booking
+type BookingSeat struct {
+	ID         int     `json:"id"`
+	BookingID  int     `json:"booking_id"`
+	SeatID     int     `json:"seat_id"`
+	SeatNumber string  `json:"seat_number"`
+	SeatType   string  `json:"seat_type"`
+	Price      float64 `json:"price"`
+}
+
+// Payment represents a single charge or refund attempt with a payment gateway
+type Payment struct {
+	ID                int       `json:"id"`
+	BookingID         int       `json:"booking_id"`
+	PaymentMethodID   *int      `json:"payment_method_id,omitempty"`
+	Provider          string    `json:"provider"`
+	ProviderReference *string   `json:"provider_reference,omitempty"`
+	Type              string    `json:"type"`
+	Amount            float64   `json:"amount"`
+	Status            string    `json:"status"`
+	Reason            string    `json:"reason,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
 }
 
 // BookingDetail extends Booking with related information
 type BookingDetail struct {
 	Booking
-	CinemaName        string  `json:"cinema_name"`
-	CinemaLocation    string  `json:"cinema_location"`
-	SeatNumber        string  `json:"seat_number"`
-	SeatType          string  `json:"seat_type"`
-	PaymentMethodName *string `json:"payment_method_name,omitempty"`
+	CinemaName        string    `json:"cinema_name"`
+	CinemaLocation    string    `json:"cinema_location"`
+	MovieTitle        string    `json:"movie_title"`
+	StartTime         time.Time `json:"start_time"`
+	PaymentMethodName *string   `json:"payment_method_name,omitempty"`
+}
+
+// IdempotencyKey stores the outcome of a request made with an Idempotency-Key
+// header. StatusCode is nil while the original request is still in flight.
+type IdempotencyKey struct {
+	UserID       int       `json:"user_id"`
+	Key          string    `json:"key"`
+	RequestHash  string    `json:"request_hash"`
+	StatusCode   *int      `json:"status_code,omitempty"`
+	ResponseBody []byte    `json:"response_body,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	ExpiresAt    time.Time `json:"expires_at"`
+}
+
+// RefreshToken represents one link in a session's chain of rotated refresh
+// tokens. Only the hash of the token is stored.
+type RefreshToken struct {
+	ID        int        `json:"id"`
+	TokenID   int        `json:"token_id"`
+	UserID    int        `json:"user_id"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
 }
 
-// Token represents an authentication token
+// Token represents a login session and the hash of its current access token
 type Token struct {
 	ID        int       `json:"id"`
 	UserID    int       `json:"user_id"`
-	Token     string    `json:"token"`
+	TokenHash string    `json:"-"`
+	UserAgent string    `json:"user_agent,omitempty"`
+	IPAddress string    `json:"ip_address,omitempty"`
 	ExpiresAt time.Time `json:"expires_at"`
 	CreatedAt time.Time `json:"created_at"`
 }
@@ -0,0 +1,96 @@
+// Package openapi embeds the OpenAPI 3 document of the API and the Swagger UI
+// page that renders it, and checks the document against the router. The
+// Swagger UI scripts and styles are vendored in swagger-ui so the page loads
+// no third-party code.
+package openapi
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+)
+
+var (
+	//go:embed openapi.json
+	spec []byte
+
+	//go:embed swagger.html
+	swaggerUI []byte
+
+	//go:embed swagger-ui/swagger-ui.css swagger-ui/swagger-ui-bundle.js
+	swaggerAssets embed.FS
+)
+
+// Spec returns the OpenAPI document as JSON
+func Spec() []byte {
+	return spec
+}
+
+// SwaggerUI returns the HTML page that renders the OpenAPI document
+func SwaggerUI() []byte {
+	return swaggerUI
+}
+
+// SwaggerAssets returns the scripts and styles the Swagger UI page loads
+func SwaggerAssets() fs.FS {
+	assets, _ := fs.Sub(swaggerAssets, "swagger-ui")
+	return assets
+}
+
+// CheckRoutes reports every route registered on routes that the OpenAPI
+// document does not describe, and every documented operation that has no
+// route, so the two cannot drift apart unnoticed
+func CheckRoutes(routes chi.Routes) error {
+	var document struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(spec, &document); err != nil {
+		return fmt.Errorf("failed to parse openapi document: %w", err)
+	}
+
+	documented := make(map[string]bool)
+	for path, operations := range document.Paths {
+		for method := range operations {
+			documented[strings.ToUpper(method)+" "+path] = true
+		}
+	}
+
+	var undocumented []string
+	err := chi.Walk(routes, func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		key := method + " " + route
+		if documented[key] {
+			delete(documented, key)
+		} else {
+			undocumented = append(undocumented, key)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to walk routes: %w", err)
+	}
+
+	var problems []string
+	if len(undocumented) > 0 {
+		sort.Strings(undocumented)
+		problems = append(problems, "routes missing from openapi.json: "+strings.Join(undocumented, ", "))
+	}
+	if len(documented) > 0 {
+		unrouted := make([]string, 0, len(documented))
+		for key := range documented {
+			unrouted = append(unrouted, key)
+		}
+		sort.Strings(unrouted)
+		problems = append(problems, "documented operations without a route: "+strings.Join(unrouted, ", "))
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+
+	return nil
+}
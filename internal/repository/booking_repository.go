@@ -2,14 +2,36 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
 
+	"cinema-booking-system/internal/apperror"
 	"cinema-booking-system/internal/models"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// bookingDetailSelect is shared by every query that returns booking details
+const bookingDetailSelect = `
+	SELECT
+		b.id, b.user_id, b.cinema_id, b.showtime_id,
+		b.payment_method_id, b.payment_status, b.total_amount, b.booking_status,
+		b.hold_expires_at, b.created_at, b.updated_at,
+		c.name as cinema_name,
+		c.location as cinema_location,
+		m.title as movie_title,
+		st.start_time,
+		pm.name as payment_method_name
+	FROM bookings b
+	INNER JOIN cinemas c ON b.cinema_id = c.id
+	INNER JOIN showtimes st ON b.showtime_id = st.id
+	INNER JOIN movies m ON st.movie_id = m.id
+	LEFT JOIN payment_methods pm ON b.payment_method_id = pm.id
+`
+
 // BookingRepository handles booking-related database operations
 type BookingRepository struct {
 	db *pgxpool.Pool
@@ -20,42 +42,146 @@ func NewBookingRepository(db *pgxpool.Pool) *BookingRepository {
 	return &BookingRepository{db: db}
 }
 
-// Create inserts a new booking into the database
-func (r *BookingRepository) Create(ctx context.Context, booking *models.Booking) error {
+// uniqueViolation is the PostgreSQL error code for unique constraint violations
+const uniqueViolation = "23505"
+
+// foreignKeyViolation is the PostgreSQL error code for foreign key violations
+const foreignKeyViolation = "23503"
+
+// expireHoldsQuery marks unpaid reservations whose hold has lapsed as expired.
+// Bookings awaiting payment confirmation are given $1 more seconds for the
+// provider to respond. The booking_seats trigger releases their seats.
+const expireHoldsQuery = `
+	UPDATE bookings
+	SET booking_status = 'expired', payment_status = 'expired', updated_at = CURRENT_TIMESTAMP
+	WHERE booking_status = 'reserved'
+	  AND (
+		(payment_status IN ('pending', 'failed') AND hold_expires_at < CURRENT_TIMESTAMP)
+		OR (payment_status = 'processing' AND hold_expires_at + make_interval(secs => $1) < CURRENT_TIMESTAMP)
+	  )
+`
+
+// ErrBookingNotPayable is returned when a booking is no longer an active reservation
+var ErrBookingNotPayable = apperror.Conflict("booking is not an active reservation")
+
+// ErrBookingNotCancellable is returned when a booking is neither reserved nor paid
+var ErrBookingNotCancellable = apperror.Conflict("booking cannot be cancelled")
+
+// SeatTakenError is returned when one or more requested seats are held by
+// another active booking for the same showtime
+type SeatTakenError struct {
+	SeatIDs []int
+}
+
+func (e *SeatTakenError) Error() string {
+	return fmt.Sprintf("seat already taken: seat ids %v", e.SeatIDs)
+}
+
+// CreateWithSeats inserts a booking and all of its seats in a single transaction.
+// Nothing is written if any of the seats is already booked for the showtime.
+//
+// Concurrent bookings for the same showtime are serialized by locking the
+// showtime row, and the partial unique index on active booking seats turns
+// any remaining race into a *SeatTakenError rather than a double booking.
+func (r *BookingRepository) CreateWithSeats(ctx context.Context, booking *models.Booking, processingGrace time.Duration) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the showtime so availability checks for it are serialized
+	var lockedID int
+	err = tx.QueryRow(ctx, `SELECT id FROM showtimes WHERE id = $1 FOR UPDATE`, booking.ShowtimeID).Scan(&lockedID)
+	if err == pgx.ErrNoRows {
+		return apperror.NotFound("showtime not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock showtime: %w", err)
+	}
+
+	// Release lapsed holds first so their seats can be booked again right away
+	_, err = tx.Exec(ctx, expireHoldsQuery+` AND showtime_id = $2`, processingGrace.Seconds(), booking.ShowtimeID)
+	if err != nil {
+		return fmt.Errorf("failed to expire stale holds: %w", err)
+	}
+
+	// Check that none of the seats is taken
+	seatIDs := make([]int, len(booking.Seats))
+	for i, seat := range booking.Seats {
+		seatIDs[i] = seat.SeatID
+	}
+
+	taken, err := r.getTakenSeats(ctx, tx, booking.ShowtimeID, seatIDs)
+	if err != nil {
+		return err
+	}
+	if len(taken) > 0 {
+		return &SeatTakenError{SeatIDs: taken}
+	}
+
+	// Insert parent booking
 	query := `
 		INSERT INTO bookings (
-			user_id, cinema_id, seat_id, booking_date, booking_time,
-			payment_method_id, payment_status, total_amount, booking_status
+			user_id, cinema_id, showtime_id,
+			payment_method_id, payment_status, total_amount, booking_status,
+			hold_expires_at
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id, created_at, updated_at
 	`
 
-	err := r.db.QueryRow(ctx, query,
+	err = tx.QueryRow(ctx, query,
 		booking.UserID,
 		booking.CinemaID,
-		booking.SeatID,
-		booking.BookingDate,
-		booking.BookingTime,
+		booking.ShowtimeID,
 		booking.PaymentMethodID,
 		booking.PaymentStatus,
 		booking.TotalAmount,
 		booking.BookingStatus,
+		booking.HoldExpiresAt,
 	).Scan(&booking.ID, &booking.CreatedAt, &booking.UpdatedAt)
-
 	if err != nil {
 		return fmt.Errorf("failed to create booking: %w", err)
 	}
 
+	// Insert seat line items
+	seatQuery := `
+		INSERT INTO booking_seats (booking_id, showtime_id, seat_id, price)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+
+	for _, seat := range booking.Seats {
+		seat.BookingID = booking.ID
+		err := tx.QueryRow(ctx, seatQuery,
+			booking.ID,
+			booking.ShowtimeID,
+			seat.SeatID,
+			seat.Price,
+		).Scan(&seat.ID)
+		if err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+				return &SeatTakenError{SeatIDs: []int{seat.SeatID}}
+			}
+			return fmt.Errorf("failed to create booking seat: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit booking: %w", err)
+	}
+
 	return nil
 }
 
-// GetByID retrieves a booking by ID
+// GetByID retrieves a booking and its seats by ID
 func (r *BookingRepository) GetByID(ctx context.Context, id int) (*models.Booking, error) {
 	query := `
-		SELECT id, user_id, cinema_id, seat_id, booking_date, booking_time,
+		SELECT id, user_id, cinema_id, showtime_id,
 			   payment_method_id, payment_status, total_amount, booking_status,
-			   created_at, updated_at
+			   hold_expires_at, created_at, updated_at
 		FROM bookings
 		WHERE id = $1
 	`
@@ -65,71 +191,123 @@ func (r *BookingRepository) GetByID(ctx context.Context, id int) (*models.Bookin
 		&booking.ID,
 		&booking.UserID,
 		&booking.CinemaID,
-		&booking.SeatID,
-		&booking.BookingDate,
-		&booking.BookingTime,
+		&booking.ShowtimeID,
 		&booking.PaymentMethodID,
 		&booking.PaymentStatus,
 		&booking.TotalAmount,
 		&booking.BookingStatus,
+		&booking.HoldExpiresAt,
 		&booking.CreatedAt,
 		&booking.UpdatedAt,
 	)
 
 	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("booking not found")
+		return nil, apperror.NotFound("booking not found")
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get booking: %w", err)
 	}
 
+	seats, err := r.getBookingSeats(ctx, []int{booking.ID})
+	if err != nil {
+		return nil, err
+	}
+	booking.Seats = seats[booking.ID]
+
 	return &booking, nil
 }
 
-// CheckSeatAvailability checks if a seat is available for booking
-func (r *BookingRepository) CheckSeatAvailability(ctx context.Context, cinemaID, seatID int, date, time string) (bool, error) {
+// getTakenSeats returns the subset of seatIDs held by active bookings for a showtime
+func (r *BookingRepository) getTakenSeats(ctx context.Context, tx pgx.Tx, showtimeID int, seatIDs []int) ([]int, error) {
 	query := `
-		SELECT COUNT(*)
-		FROM bookings
-		WHERE cinema_id = $1 
-		  AND seat_id = $2 
-		  AND booking_date = $3 
-		  AND booking_time = $4
-		  AND booking_status IN ('reserved', 'paid')
+		SELECT bs.seat_id
+		FROM booking_seats bs
+		INNER JOIN bookings b ON bs.booking_id = b.id
+		WHERE bs.showtime_id = $1
+		  AND bs.seat_id = ANY($2)
+		  AND b.booking_status IN ('reserved', 'paid')
+		ORDER BY bs.seat_id
+	`
+
+	rows, err := tx.Query(ctx, query, showtimeID, seatIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check seat availability: %w", err)
+	}
+
+	taken, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		return nil, fmt.Errorf("failed to check seat availability: %w", err)
+	}
+
+	return taken, nil
+}
+
+// getBookingSeats retrieves the seats of the given bookings keyed by booking ID
+func (r *BookingRepository) getBookingSeats(ctx context.Context, bookingIDs []int) (map[int][]*models.BookingSeat, error) {
+	query := `
+		SELECT bs.id, bs.booking_id, bs.seat_id, s.seat_number, s.seat_type, bs.price
+		FROM booking_seats bs
+		INNER JOIN seats s ON bs.seat_id = s.id
+		WHERE bs.booking_id = ANY($1)
+		ORDER BY s.row_number, s.seat_number
 	`
 
-	var count int
-	err := r.db.QueryRow(ctx, query, cinemaID, seatID, date, time).Scan(&count)
+	rows, err := r.db.Query(ctx, query, bookingIDs)
 	if err != nil {
-		return false, fmt.Errorf("failed to check seat availability: %w", err)
+		return nil, fmt.Errorf("failed to get booking seats: %w", err)
+	}
+	defer rows.Close()
+
+	seats := make(map[int][]*models.BookingSeat)
+	for rows.Next() {
+		var seat models.BookingSeat
+		err := rows.Scan(
+			&seat.ID,
+			&seat.BookingID,
+			&seat.SeatID,
+			&seat.SeatNumber,
+			&seat.SeatType,
+			&seat.Price,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan booking seat: %w", err)
+		}
+		seats[seat.BookingID] = append(seats[seat.BookingID], &seat)
 	}
 
-	return count == 0, nil
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating booking seats: %w", err)
+	}
+
+	return seats, nil
 }
 
 // GetUserBookings retrieves all bookings for a user
 func (r *BookingRepository) GetUserBookings(ctx context.Context, userID int) ([]*models.BookingDetail, error) {
-	query := `
-		SELECT 
-			b.id, b.user_id, b.cinema_id, b.seat_id, b.booking_date, b.booking_time,
-			b.payment_method_id, b.payment_status, b.total_amount, b.booking_status,
-			b.created_at, b.updated_at,
-			c.name as cinema_name,
-			c.location as cinema_location,
-			s.seat_number,
-			s.seat_type,
-			pm.name as payment_method_name
-		FROM bookings b
-		INNER JOIN cinemas c ON b.cinema_id = c.id
-		INNER JOIN seats s ON b.seat_id = s.id
-		LEFT JOIN payment_methods pm ON b.payment_method_id = pm.id
+	query := bookingDetailSelect + `
 		WHERE b.user_id = $1
 		ORDER BY b.created_at DESC
 	`
 
-	rows, err := r.db.Query(ctx, query, userID)
+	return r.queryBookingDetails(ctx, query, userID)
+}
+
+// GetShowtimeBookings retrieves all active bookings for a showtime
+func (r *BookingRepository) GetShowtimeBookings(ctx context.Context, showtimeID int) ([]*models.BookingDetail, error) {
+	query := bookingDetailSelect + `
+		WHERE b.showtime_id = $1
+		  AND b.booking_status IN ('reserved', 'paid')
+		ORDER BY b.created_at
+	`
+
+	return r.queryBookingDetails(ctx, query, showtimeID)
+}
+
+// queryBookingDetails runs a booking detail query and attaches seats to the results
+func (r *BookingRepository) queryBookingDetails(ctx context.Context, query string, args ...interface{}) ([]*models.BookingDetail, error) {
+	rows, err := r.db.Query(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user bookings: %w", err)
+		return nil, fmt.Errorf("failed to get bookings: %w", err)
 	}
 	defer rows.Close()
 
@@ -140,19 +318,18 @@ func (r *BookingRepository) GetUserBookings(ctx context.Context, userID int) ([]
 			&booking.ID,
 			&booking.UserID,
 			&booking.CinemaID,
-			&booking.SeatID,
-			&booking.BookingDate,
-			&booking.BookingTime,
+			&booking.ShowtimeID,
 			&booking.PaymentMethodID,
 			&booking.PaymentStatus,
 			&booking.TotalAmount,
 			&booking.BookingStatus,
+			&booking.HoldExpiresAt,
 			&booking.CreatedAt,
 			&booking.UpdatedAt,
 			&booking.CinemaName,
 			&booking.CinemaLocation,
-			&booking.SeatNumber,
-			&booking.SeatType,
+			&booking.MovieTitle,
+			&booking.StartTime,
 			&booking.PaymentMethodName,
 		)
 		if err != nil {
@@ -165,45 +342,207 @@ func (r *BookingRepository) GetUserBookings(ctx context.Context, userID int) ([]
 		return nil, fmt.Errorf("error iterating bookings: %w", err)
 	}
 
+	// Attach seats to each booking
+	bookingIDs := make([]int, len(bookings))
+	for i, booking := range bookings {
+		bookingIDs[i] = booking.ID
+	}
+
+	seats, err := r.getBookingSeats(ctx, bookingIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, booking := range bookings {
+		booking.Seats = seats[booking.ID]
+	}
+
 	return bookings, nil
 }
 
-// UpdatePaymentStatus updates the payment status of a booking
+// UpdatePaymentStatus updates the payment status of a cancelled or expired
+// booking. Reserved and paid bookings are left unchanged, so refunding a
+// duplicate charge never overwrites the status of the payment that counts.
 func (r *BookingRepository) UpdatePaymentStatus(ctx context.Context, bookingID int, status string) error {
 	query := `
 		UPDATE bookings
 		SET payment_status = $1, updated_at = CURRENT_TIMESTAMP
 		WHERE id = $2
+		  AND booking_status NOT IN ('reserved', 'paid')
 	`
 
-	result, err := r.db.Exec(ctx, query, status, bookingID)
-	if err != nil {
+	if _, err := r.db.Exec(ctx, query, status, bookingID); err != nil {
 		return fmt.Errorf("failed to update payment status: %w", err)
 	}
 
+	return nil
+}
+
+// MarkPaid marks a reserved booking as paid by the given charge, provided its
+// hold has not lapsed and no other payment is awaiting confirmation
+func (r *BookingRepository) MarkPaid(ctx context.Context, bookingID, paymentMethodID, paymentID int) error {
+	query := `
+		UPDATE bookings
+		SET payment_status = 'paid', booking_status = 'paid', payment_method_id = $2,
+			paid_payment_id = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		  AND booking_status = 'reserved'
+		  AND payment_status IN ('pending', 'failed')
+		  AND (hold_expires_at IS NULL OR hold_expires_at > CURRENT_TIMESTAMP)
+	`
+
+	result, err := r.db.Exec(ctx, query, bookingID, paymentMethodID, paymentID)
+	if err != nil {
+		return fmt.Errorf("failed to mark booking as paid: %w", err)
+	}
+
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("booking not found")
+		return ErrBookingNotPayable
 	}
 
 	return nil
 }
 
-// UpdateBookingStatus updates the booking status
-func (r *BookingRepository) UpdateBookingStatus(ctx context.Context, bookingID int, status string) error {
-	query := `
+// ExpireStaleHolds expires every unpaid reservation whose hold has lapsed,
+// and every booking still awaiting payment confirmation processingGrace after
+// its hold lapsed, and returns the number of bookings released
+func (r *BookingRepository) ExpireStaleHolds(ctx context.Context, processingGrace time.Duration) (int64, error) {
+	result, err := r.db.Exec(ctx, expireHoldsQuery, processingGrace.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to expire stale holds: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// Cancel cancels an active booking, releasing its seats, and reports whether
+// it had been paid. Paid bookings are left with payment_status 'refund_pending'
+// until the refund outcome is known.
+func (r *BookingRepository) Cancel(ctx context.Context, bookingID int) (bool, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the booking so a concurrent payment or expiry cannot interleave
+	var bookingStatus, paymentStatus string
+	err = tx.QueryRow(ctx, `
+		SELECT booking_status, payment_status
+		FROM bookings
+		WHERE id = $1
+		FOR UPDATE
+	`, bookingID).Scan(&bookingStatus, &paymentStatus)
+	if err == pgx.ErrNoRows {
+		return false, apperror.NotFound("booking not found")
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get booking: %w", err)
+	}
+
+	if bookingStatus != "reserved" && bookingStatus != "paid" {
+		return false, ErrBookingNotCancellable
+	}
+
+	wasPaid := paymentStatus == "paid"
+	newPaymentStatus := "cancelled"
+	if wasPaid {
+		newPaymentStatus = "refund_pending"
+	}
+
+	_, err = tx.Exec(ctx, `
 		UPDATE bookings
-		SET booking_status = $1, updated_at = CURRENT_TIMESTAMP
+		SET booking_status = 'cancelled', payment_status = $1, updated_at = CURRENT_TIMESTAMP
 		WHERE id = $2
+	`, newPaymentStatus, bookingID)
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel booking: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit cancellation: %w", err)
+	}
+
+	return wasPaid, nil
+}
+
+// MarkPaymentProcessing flags a reserved booking as awaiting asynchronous
+// confirmation of the given charge, provided its hold has not lapsed and no
+// other payment is awaiting confirmation. Processing bookings
+// keep their seats until the provider confirms or fails the payment, or until
+// ExpireStaleHolds gives up on the provider.
+func (r *BookingRepository) MarkPaymentProcessing(ctx context.Context, bookingID, paymentMethodID, paymentID int) error {
+	query := `
+		UPDATE bookings
+		SET payment_status = 'processing', payment_method_id = $2, processing_payment_id = $3,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		  AND booking_status = 'reserved'
+		  AND payment_status IN ('pending', 'failed')
+		  AND (hold_expires_at IS NULL OR hold_expires_at > CURRENT_TIMESTAMP)
 	`
 
-	result, err := r.db.Exec(ctx, query, status, bookingID)
+	result, err := r.db.Exec(ctx, query, bookingID, paymentMethodID, paymentID)
 	if err != nil {
-		return fmt.Errorf("failed to update booking status: %w", err)
+		return fmt.Errorf("failed to mark payment processing: %w", err)
 	}
 
 	if result.RowsAffected() == 0 {
-		return fmt.Errorf("booking not found")
+		return ErrBookingNotPayable
 	}
 
 	return nil
 }
+
+// ConfirmPayment marks a booking awaiting asynchronous payment as paid by the
+// given charge and reports whether the booking is paid by that charge, which
+// also holds when the confirmation is applied again
+func (r *BookingRepository) ConfirmPayment(ctx context.Context, bookingID, paymentID int) (bool, error) {
+	query := `
+		UPDATE bookings
+		SET payment_status = 'paid', booking_status = 'paid', paid_payment_id = $2,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		  AND booking_status = 'reserved'
+		  AND payment_status = 'processing'
+	`
+
+	result, err := r.db.Exec(ctx, query, bookingID, paymentID)
+	if err != nil {
+		return false, fmt.Errorf("failed to confirm payment: %w", err)
+	}
+	if result.RowsAffected() > 0 {
+		return true, nil
+	}
+
+	var paidPaymentID *int
+	err = r.db.QueryRow(ctx, `SELECT paid_payment_id FROM bookings WHERE id = $1`, bookingID).Scan(&paidPaymentID)
+	if err == pgx.ErrNoRows {
+		return false, apperror.NotFound("booking not found")
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get paying charge: %w", err)
+	}
+
+	return paidPaymentID != nil && *paidPaymentID == paymentID, nil
+}
+
+// FailPayment marks a booking awaiting asynchronous confirmation of the given
+// charge as failed so the customer can retry until the hold lapses, and
+// reports whether it changed. Failures of any other charge leave it as is.
+func (r *BookingRepository) FailPayment(ctx context.Context, bookingID, paymentID int) (bool, error) {
+	query := `
+		UPDATE bookings
+		SET payment_status = 'failed', updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		  AND booking_status = 'reserved'
+		  AND payment_status = 'processing'
+		  AND processing_payment_id = $2
+	`
+
+	result, err := r.db.Exec(ctx, query, bookingID, paymentID)
+	if err != nil {
+		return false, fmt.Errorf("failed to fail payment: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
@@ -0,0 +1,404 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"cinema-booking-system/internal/apperror"
+	"cinema-booking-system/internal/config"
+	"cinema-booking-system/internal/dto"
+	"cinema-booking-system/internal/models"
+	"cinema-booking-system/internal/repository"
+	"cinema-booking-system/internal/service"
+	"cinema-booking-system/internal/utils"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// concurrentBookers is how many requests race for the same seat
+const concurrentBookers = 20
+
+// testSessionTimeZone is the database session time zone of every test. It
+// differs from UTC and from testLocalZone so that code mixing up the
+// database's, Go's and UTC's notion of wall clock time fails.
+const testSessionTimeZone = "America/Sao_Paulo"
+
+// testLocalZone stands in for the server's time zone in tests that run in
+// a zone other than UTC
+var testLocalZone = time.FixedZone("WIB", 7*60*60)
+
+// openTestDB connects to the migrated database named by TEST_DATABASE_URL and
+// skips the test when it is not set
+func openTestDB(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	cfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		t.Fatalf("failed to parse TEST_DATABASE_URL: %v", err)
+	}
+	// Give every booker its own connection so the race happens in PostgreSQL
+	cfg.MaxConns = concurrentBookers + 2
+	cfg.ConnConfig.RuntimeParams["timezone"] = testSessionTimeZone
+
+	db, err := pgxpool.NewWithConfig(context.Background(), cfg)
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	t.Cleanup(db.Close)
+
+	if err := db.Ping(context.Background()); err != nil {
+		t.Fatalf("failed to ping test database: %v", err)
+	}
+	return db
+}
+
+// useLocalZone runs the rest of the test with testLocalZone as time.Local
+func useLocalZone(t *testing.T) {
+	t.Helper()
+
+	local := time.Local
+	time.Local = testLocalZone
+	t.Cleanup(func() { time.Local = local })
+}
+
+// bookingFixture is a showtime with a single bookable seat
+type bookingFixture struct {
+	userIDs         []int
+	cinemaID        int
+	movieID         int
+	seatID          int
+	showtimeID      int
+	paymentMethodID int
+}
+
+// newBookingFixture inserts a cinema, seat, movie, showtime, payment method
+// and the given number of users, and removes them again, with any other
+// showtimes, bookings and payments of the cinema, when the test ends
+func newBookingFixture(t *testing.T, db *pgxpool.Pool, users int) *bookingFixture {
+	t.Helper()
+	ctx := context.Background()
+	suffix := time.Now().UnixNano()
+	f := &bookingFixture{}
+
+	mustScan := func(query string, dest *int, args ...interface{}) {
+		t.Helper()
+		if err := db.QueryRow(ctx, query, args...).Scan(dest); err != nil {
+			t.Fatalf("failed to create fixture: %v", err)
+		}
+	}
+
+	mustScan(`INSERT INTO cinemas (name, location, total_seats) VALUES ($1, 'Test', 1) RETURNING id`,
+		&f.cinemaID, fmt.Sprintf("Race Cinema %d", suffix))
+	mustScan(`INSERT INTO seats (cinema_id, seat_number, row_number, price) VALUES ($1, 'A1', 'A', 50000) RETURNING id`,
+		&f.seatID, f.cinemaID)
+
+	mustScan(`INSERT INTO movies (title, duration_minutes, language) VALUES ('Race', 120, 'Indonesia') RETURNING id`,
+		&f.movieID)
+	start := time.Now().Add(24 * time.Hour)
+	mustScan(`INSERT INTO showtimes (movie_id, cinema_id, start_time, end_time) VALUES ($1, $2, $3, $4) RETURNING id`,
+		&f.showtimeID, f.movieID, f.cinemaID, start, start.Add(2*time.Hour))
+	mustScan(`INSERT INTO payment_methods (name) VALUES ('Race Pay') RETURNING id`, &f.paymentMethodID)
+
+	for i := 0; i < users; i++ {
+		var userID int
+		mustScan(`INSERT INTO users (username, email, password_hash) VALUES ($1, $2, 'x') RETURNING id`,
+			&userID, fmt.Sprintf("race_%d_%d", suffix, i), fmt.Sprintf("race_%d_%d@example.com", suffix, i))
+		f.userIDs = append(f.userIDs, userID)
+	}
+
+	t.Cleanup(func() {
+		for _, stmt := range []struct {
+			query string
+			arg   interface{}
+		}{
+			{`UPDATE bookings SET paid_payment_id = NULL, processing_payment_id = NULL WHERE cinema_id = $1`, f.cinemaID},
+			{`DELETE FROM payments WHERE booking_id IN (SELECT id FROM bookings WHERE cinema_id = $1)`, f.cinemaID},
+			{`DELETE FROM booking_seats WHERE showtime_id IN (SELECT id FROM showtimes WHERE cinema_id = $1)`, f.cinemaID},
+			{`DELETE FROM bookings WHERE cinema_id = $1`, f.cinemaID},
+			{`DELETE FROM showtimes WHERE cinema_id = $1`, f.cinemaID},
+			{`DELETE FROM movies WHERE id = $1`, f.movieID},
+			{`DELETE FROM cinemas WHERE id = $1`, f.cinemaID},
+			{`DELETE FROM payment_methods WHERE id = $1`, f.paymentMethodID},
+			{`DELETE FROM users WHERE id = ANY($1)`, f.userIDs},
+		} {
+			if _, err := db.Exec(ctx, stmt.query, stmt.arg); err != nil {
+				t.Errorf("failed to clean up fixture: %v", err)
+			}
+		}
+	})
+
+	return f
+}
+
+// race runs fn concurrently for every booker, releasing them all at once,
+// and returns each booker's error
+func race(fn func(i int) error) []error {
+	errs := make([]error, concurrentBookers)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+
+	for i := 0; i < concurrentBookers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = fn(i)
+		}(i)
+	}
+
+	close(start)
+	wg.Wait()
+	return errs
+}
+
+func TestCreateWithSeatsConcurrentSameSeat(t *testing.T) {
+	db := openTestDB(t)
+	f := newBookingFixture(t, db, concurrentBookers)
+	repo := repository.NewBookingRepository(db)
+
+	errs := race(func(i int) error {
+		holdExpiresAt := time.Now().Add(15 * time.Minute)
+		return repo.CreateWithSeats(context.Background(), &models.Booking{
+			UserID:          f.userIDs[i],
+			CinemaID:        f.cinemaID,
+			ShowtimeID:      f.showtimeID,
+			PaymentMethodID: &f.paymentMethodID,
+			PaymentStatus:   "pending",
+			TotalAmount:     50000,
+			BookingStatus:   "reserved",
+			HoldExpiresAt:   &holdExpiresAt,
+			Seats:           []*models.BookingSeat{{SeatID: f.seatID, Price: 50000}},
+		}, 30*time.Minute)
+	})
+
+	succeeded := 0
+	for i, err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		var seatErr *repository.SeatTakenError
+		if !errors.As(err, &seatErr) {
+			t.Errorf("booker %d: got error %v, want *SeatTakenError", i, err)
+			continue
+		}
+		if !reflect.DeepEqual(seatErr.SeatIDs, []int{f.seatID}) {
+			t.Errorf("booker %d: SeatTakenError.SeatIDs = %v, want [%d]", i, seatErr.SeatIDs, f.seatID)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("%d bookings succeeded, want exactly 1", succeeded)
+	}
+
+	var held int
+	err := db.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM booking_seats WHERE showtime_id = $1 AND seat_id = $2`,
+		f.showtimeID, f.seatID).Scan(&held)
+	if err != nil {
+		t.Fatalf("failed to count booking seats: %v", err)
+	}
+	if held != 1 {
+		t.Fatalf("seat is held by %d booking seats, want 1", held)
+	}
+}
+
+func TestCreateBookingConcurrentSameSeatRespondsConflict(t *testing.T) {
+	db := openTestDB(t)
+	f := newBookingFixture(t, db, concurrentBookers)
+
+	cfg := &config.Config{Booking: config.BookingConfig{HoldMinutes: 15, ProcessingGraceMinutes: 30}}
+	bookingService := service.NewBookingService(
+		repository.NewBookingRepository(db),
+		repository.NewCinemaRepository(db),
+		repository.NewShowtimeRepository(db),
+		repository.NewPaymentRepository(db),
+		nil,
+		cfg,
+		zap.NewNop(),
+	)
+
+	errs := race(func(i int) error {
+		_, err := bookingService.CreateBooking(context.Background(), f.userIDs[i], &dto.BookingRequest{
+			ShowtimeID:    f.showtimeID,
+			SeatIDs:       []int{f.seatID},
+			PaymentMethod: f.paymentMethodID,
+		})
+		return err
+	})
+
+	succeeded := 0
+	for i, err := range errs {
+		if err == nil {
+			succeeded++
+			continue
+		}
+		if !errors.Is(err, service.ErrSeatTaken) {
+			t.Errorf("booker %d: got error %v, want ErrSeatTaken", i, err)
+			continue
+		}
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/bookings", nil)
+		utils.RespondWithAppError(rec, req, err)
+		if rec.Code != http.StatusConflict {
+			t.Errorf("booker %d: responded with status %d, want %d", i, rec.Code, http.StatusConflict)
+		}
+		if code := apperror.CodeOf(err); code != apperror.CodeConflict {
+			t.Errorf("booker %d: error code %q, want %q", i, code, apperror.CodeConflict)
+		}
+	}
+	if succeeded != 1 {
+		t.Fatalf("%d bookings succeeded, want exactly 1", succeeded)
+	}
+}
+
+func TestSeatHoldExpiryOutsideUTC(t *testing.T) {
+	db := openTestDB(t)
+	useLocalZone(t)
+	f := newBookingFixture(t, db, 1)
+	ctx := context.Background()
+	repo := repository.NewBookingRepository(db)
+
+	// PostgreSQL keeps microseconds
+	holdExpiresAt := time.Now().Add(10 * time.Minute).Truncate(time.Microsecond)
+	booking := &models.Booking{
+		UserID:          f.userIDs[0],
+		CinemaID:        f.cinemaID,
+		ShowtimeID:      f.showtimeID,
+		PaymentMethodID: &f.paymentMethodID,
+		PaymentStatus:   "pending",
+		TotalAmount:     50000,
+		BookingStatus:   "reserved",
+		HoldExpiresAt:   &holdExpiresAt,
+		Seats:           []*models.BookingSeat{{SeatID: f.seatID, Price: 50000}},
+	}
+	if err := repo.CreateWithSeats(ctx, booking, 30*time.Minute); err != nil {
+		t.Fatalf("CreateWithSeats() error = %v", err)
+	}
+
+	if _, err := repo.ExpireStaleHolds(ctx, 30*time.Minute); err != nil {
+		t.Fatalf("ExpireStaleHolds() error = %v", err)
+	}
+	got, err := repo.GetByID(ctx, booking.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if got.BookingStatus != "reserved" {
+		t.Fatalf("booking status = %q before its hold expired, want reserved", got.BookingStatus)
+	}
+	if got.HoldExpiresAt == nil || !got.HoldExpiresAt.Equal(holdExpiresAt) {
+		t.Fatalf("HoldExpiresAt = %v, want %v", got.HoldExpiresAt, holdExpiresAt)
+	}
+
+	lapsed := time.Now().Add(-time.Minute)
+	if _, err := db.Exec(ctx, `UPDATE bookings SET hold_expires_at = $1 WHERE id = $2`, lapsed, booking.ID); err != nil {
+		t.Fatalf("failed to move hold into the past: %v", err)
+	}
+	if _, err := repo.ExpireStaleHolds(ctx, 30*time.Minute); err != nil {
+		t.Fatalf("ExpireStaleHolds() error = %v", err)
+	}
+	got, err = repo.GetByID(ctx, booking.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if got.BookingStatus != "expired" {
+		t.Fatalf("booking status = %q after its hold expired, want expired", got.BookingStatus)
+	}
+}
+
+func TestCancelPaidBookingWithoutChargeReference(t *testing.T) {
+	tests := []struct {
+		name       string
+		withCharge bool
+	}{
+		{"no charge", false},
+		{"charge without reference", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openTestDB(t)
+			f := newBookingFixture(t, db, 1)
+			ctx := context.Background()
+
+			cfg := &config.Config{Booking: config.BookingConfig{HoldMinutes: 15, ProcessingGraceMinutes: 30, CancelCutoffHours: 2}}
+			bookingService := service.NewBookingService(
+				repository.NewBookingRepository(db),
+				repository.NewCinemaRepository(db),
+				repository.NewShowtimeRepository(db),
+				repository.NewPaymentRepository(db),
+				nil,
+				cfg,
+				zap.NewNop(),
+			)
+
+			booking, err := bookingService.CreateBooking(ctx, f.userIDs[0], &dto.BookingRequest{
+				ShowtimeID:    f.showtimeID,
+				SeatIDs:       []int{f.seatID},
+				PaymentMethod: f.paymentMethodID,
+			})
+			if err != nil {
+				t.Fatalf("CreateBooking() error = %v", err)
+			}
+
+			// Paid before gateways existed, or by a gateway that kept no reference
+			var chargeID *int
+			if tt.withCharge {
+				var id int
+				err := db.QueryRow(ctx, `
+					INSERT INTO payments (booking_id, provider, type, amount, status)
+					VALUES ($1, 'mock', 'charge', 50000, 'succeeded')
+					RETURNING id
+				`, booking.ID).Scan(&id)
+				if err != nil {
+					t.Fatalf("failed to insert charge: %v", err)
+				}
+				chargeID = &id
+			}
+			_, err = db.Exec(ctx, `
+				UPDATE bookings SET booking_status = 'paid', payment_status = 'paid', paid_payment_id = $1
+				WHERE id = $2
+			`, chargeID, booking.ID)
+			if err != nil {
+				t.Fatalf("failed to mark booking paid: %v", err)
+			}
+
+			resp, err := bookingService.CancelBooking(ctx, f.userIDs[0], booking.ID)
+			if err != nil {
+				t.Fatalf("CancelBooking() error = %v", err)
+			}
+			if resp.Refund == nil || resp.Refund.Status != "pending" {
+				t.Fatalf("CancelBooking() refund = %+v, want a pending manual refund", resp.Refund)
+			}
+			if resp.Booking.BookingStatus != "cancelled" || resp.Booking.PaymentStatus != "refund_manual" {
+				t.Fatalf("cancelled booking is %q with payment %q, want cancelled and refund_manual",
+					resp.Booking.BookingStatus, resp.Booking.PaymentStatus)
+			}
+
+			var refunds int
+			err = db.QueryRow(ctx, `
+				SELECT COUNT(*) FROM payments WHERE booking_id = $1 AND type = 'refund' AND status = 'pending'
+			`, booking.ID).Scan(&refunds)
+			if err != nil {
+				t.Fatalf("failed to count refunds: %v", err)
+			}
+			if refunds != 1 {
+				t.Fatalf("%d pending refunds recorded, want 1", refunds)
+			}
+		})
+	}
+}
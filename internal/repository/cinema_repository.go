@@ -2,14 +2,25 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"cinema-booking-system/internal/apperror"
 	"cinema-booking-system/internal/models"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// ErrCinemaInUse is returned when bookings reference a cinema or seat. Booking
+// and payment history is kept for accounting, so such rows are never deleted.
+var ErrCinemaInUse = apperror.Conflict("cinema has bookings and cannot be deleted")
+
+// ErrNotCinemaManager is returned when a user without the cinema_manager role
+// is assigned to a cinema
+var ErrNotCinemaManager = apperror.Validation("user must have the cinema_manager role")
+
 // CinemaRepository handles cinema-related database operations
 type CinemaRepository struct {
 	db *pgxpool.Pool
@@ -93,7 +104,7 @@ func (r *CinemaRepository) GetByID(ctx context.Context, id int) (*models.Cinema,
 	)
 
 	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("cinema not found")
+		return nil, apperror.NotFound("cinema not found")
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get cinema: %w", err)
@@ -142,7 +153,7 @@ func (r *CinemaRepository) GetSeats(ctx context.Context, cinemaID int) ([]*model
 }
 
 // GetSeatsAvailability retrieves seat availability for a specific showtime
-func (r *CinemaRepository) GetSeatsAvailability(ctx context.Context, cinemaID int, date, time string) ([]*models.SeatAvailability, error) {
+func (r *CinemaRepository) GetSeatsAvailability(ctx context.Context, showtimeID int) ([]*models.SeatAvailability, error) {
 	query := `
 		SELECT 
 			s.id,
@@ -155,17 +166,25 @@ func (r *CinemaRepository) GetSeatsAvailability(ctx context.Context, cinemaID in
 				WHEN b.id IS NULL THEN true 
 				ELSE false 
 			END as is_available
-		FROM seats s
-		LEFT JOIN bookings b ON s.id = b.seat_id 
-			AND b.cinema_id = $1 
-			AND b.booking_date = $2 
-			AND b.booking_time = $3
-			AND b.booking_status IN ('reserved', 'paid')
-		WHERE s.cinema_id = $1
+		FROM showtimes st
+		INNER JOIN seats s ON s.cinema_id = st.cinema_id
+		LEFT JOIN (
+			SELECT bs.seat_id, b.id
+			FROM booking_seats bs
+			INNER JOIN bookings b ON bs.booking_id = b.id
+			WHERE bs.showtime_id = $1
+			  AND b.booking_status IN ('reserved', 'paid')
+			  AND NOT (
+				b.booking_status = 'reserved'
+				AND b.payment_status IN ('pending', 'failed')
+				AND b.hold_expires_at < CURRENT_TIMESTAMP
+			  )
+		) b ON s.id = b.seat_id
+		WHERE st.id = $1
 		ORDER BY s.row_number, s.seat_number
 	`
 
-	rows, err := r.db.Query(ctx, query, cinemaID, date, time)
+	rows, err := r.db.Query(ctx, query, showtimeID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get seat availability: %w", err)
 	}
@@ -215,7 +234,7 @@ func (r *CinemaRepository) GetSeatByID(ctx context.Context, seatID int) (*models
 	)
 
 	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("seat not found")
+		return nil, apperror.NotFound("seat not found")
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get seat: %w", err)
@@ -223,3 +242,323 @@ func (r *CinemaRepository) GetSeatByID(ctx context.Context, seatID int) (*models
 
 	return &seat, nil
 }
+
+// GetSeatsByIDs retrieves the seats with the given IDs
+func (r *CinemaRepository) GetSeatsByIDs(ctx context.Context, seatIDs []int) ([]*models.Seat, error) {
+	query := `
+		SELECT id, cinema_id, seat_number, row_number, seat_type, price
+		FROM seats
+		WHERE id = ANY($1)
+		ORDER BY row_number, seat_number
+	`
+
+	rows, err := r.db.Query(ctx, query, seatIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get seats: %w", err)
+	}
+	defer rows.Close()
+
+	var seats []*models.Seat
+	for rows.Next() {
+		var seat models.Seat
+		err := rows.Scan(
+			&seat.ID,
+			&seat.CinemaID,
+			&seat.SeatNumber,
+			&seat.RowNumber,
+			&seat.SeatType,
+			&seat.Price,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan seat: %w", err)
+		}
+		seats = append(seats, &seat)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating seats: %w", err)
+	}
+
+	return seats, nil
+}
+
+// AddManager assigns a user as manager of a cinema. It refuses with
+// ErrNotCinemaManager unless the user has the cinema_manager role.
+func (r *CinemaRepository) AddManager(ctx context.Context, cinemaID, userID int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Hold the user's role until the assignment commits; a concurrent role
+	// change would otherwise leave the assignment behind
+	var role string
+	err = tx.QueryRow(ctx, `SELECT role FROM users WHERE id = $1 FOR SHARE`, userID).Scan(&role)
+	if err == pgx.ErrNoRows {
+		return apperror.NotFound("user not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock user: %w", err)
+	}
+	if role != models.RoleCinemaManager {
+		return ErrNotCinemaManager
+	}
+
+	query := `
+		INSERT INTO cinema_managers (user_id, cinema_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, cinema_id) DO NOTHING
+	`
+
+	_, err = tx.Exec(ctx, query, userID, cinemaID)
+	if err != nil {
+		return fmt.Errorf("failed to add cinema manager: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit cinema manager: %w", err)
+	}
+
+	return nil
+}
+
+// RemoveManager unassigns a user from managing a cinema
+func (r *CinemaRepository) RemoveManager(ctx context.Context, cinemaID, userID int) error {
+	query := `DELETE FROM cinema_managers WHERE user_id = $1 AND cinema_id = $2`
+
+	result, err := r.db.Exec(ctx, query, userID, cinemaID)
+	if err != nil {
+		return fmt.Errorf("failed to remove cinema manager: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return apperror.NotFound("cinema manager not found")
+	}
+
+	return nil
+}
+
+// IsManagedBy reports whether a user manages a cinema
+func (r *CinemaRepository) IsManagedBy(ctx context.Context, cinemaID, userID int) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM cinema_managers WHERE user_id = $1 AND cinema_id = $2
+		)
+	`
+
+	var managed bool
+	if err := r.db.QueryRow(ctx, query, userID, cinemaID).Scan(&managed); err != nil {
+		return false, fmt.Errorf("failed to check cinema manager: %w", err)
+	}
+
+	return managed, nil
+}
+
+// Create inserts a new cinema with no seats
+func (r *CinemaRepository) Create(ctx context.Context, cinema *models.Cinema) error {
+	query := `
+		INSERT INTO cinemas (name, location, description, total_seats)
+		VALUES ($1, $2, $3, 0)
+		RETURNING id, total_seats, created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		cinema.Name,
+		cinema.Location,
+		cinema.Description,
+	).Scan(&cinema.ID, &cinema.TotalSeats, &cinema.CreatedAt, &cinema.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create cinema: %w", err)
+	}
+
+	return nil
+}
+
+// Update changes the name, location and description of a cinema
+func (r *CinemaRepository) Update(ctx context.Context, cinema *models.Cinema) error {
+	query := `
+		UPDATE cinemas
+		SET name = $1, location = $2, description = $3, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4
+		RETURNING total_seats, created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		cinema.Name,
+		cinema.Location,
+		cinema.Description,
+		cinema.ID,
+	).Scan(&cinema.TotalSeats, &cinema.CreatedAt, &cinema.UpdatedAt)
+
+	if err == pgx.ErrNoRows {
+		return apperror.NotFound("cinema not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update cinema: %w", err)
+	}
+
+	return nil
+}
+
+// Delete removes a cinema together with its seats and showtimes. It refuses
+// with ErrCinemaInUse once any booking, past or upcoming, references it.
+func (r *CinemaRepository) Delete(ctx context.Context, cinemaID int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the cinema; new bookings reference it and wait for this lock
+	if err := lockCinema(ctx, tx, cinemaID); err != nil {
+		return err
+	}
+
+	var inUse bool
+	err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM bookings WHERE cinema_id = $1)`, cinemaID).Scan(&inUse)
+	if err != nil {
+		return fmt.Errorf("failed to check cinema bookings: %w", err)
+	}
+	if inUse {
+		return ErrCinemaInUse
+	}
+
+	// Showtimes restrict deletion of their cinema, so remove them first
+	if _, err := tx.Exec(ctx, `DELETE FROM showtimes WHERE cinema_id = $1`, cinemaID); err != nil {
+		return fmt.Errorf("failed to delete cinema showtimes: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM cinemas WHERE id = $1`, cinemaID); err != nil {
+		return fmt.Errorf("failed to delete cinema: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit cinema deletion: %w", err)
+	}
+
+	return nil
+}
+
+// UpsertSeats creates the given seats, updating type and price of seats that
+// already exist, and refreshes the cinema's total_seats
+func (r *CinemaRepository) UpsertSeats(ctx context.Context, cinemaID int, seats []*models.Seat) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockCinema(ctx, tx, cinemaID); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO seats (cinema_id, seat_number, row_number, seat_type, price)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (cinema_id, seat_number)
+		DO UPDATE SET row_number = EXCLUDED.row_number, seat_type = EXCLUDED.seat_type, price = EXCLUDED.price
+		RETURNING id
+	`
+
+	for _, seat := range seats {
+		seat.CinemaID = cinemaID
+		err := tx.QueryRow(ctx, query,
+			cinemaID,
+			seat.SeatNumber,
+			seat.RowNumber,
+			seat.SeatType,
+			seat.Price,
+		).Scan(&seat.ID)
+		if err != nil {
+			return fmt.Errorf("failed to save seat %s: %w", seat.SeatNumber, err)
+		}
+	}
+
+	if err := syncTotalSeats(ctx, tx, cinemaID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit seat layout: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteSeat removes a seat from a cinema and refreshes total_seats. It
+// refuses with ErrCinemaInUse once the seat appears in any booking.
+func (r *CinemaRepository) DeleteSeat(ctx context.Context, cinemaID, seatID int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := lockCinema(ctx, tx, cinemaID); err != nil {
+		return err
+	}
+
+	var inUse bool
+	err = tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM booking_seats WHERE seat_id = $1)`, seatID).Scan(&inUse)
+	if err != nil {
+		return fmt.Errorf("failed to check seat bookings: %w", err)
+	}
+	if inUse {
+		return ErrCinemaInUse
+	}
+
+	// Bookings lock the showtime rather than the cinema, so one may take the
+	// seat after the check above; the foreign key then refuses the delete
+	result, err := tx.Exec(ctx, `DELETE FROM seats WHERE id = $1 AND cinema_id = $2`, seatID, cinemaID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == foreignKeyViolation {
+			return ErrCinemaInUse
+		}
+		return fmt.Errorf("failed to delete seat: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return apperror.NotFound("seat not found")
+	}
+
+	if err := syncTotalSeats(ctx, tx, cinemaID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit seat deletion: %w", err)
+	}
+
+	return nil
+}
+
+// lockCinema locks a cinema row for the rest of the transaction
+func lockCinema(ctx context.Context, tx pgx.Tx, cinemaID int) error {
+	var id int
+	err := tx.QueryRow(ctx, `SELECT id FROM cinemas WHERE id = $1 FOR UPDATE`, cinemaID).Scan(&id)
+	if err == pgx.ErrNoRows {
+		return apperror.NotFound("cinema not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to lock cinema: %w", err)
+	}
+
+	return nil
+}
+
+// syncTotalSeats recalculates cinemas.total_seats from the seats table
+func syncTotalSeats(ctx context.Context, tx pgx.Tx, cinemaID int) error {
+	_, err := tx.Exec(ctx, `
+		UPDATE cinemas
+		SET total_seats = (SELECT COUNT(*) FROM seats WHERE cinema_id = $1),
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, cinemaID)
+	if err != nil {
+		return fmt.Errorf("failed to update total seats: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cinema-booking-system/internal/repository"
+)
+
+func TestDeleteSeatBookedDuringDelete(t *testing.T) {
+	db := openTestDB(t)
+	f := newBookingFixture(t, db, 1)
+	ctx := context.Background()
+
+	var bookingID int
+	err := db.QueryRow(ctx, `
+		INSERT INTO bookings (user_id, cinema_id, showtime_id, payment_status, total_amount, booking_status)
+		VALUES ($1, $2, $3, 'pending', 50000, 'reserved')
+		RETURNING id
+	`, f.userIDs[0], f.cinemaID, f.showtimeID).Scan(&bookingID)
+	if err != nil {
+		t.Fatalf("failed to create booking: %v", err)
+	}
+
+	// Take the seat without committing, the way a booking in flight does
+	tx, err := db.Begin(ctx)
+	if err != nil {
+		t.Fatalf("failed to begin transaction: %v", err)
+	}
+	defer tx.Rollback(ctx)
+	_, err = tx.Exec(ctx, `
+		INSERT INTO booking_seats (booking_id, showtime_id, seat_id, price)
+		VALUES ($1, $2, $3, 50000)
+	`, bookingID, f.showtimeID, f.seatID)
+	if err != nil {
+		t.Fatalf("failed to book seat: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- repository.NewCinemaRepository(db).DeleteSeat(ctx, f.cinemaID, f.seatID)
+	}()
+
+	// Commit once the delete has passed its check and waits on the seat
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		var waiting bool
+		err := db.QueryRow(ctx, `
+			SELECT EXISTS (
+				SELECT 1 FROM pg_stat_activity
+				WHERE wait_event_type = 'Lock' AND query LIKE '%DELETE FROM seats%'
+			)
+		`).Scan(&waiting)
+		if err != nil {
+			t.Fatalf("failed to check for waiting delete: %v", err)
+		}
+		if waiting {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("DeleteSeat() never waited on the booked seat")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("failed to commit booking: %v", err)
+	}
+
+	if err := <-done; !errors.Is(err, repository.ErrCinemaInUse) {
+		t.Fatalf("DeleteSeat() error = %v, want ErrCinemaInUse", err)
+	}
+}
@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cinema-booking-system/internal/apperror"
+	"cinema-booking-system/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// IdempotencyRepository handles idempotency key database operations
+type IdempotencyRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewIdempotencyRepository creates a new idempotency repository
+func NewIdempotencyRepository(db *pgxpool.Pool) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// acquireAttempts bounds how often Acquire looks for a key that is released
+// between its claim and its read
+const acquireAttempts = 3
+
+// Acquire claims an idempotency key for a new request. It returns
+// acquired=true when the key was free (or had expired); otherwise it returns
+// the record stored by the earlier request.
+func (r *IdempotencyRepository) Acquire(ctx context.Context, userID int, key, requestHash string, ttl time.Duration) (*models.IdempotencyKey, bool, error) {
+	// Drop this user's other expired keys; the claim below takes over an
+	// expired key itself, so it does not depend on this
+	deleteQuery := `
+		DELETE FROM idempotency_keys
+		WHERE user_id = $1 AND idempotency_key <> $2 AND expires_at < CURRENT_TIMESTAMP
+	`
+	if _, err := r.db.Exec(ctx, deleteQuery, userID, key); err != nil {
+		return nil, false, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+
+	// Insert the key, or take it over in the same statement when it expired
+	claimQuery := `
+		INSERT INTO idempotency_keys (user_id, idempotency_key, request_hash, expires_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP + make_interval(secs => $4))
+		ON CONFLICT (user_id, idempotency_key) DO UPDATE
+		SET request_hash = EXCLUDED.request_hash,
+			status_code = NULL,
+			response_body = NULL,
+			created_at = CURRENT_TIMESTAMP,
+			expires_at = EXCLUDED.expires_at
+		WHERE idempotency_keys.expires_at < CURRENT_TIMESTAMP
+	`
+
+	for attempt := 0; attempt < acquireAttempts; attempt++ {
+		tag, err := r.db.Exec(ctx, claimQuery, userID, key, requestHash, ttl.Seconds())
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to acquire idempotency key: %w", err)
+		}
+		if tag.RowsAffected() == 1 {
+			return nil, true, nil
+		}
+
+		// The key is held by a live request. If that request releases it
+		// before we read it, claim it again.
+		record, err := r.Get(ctx, userID, key)
+		if apperror.CodeOf(err) == apperror.CodeNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, false, err
+		}
+
+		return record, false, nil
+	}
+
+	return nil, false, fmt.Errorf("failed to acquire idempotency key: released %d times while acquiring", acquireAttempts)
+}
+
+// Get retrieves a stored idempotency key
+func (r *IdempotencyRepository) Get(ctx context.Context, userID int, key string) (*models.IdempotencyKey, error) {
+	query := `
+		SELECT user_id, idempotency_key, request_hash, status_code, response_body,
+			   created_at, expires_at
+		FROM idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2
+	`
+
+	var record models.IdempotencyKey
+	err := r.db.QueryRow(ctx, query, userID, key).Scan(
+		&record.UserID,
+		&record.Key,
+		&record.RequestHash,
+		&record.StatusCode,
+		&record.ResponseBody,
+		&record.CreatedAt,
+		&record.ExpiresAt,
+	)
+
+	if err == pgx.ErrNoRows {
+		return nil, apperror.NotFound("idempotency key not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get idempotency key: %w", err)
+	}
+
+	return &record, nil
+}
+
+// Complete stores the response produced for an idempotency key
+func (r *IdempotencyRepository) Complete(ctx context.Context, userID int, key string, statusCode int, body []byte) error {
+	query := `
+		UPDATE idempotency_keys
+		SET status_code = $3, response_body = $4
+		WHERE user_id = $1 AND idempotency_key = $2
+	`
+
+	_, err := r.db.Exec(ctx, query, userID, key, statusCode, body)
+	if err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+
+	return nil
+}
+
+// Release removes an unfinished idempotency key so the request can be retried
+func (r *IdempotencyRepository) Release(ctx context.Context, userID int, key string) error {
+	query := `
+		DELETE FROM idempotency_keys
+		WHERE user_id = $1 AND idempotency_key = $2 AND status_code IS NULL
+	`
+
+	_, err := r.db.Exec(ctx, query, userID, key)
+	if err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+
+	return nil
+}
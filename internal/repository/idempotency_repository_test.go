@@ -0,0 +1,147 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"cinema-booking-system/internal/models"
+	"cinema-booking-system/internal/repository"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// newIdempotencyUser creates a user to own idempotency keys and removes it,
+// with its keys, when the test ends
+func newIdempotencyUser(t *testing.T, db *pgxpool.Pool) int {
+	t.Helper()
+	ctx := context.Background()
+	suffix := time.Now().UnixNano()
+
+	user := &models.User{
+		Username:     fmt.Sprintf("idempotency_%d", suffix),
+		Email:        fmt.Sprintf("idempotency_%d@example.com", suffix),
+		PasswordHash: "x",
+	}
+	if err := repository.NewUserRepository(db).Create(ctx, user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := db.Exec(ctx, `DELETE FROM users WHERE id = $1`, user.ID); err != nil {
+			t.Errorf("failed to clean up user: %v", err)
+		}
+	})
+
+	return user.ID
+}
+
+func TestAcquireConcurrentSameKey(t *testing.T) {
+	db := openTestDB(t)
+	userID := newIdempotencyUser(t, db)
+	repo := repository.NewIdempotencyRepository(db)
+
+	acquired := make([]bool, concurrentBookers)
+	records := make([]*models.IdempotencyKey, concurrentBookers)
+	errs := race(func(i int) error {
+		var err error
+		records[i], acquired[i], err = repo.Acquire(context.Background(), userID, "same-key", "hash", time.Hour)
+		return err
+	})
+
+	winners := 0
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("Acquire() #%d error = %v", i, err)
+		}
+		if acquired[i] {
+			winners++
+			continue
+		}
+		if records[i] == nil || records[i].RequestHash != "hash" {
+			t.Errorf("Acquire() #%d returned record %+v, want the winner's", i, records[i])
+		}
+	}
+	if winners != 1 {
+		t.Fatalf("%d requests acquired the key, want exactly 1", winners)
+	}
+}
+
+func TestAcquireTakesOverExpiredKey(t *testing.T) {
+	db := openTestDB(t)
+	userID := newIdempotencyUser(t, db)
+	repo := repository.NewIdempotencyRepository(db)
+	ctx := context.Background()
+
+	if _, _, err := repo.Acquire(ctx, userID, "expired-key", "old", time.Hour); err != nil {
+		t.Fatalf("Acquire() error = %v", err)
+	}
+	if err := repo.Complete(ctx, userID, "expired-key", 201, []byte(`{}`)); err != nil {
+		t.Fatalf("Complete() error = %v", err)
+	}
+	_, err := db.Exec(ctx, `
+		UPDATE idempotency_keys SET expires_at = CURRENT_TIMESTAMP - INTERVAL '1 minute'
+		WHERE user_id = $1 AND idempotency_key = $2
+	`, userID, "expired-key")
+	if err != nil {
+		t.Fatalf("failed to expire key: %v", err)
+	}
+
+	acquired := make([]bool, concurrentBookers)
+	errs := race(func(i int) error {
+		var err error
+		_, acquired[i], err = repo.Acquire(ctx, userID, "expired-key", "new", time.Hour)
+		return err
+	})
+
+	winners := 0
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("Acquire() #%d error = %v", i, err)
+		}
+		if acquired[i] {
+			winners++
+		}
+	}
+	if winners != 1 {
+		t.Fatalf("%d requests took over the expired key, want exactly 1", winners)
+	}
+
+	record, err := repo.Get(ctx, userID, "expired-key")
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if record.RequestHash != "new" || record.StatusCode != nil || record.ResponseBody != nil {
+		t.Fatalf("taken over key = %+v, want the new request with no response", record)
+	}
+}
+
+func TestAcquireWhileKeyIsReleased(t *testing.T) {
+	db := openTestDB(t)
+	userID := newIdempotencyUser(t, db)
+	repo := repository.NewIdempotencyRepository(db)
+
+	// Every winner releases the key at once, so losers often find it gone
+	// when they read it
+	errs := race(func(i int) error {
+		ctx := context.Background()
+		for round := 0; round < 10; round++ {
+			_, acquired, err := repo.Acquire(ctx, userID, "released-key", "hash", time.Hour)
+			if err != nil {
+				return err
+			}
+			if acquired {
+				if err := repo.Release(ctx, userID, "released-key"); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("Acquire() #%d error = %v", i, err)
+		}
+	}
+}
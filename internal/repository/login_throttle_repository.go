@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Login throttle scopes
+const (
+	ThrottleScopeUsername = "username"
+	ThrottleScopeIP       = "ip"
+)
+
+// LoginThrottleRepository handles failed login tracking database operations
+type LoginThrottleRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewLoginThrottleRepository creates a new login throttle repository
+func NewLoginThrottleRepository(db *pgxpool.Pool) *LoginThrottleRepository {
+	return &LoginThrottleRepository{db: db}
+}
+
+// LoginAttempt is a login attempt counted against a subject before its
+// credentials are checked
+type LoginAttempt struct {
+	// Allowed is false when the subject was locked and the attempt was not counted
+	Allowed bool
+	// Attempts is the number of consecutive failures, counting this attempt
+	Attempts int
+	// LockedUntil is the lock that refused the attempt, or the lock the
+	// attempt placed because it reached the limit
+	LockedUntil *time.Time
+}
+
+// BeginAttempt counts a login attempt against a subject before its
+// credentials are checked, so that parallel guesses cannot all slip in
+// before any failure is recorded. The row is locked while the count is read
+// and updated. When the subject is locked the attempt is refused and not
+// counted. Otherwise the count goes up, starting over when the previous
+// failure is older than window, and the subject is locked for
+// lockout(attempts) if that is non-zero. A successful attempt is handed
+// back with ReleaseAttempt.
+func (r *LoginThrottleRepository) BeginAttempt(ctx context.Context, scope, subject string, window time.Duration, lockout func(attempts int) time.Duration) (*LoginAttempt, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO login_throttles (scope, subject, failed_attempts, last_failed_at)
+		VALUES ($1, $2, 0, CURRENT_TIMESTAMP)
+		ON CONFLICT (scope, subject) DO NOTHING
+	`, scope, subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create login throttle: %w", err)
+	}
+
+	var (
+		attempt LoginAttempt
+		locked  bool
+		stale   bool
+	)
+	err = tx.QueryRow(ctx, `
+		SELECT failed_attempts, locked_until,
+			COALESCE(locked_until > CURRENT_TIMESTAMP, false),
+			last_failed_at < CURRENT_TIMESTAMP - make_interval(secs => $3)
+		FROM login_throttles
+		WHERE scope = $1 AND subject = $2
+		FOR UPDATE
+	`, scope, subject, window.Seconds()).Scan(&attempt.Attempts, &attempt.LockedUntil, &locked, &stale)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login throttle: %w", err)
+	}
+
+	if !locked {
+		if err := r.countAttempt(ctx, tx, scope, subject, &attempt, stale, lockout); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return &attempt, nil
+}
+
+// countAttempt allows an attempt on a subject that is not locked, adding it
+// to the failure count and placing the lock the new count calls for
+func (r *LoginThrottleRepository) countAttempt(ctx context.Context, tx pgx.Tx, scope, subject string, attempt *LoginAttempt, stale bool, lockout func(attempts int) time.Duration) error {
+	attempt.Allowed = true
+	attempt.LockedUntil = nil
+	if stale {
+		attempt.Attempts = 0
+	}
+	attempt.Attempts++
+
+	err := tx.QueryRow(ctx, `
+		UPDATE login_throttles
+		SET failed_attempts = $3,
+			last_failed_at = CURRENT_TIMESTAMP,
+			locked_until = CASE
+				WHEN $4::float8 > 0 THEN CURRENT_TIMESTAMP + make_interval(secs => $4)
+				ELSE locked_until
+			END
+		WHERE scope = $1 AND subject = $2
+		RETURNING CASE WHEN $4::float8 > 0 THEN locked_until END
+	`, scope, subject, attempt.Attempts, lockout(attempt.Attempts).Seconds()).Scan(&attempt.LockedUntil)
+	if err != nil {
+		return fmt.Errorf("failed to record login attempt: %w", err)
+	}
+
+	return nil
+}
+
+// ReleaseAttempt uncounts an allowed attempt whose credentials turned out to
+// be valid, lifting the lock it placed if no other attempt has replaced it
+func (r *LoginThrottleRepository) ReleaseAttempt(ctx context.Context, scope, subject string, attempt *LoginAttempt) error {
+	query := `
+		UPDATE login_throttles
+		SET failed_attempts = GREATEST(failed_attempts - 1, 0),
+			locked_until = CASE WHEN locked_until = $3 THEN NULL ELSE locked_until END
+		WHERE scope = $1 AND subject = $2
+	`
+
+	if _, err := r.db.Exec(ctx, query, scope, subject, attempt.LockedUntil); err != nil {
+		return fmt.Errorf("failed to release login attempt: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteStale removes the counters of subjects that are not locked and have
+// not failed a login within window, such as unknown usernames and IPs
+// tried once. Their count would start over on the next attempt anyway.
+func (r *LoginThrottleRepository) DeleteStale(ctx context.Context, window time.Duration) (int64, error) {
+	query := `
+		DELETE FROM login_throttles
+		WHERE last_failed_at < CURRENT_TIMESTAMP - make_interval(secs => $1)
+		  AND (locked_until IS NULL OR locked_until <= CURRENT_TIMESTAMP)
+	`
+
+	result, err := r.db.Exec(ctx, query, window.Seconds())
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete stale login throttles: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
+// Reset clears the failed attempts and any lock of a subject and reports
+// whether there was anything to clear
+func (r *LoginThrottleRepository) Reset(ctx context.Context, scope, subject string) (bool, error) {
+	query := `DELETE FROM login_throttles WHERE scope = $1 AND subject = $2`
+
+	result, err := r.db.Exec(ctx, query, scope, subject)
+	if err != nil {
+		return false, fmt.Errorf("failed to reset login throttle: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
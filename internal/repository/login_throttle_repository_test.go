@@ -0,0 +1,141 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"cinema-booking-system/internal/config"
+	"cinema-booking-system/internal/repository"
+)
+
+func TestBeginAttemptConcurrentBurstStopsAtLimit(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	repo := repository.NewLoginThrottleRepository(db)
+
+	const maxAttempts = 5
+	cfg := &config.Config{Auth: config.AuthConfig{LoginLockoutSeconds: 60, LoginMaxLockoutMinutes: 60}}
+	lockout := func(failures int) time.Duration {
+		return cfg.GetLoginLockout(failures, maxAttempts)
+	}
+
+	subject := fmt.Sprintf("burst_%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		if _, err := repo.Reset(ctx, repository.ThrottleScopeUsername, subject); err != nil {
+			t.Errorf("failed to clean up login throttle: %v", err)
+		}
+	})
+
+	attempts := make([]*repository.LoginAttempt, concurrentBookers)
+	errs := race(func(i int) error {
+		attempt, err := repo.BeginAttempt(ctx, repository.ThrottleScopeUsername, subject, 15*time.Minute, lockout)
+		attempts[i] = attempt
+		return err
+	})
+
+	allowed := 0
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("attempt %d: BeginAttempt() error = %v", i, err)
+		}
+		if attempts[i].Allowed {
+			allowed++
+			continue
+		}
+		if attempts[i].LockedUntil == nil || !attempts[i].LockedUntil.After(time.Now()) {
+			t.Errorf("attempt %d: refused with LockedUntil = %v, want a lock in the future", i, attempts[i].LockedUntil)
+		}
+	}
+	if allowed != maxAttempts {
+		t.Fatalf("%d of %d parallel attempts were allowed, want %d", allowed, concurrentBookers, maxAttempts)
+	}
+}
+
+func TestReleaseAttemptLiftsOwnLock(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	repo := repository.NewLoginThrottleRepository(db)
+
+	subject := fmt.Sprintf("release_%d", time.Now().UnixNano())
+	t.Cleanup(func() {
+		if _, err := repo.Reset(ctx, repository.ThrottleScopeIP, subject); err != nil {
+			t.Errorf("failed to clean up login throttle: %v", err)
+		}
+	})
+
+	lockAlways := func(int) time.Duration { return time.Minute }
+	attempt, err := repo.BeginAttempt(ctx, repository.ThrottleScopeIP, subject, 15*time.Minute, lockAlways)
+	if err != nil {
+		t.Fatalf("BeginAttempt() error = %v", err)
+	}
+	if !attempt.Allowed || attempt.LockedUntil == nil {
+		t.Fatalf("first attempt = %+v, want allowed and placing a lock", attempt)
+	}
+
+	if err := repo.ReleaseAttempt(ctx, repository.ThrottleScopeIP, subject, attempt); err != nil {
+		t.Fatalf("ReleaseAttempt() error = %v", err)
+	}
+
+	next, err := repo.BeginAttempt(ctx, repository.ThrottleScopeIP, subject, 15*time.Minute, func(int) time.Duration { return 0 })
+	if err != nil {
+		t.Fatalf("BeginAttempt() error = %v", err)
+	}
+	if !next.Allowed || next.Attempts != 1 {
+		t.Fatalf("attempt after release = %+v, want allowed as the first attempt", next)
+	}
+}
+
+func TestDeleteStaleKeepsRecentAndLockedThrottles(t *testing.T) {
+	db := openTestDB(t)
+	ctx := context.Background()
+	repo := repository.NewLoginThrottleRepository(db)
+
+	suffix := time.Now().UnixNano()
+	stale := fmt.Sprintf("stale_%d", suffix)
+	recent := fmt.Sprintf("recent_%d", suffix)
+	locked := fmt.Sprintf("locked_%d", suffix)
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, `DELETE FROM login_throttles WHERE subject = ANY($1)`, []string{stale, recent, locked})
+		if err != nil {
+			t.Errorf("failed to clean up login throttles: %v", err)
+		}
+	})
+
+	_, err := db.Exec(ctx, `
+		INSERT INTO login_throttles (scope, subject, failed_attempts, locked_until, last_failed_at) VALUES
+			('username', $1, 1, NULL, CURRENT_TIMESTAMP - INTERVAL '1 hour'),
+			('username', $2, 1, NULL, CURRENT_TIMESTAMP),
+			('username', $3, 9, CURRENT_TIMESTAMP + INTERVAL '1 hour', CURRENT_TIMESTAMP - INTERVAL '1 hour')
+	`, stale, recent, locked)
+	if err != nil {
+		t.Fatalf("failed to create login throttles: %v", err)
+	}
+
+	if _, err := repo.DeleteStale(ctx, 15*time.Minute); err != nil {
+		t.Fatalf("DeleteStale() error = %v", err)
+	}
+
+	var remaining []string
+	rows, err := db.Query(ctx, `SELECT subject FROM login_throttles WHERE subject = ANY($1) ORDER BY subject`,
+		[]string{stale, recent, locked})
+	if err != nil {
+		t.Fatalf("failed to list login throttles: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var subject string
+		if err := rows.Scan(&subject); err != nil {
+			t.Fatalf("failed to scan login throttle: %v", err)
+		}
+		remaining = append(remaining, subject)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("failed to list login throttles: %v", err)
+	}
+
+	if len(remaining) != 2 || remaining[0] != locked || remaining[1] != recent {
+		t.Fatalf("remaining throttles = %v, want [%s %s]", remaining, locked, recent)
+	}
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"cinema-booking-system/internal/apperror"
+	"cinema-booking-system/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// MovieRepository handles movie-related database operations
+type MovieRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewMovieRepository creates a new movie repository
+func NewMovieRepository(db *pgxpool.Pool) *MovieRepository {
+	return &MovieRepository{db: db}
+}
+
+// Create inserts a new movie into the database
+func (r *MovieRepository) Create(ctx context.Context, movie *models.Movie) error {
+	query := `
+		INSERT INTO movies (title, description, duration_minutes, rating, language)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		movie.Title,
+		movie.Description,
+		movie.DurationMinutes,
+		movie.Rating,
+		movie.Language,
+	).Scan(&movie.ID, &movie.CreatedAt, &movie.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create movie: %w", err)
+	}
+
+	return nil
+}
+
+// GetAll retrieves all movies with pagination
+func (r *MovieRepository) GetAll(ctx context.Context, limit, offset int) ([]*models.Movie, error) {
+	query := `
+		SELECT id, title, COALESCE(description, ''), duration_minutes, rating, language,
+			   created_at, updated_at
+		FROM movies
+		ORDER BY id
+		LIMIT $1 OFFSET $2
+	`
+
+	rows, err := r.db.Query(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get movies: %w", err)
+	}
+	defer rows.Close()
+
+	var movies []*models.Movie
+	for rows.Next() {
+		var movie models.Movie
+		err := rows.Scan(
+			&movie.ID,
+			&movie.Title,
+			&movie.Description,
+			&movie.DurationMinutes,
+			&movie.Rating,
+			&movie.Language,
+			&movie.CreatedAt,
+			&movie.UpdatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan movie: %w", err)
+		}
+		movies = append(movies, &movie)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating movies: %w", err)
+	}
+
+	return movies, nil
+}
+
+// Count returns the total number of movies
+func (r *MovieRepository) Count(ctx context.Context) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM movies`
+
+	err := r.db.QueryRow(ctx, query).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count movies: %w", err)
+	}
+
+	return count, nil
+}
+
+// GetByID retrieves a movie by ID
+func (r *MovieRepository) GetByID(ctx context.Context, id int) (*models.Movie, error) {
+	query := `
+		SELECT id, title, COALESCE(description, ''), duration_minutes, rating, language,
+			   created_at, updated_at
+		FROM movies
+		WHERE id = $1
+	`
+
+	var movie models.Movie
+	err := r.db.QueryRow(ctx, query, id).Scan(
+		&movie.ID,
+		&movie.Title,
+		&movie.Description,
+		&movie.DurationMinutes,
+		&movie.Rating,
+		&movie.Language,
+		&movie.CreatedAt,
+		&movie.UpdatedAt,
+	)
+
+	if err == pgx.ErrNoRows {
+		return nil, apperror.NotFound("movie not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get movie: %w", err)
+	}
+
+	return &movie, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 
+	"cinema-booking-system/internal/apperror"
 	"cinema-booking-system/internal/models"
 
 	"github.com/jackc/pgx/v5"
@@ -23,7 +24,7 @@ func NewPaymentRepository(db *pgxpool.Pool) *PaymentRepository {
 // GetAllPaymentMethods retrieves all active payment methods
 func (r *PaymentRepository) GetAllPaymentMethods(ctx context.Context) ([]*models.PaymentMethod, error) {
 	query := `
-		SELECT id, name, description, is_active, created_at
+		SELECT id, name, description, provider, is_active, created_at
 		FROM payment_methods
 		WHERE is_active = true
 		ORDER BY id
@@ -42,6 +43,7 @@ func (r *PaymentRepository) GetAllPaymentMethods(ctx context.Context) ([]*models
 			&method.ID,
 			&method.Name,
 			&method.Description,
+			&method.Provider,
 			&method.IsActive,
 			&method.CreatedAt,
 		)
@@ -61,7 +63,7 @@ func (r *PaymentRepository) GetAllPaymentMethods(ctx context.Context) ([]*models
 // GetPaymentMethodByID retrieves a payment method by ID
 func (r *PaymentRepository) GetPaymentMethodByID(ctx context.Context, id int) (*models.PaymentMethod, error) {
 	query := `
-		SELECT id, name, description, is_active, created_at
+		SELECT id, name, description, provider, is_active, created_at
 		FROM payment_methods
 		WHERE id = $1
 	`
@@ -71,12 +73,13 @@ func (r *PaymentRepository) GetPaymentMethodByID(ctx context.Context, id int) (*
 		&method.ID,
 		&method.Name,
 		&method.Description,
+		&method.Provider,
 		&method.IsActive,
 		&method.CreatedAt,
 	)
 
 	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("payment method not found")
+		return nil, apperror.NotFound("payment method not found")
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get payment method: %w", err)
@@ -101,3 +104,162 @@ func (r *PaymentRepository) ValidatePaymentMethod(ctx context.Context, id int) (
 
 	return count > 0, nil
 }
+
+// CreatePayment records a new charge or refund attempt
+func (r *PaymentRepository) CreatePayment(ctx context.Context, payment *models.Payment) error {
+	query := `
+		INSERT INTO payments (
+			booking_id, payment_method_id, provider, provider_reference,
+			type, amount, status, reason
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''))
+		RETURNING id, created_at, updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		payment.BookingID,
+		payment.PaymentMethodID,
+		payment.Provider,
+		payment.ProviderReference,
+		payment.Type,
+		payment.Amount,
+		payment.Status,
+		payment.Reason,
+	).Scan(&payment.ID, &payment.CreatedAt, &payment.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create payment: %w", err)
+	}
+
+	return nil
+}
+
+// UpdatePaymentResult stores the gateway outcome of a payment attempt
+func (r *PaymentRepository) UpdatePaymentResult(ctx context.Context, payment *models.Payment) error {
+	query := `
+		UPDATE payments
+		SET provider_reference = $1, status = $2, reason = NULLIF($3, ''), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $4
+		RETURNING updated_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		payment.ProviderReference,
+		payment.Status,
+		payment.Reason,
+		payment.ID,
+	).Scan(&payment.UpdatedAt)
+
+	if err == pgx.ErrNoRows {
+		return apperror.NotFound("payment not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update payment: %w", err)
+	}
+
+	return nil
+}
+
+// GetSucceededCharge retrieves the charge that paid a booking
+func (r *PaymentRepository) GetSucceededCharge(ctx context.Context, bookingID int) (*models.Payment, error) {
+	query := `
+		SELECT p.id, p.booking_id, p.payment_method_id, p.provider, p.provider_reference,
+			   p.type, p.amount, p.status, COALESCE(p.reason, ''), p.created_at, p.updated_at
+		FROM payments p
+		INNER JOIN bookings b ON b.paid_payment_id = p.id
+		WHERE b.id = $1 AND p.status = 'succeeded'
+	`
+
+	var payment models.Payment
+	err := r.db.QueryRow(ctx, query, bookingID).Scan(
+		&payment.ID,
+		&payment.BookingID,
+		&payment.PaymentMethodID,
+		&payment.Provider,
+		&payment.ProviderReference,
+		&payment.Type,
+		&payment.Amount,
+		&payment.Status,
+		&payment.Reason,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+	)
+
+	if err == pgx.ErrNoRows {
+		return nil, apperror.NotFound("payment not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+
+	return &payment, nil
+}
+
+// GetPaymentByReference retrieves a payment by its provider reference
+func (r *PaymentRepository) GetPaymentByReference(ctx context.Context, provider, reference string) (*models.Payment, error) {
+	query := `
+		SELECT id, booking_id, payment_method_id, provider, provider_reference,
+			   type, amount, status, COALESCE(reason, ''), created_at, updated_at
+		FROM payments
+		WHERE provider = $1 AND provider_reference = $2
+	`
+
+	var payment models.Payment
+	err := r.db.QueryRow(ctx, query, provider, reference).Scan(
+		&payment.ID,
+		&payment.BookingID,
+		&payment.PaymentMethodID,
+		&payment.Provider,
+		&payment.ProviderReference,
+		&payment.Type,
+		&payment.Amount,
+		&payment.Status,
+		&payment.Reason,
+		&payment.CreatedAt,
+		&payment.UpdatedAt,
+	)
+
+	if err == pgx.ErrNoRows {
+		return nil, apperror.NotFound("payment not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get payment: %w", err)
+	}
+
+	return &payment, nil
+}
+
+// RecordWebhookEvent stores a provider webhook event and reports whether an
+// event with the same ID has already been fully processed
+func (r *PaymentRepository) RecordWebhookEvent(ctx context.Context, provider, eventID string, payload []byte) (bool, error) {
+	query := `
+		INSERT INTO payment_webhook_events (provider, event_id, payload)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (provider, event_id) DO UPDATE SET provider = EXCLUDED.provider
+		RETURNING processed_at IS NOT NULL
+	`
+
+	var processed bool
+	err := r.db.QueryRow(ctx, query, provider, eventID, string(payload)).Scan(&processed)
+	if err != nil {
+		return false, fmt.Errorf("failed to record webhook event: %w", err)
+	}
+
+	return processed, nil
+}
+
+// MarkWebhookEventProcessed flags a webhook event as fully applied
+func (r *PaymentRepository) MarkWebhookEventProcessed(ctx context.Context, provider, eventID string) error {
+	query := `
+		UPDATE payment_webhook_events
+		SET processed_at = CURRENT_TIMESTAMP
+		WHERE provider = $1 AND event_id = $2
+	`
+
+	_, err := r.db.Exec(ctx, query, provider, eventID)
+	if err != nil {
+		return fmt.Errorf("failed to mark webhook event processed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,250 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"cinema-booking-system/internal/config"
+	"cinema-booking-system/internal/dto"
+	"cinema-booking-system/internal/gateway"
+	"cinema-booking-system/internal/repository"
+	"cinema-booking-system/internal/service"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+const testWebhookSecret = "test-webhook-secret"
+
+// asyncPaymentFixture is a booking whose payment through the mock_async
+// gateway is awaiting the provider's webhook
+type asyncPaymentFixture struct {
+	*bookingFixture
+	db             *pgxpool.Pool
+	gw             *gateway.MockGateway
+	bookingService *service.BookingService
+	bookingID      int
+	eventPrefix    string
+}
+
+// newAsyncPaymentFixture books the fixture's seat for payment through an
+// asynchronous mock gateway
+func newAsyncPaymentFixture(t *testing.T) *asyncPaymentFixture {
+	t.Helper()
+	ctx := context.Background()
+
+	db := openTestDB(t)
+	f := &asyncPaymentFixture{
+		bookingFixture: newBookingFixture(t, db, 1),
+		db:             db,
+		gw:             gateway.NewMockGateway(gateway.MockAsyncProvider, testWebhookSecret, true),
+		eventPrefix:    fmt.Sprintf("evt_%d_", time.Now().UnixNano()),
+	}
+	t.Cleanup(func() {
+		_, err := db.Exec(ctx, `DELETE FROM payment_webhook_events WHERE event_id LIKE $1`, f.eventPrefix+"%")
+		if err != nil {
+			t.Errorf("failed to clean up webhook events: %v", err)
+		}
+	})
+
+	_, err := db.Exec(ctx, `UPDATE payment_methods SET provider = $1 WHERE id = $2`, gateway.MockAsyncProvider, f.paymentMethodID)
+	if err != nil {
+		t.Fatalf("failed to set payment method provider: %v", err)
+	}
+
+	cfg := &config.Config{
+		Booking: config.BookingConfig{HoldMinutes: 15, ProcessingGraceMinutes: 30},
+		Payment: config.PaymentConfig{GatewayTimeoutSeconds: 5, WebhookSecret: testWebhookSecret},
+	}
+	f.bookingService = service.NewBookingService(
+		repository.NewBookingRepository(db),
+		repository.NewCinemaRepository(db),
+		repository.NewShowtimeRepository(db),
+		repository.NewPaymentRepository(db),
+		gateway.NewRegistry(f.gw),
+		cfg,
+		zap.NewNop(),
+	)
+
+	booking, err := f.bookingService.CreateBooking(ctx, f.userIDs[0], &dto.BookingRequest{
+		ShowtimeID:    f.showtimeID,
+		SeatIDs:       []int{f.seatID},
+		PaymentMethod: f.paymentMethodID,
+	})
+	if err != nil {
+		t.Fatalf("CreateBooking() error = %v", err)
+	}
+	f.bookingID = booking.ID
+
+	return f
+}
+
+// pay starts a new asynchronous payment for the booking and returns the
+// provider reference of its charge
+func (f *asyncPaymentFixture) pay(t *testing.T) string {
+	t.Helper()
+	ctx := context.Background()
+
+	booking, err := f.bookingService.ProcessPayment(ctx, f.userIDs[0], &dto.PaymentRequest{
+		BookingID:     f.bookingID,
+		PaymentMethod: f.paymentMethodID,
+	})
+	if err != nil {
+		t.Fatalf("ProcessPayment() error = %v", err)
+	}
+	if booking.PaymentStatus != "processing" {
+		t.Fatalf("payment status after paying = %q, want processing", booking.PaymentStatus)
+	}
+
+	var reference string
+	err = f.db.QueryRow(ctx, `
+		SELECT provider_reference FROM payments
+		WHERE booking_id = $1 AND type = 'charge'
+		ORDER BY id DESC LIMIT 1
+	`, f.bookingID).Scan(&reference)
+	if err != nil {
+		t.Fatalf("failed to get charge reference: %v", err)
+	}
+	return reference
+}
+
+// deliver sends a webhook event for a charge, signed by the mock provider
+func (f *asyncPaymentFixture) deliver(eventID, reference string, status gateway.Status) error {
+	payload := []byte(fmt.Sprintf(`{"event_id":%q,"reference":%q,"status":%q}`,
+		f.eventPrefix+eventID, reference, status))
+	header := http.Header{}
+	header.Set(gateway.MockSignatureHeader, f.gw.SignPayload(payload))
+
+	return f.bookingService.HandlePaymentWebhook(context.Background(), gateway.MockAsyncProvider, payload, header)
+}
+
+// statuses returns the booking's payment status and the status of a charge
+func (f *asyncPaymentFixture) statuses(t *testing.T, reference string) (string, string) {
+	t.Helper()
+
+	var bookingStatus, chargeStatus string
+	err := f.db.QueryRow(context.Background(), `
+		SELECT b.payment_status, p.status
+		FROM bookings b
+		INNER JOIN payments p ON p.booking_id = b.id
+		WHERE b.id = $1 AND p.provider_reference = $2
+	`, f.bookingID, reference).Scan(&bookingStatus, &chargeStatus)
+	if err != nil {
+		t.Fatalf("failed to get payment statuses: %v", err)
+	}
+	return bookingStatus, chargeStatus
+}
+
+func TestPaymentWebhookRejectsBadSignature(t *testing.T) {
+	f := newAsyncPaymentFixture(t)
+	reference := f.pay(t)
+
+	payload := []byte(fmt.Sprintf(`{"event_id":%q,"reference":%q,"status":"succeeded"}`,
+		f.eventPrefix+"forged", reference))
+	forger := gateway.NewMockGateway(gateway.MockAsyncProvider, "guessed-secret", true)
+	header := http.Header{}
+	header.Set(gateway.MockSignatureHeader, forger.SignPayload(payload))
+
+	err := f.bookingService.HandlePaymentWebhook(context.Background(), gateway.MockAsyncProvider, payload, header)
+	if !errors.Is(err, service.ErrInvalidSignature) {
+		t.Fatalf("HandlePaymentWebhook() error = %v, want ErrInvalidSignature", err)
+	}
+
+	if bookingStatus, chargeStatus := f.statuses(t, reference); bookingStatus != "processing" || chargeStatus != "pending" {
+		t.Fatalf("after forged webhook booking payment is %q and charge %q, want processing and pending",
+			bookingStatus, chargeStatus)
+	}
+}
+
+func TestPaymentWebhookReplayIsIgnored(t *testing.T) {
+	f := newAsyncPaymentFixture(t)
+
+	declined := f.pay(t)
+	if err := f.deliver("declined", declined, gateway.StatusDeclined); err != nil {
+		t.Fatalf("HandlePaymentWebhook() error = %v", err)
+	}
+	if bookingStatus, _ := f.statuses(t, declined); bookingStatus != "failed" {
+		t.Fatalf("booking payment after decline = %q, want failed", bookingStatus)
+	}
+
+	// The customer pays again; replaying the old decline must not fail the
+	// new payment
+	retry := f.pay(t)
+	if err := f.deliver("declined", declined, gateway.StatusDeclined); err != nil {
+		t.Fatalf("replayed HandlePaymentWebhook() error = %v", err)
+	}
+	if bookingStatus, chargeStatus := f.statuses(t, retry); bookingStatus != "processing" || chargeStatus != "pending" {
+		t.Fatalf("after replay booking payment is %q and retry charge %q, want processing and pending",
+			bookingStatus, chargeStatus)
+	}
+
+	var events int
+	err := f.db.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM payment_webhook_events WHERE event_id = $1`, f.eventPrefix+"declined").Scan(&events)
+	if err != nil {
+		t.Fatalf("failed to count webhook events: %v", err)
+	}
+	if events != 1 {
+		t.Fatalf("replayed event is stored %d times, want 1", events)
+	}
+}
+
+func TestPaymentWebhookOutOfOrderFailureKeepsPayment(t *testing.T) {
+	f := newAsyncPaymentFixture(t)
+	reference := f.pay(t)
+
+	if err := f.deliver("succeeded", reference, gateway.StatusSucceeded); err != nil {
+		t.Fatalf("HandlePaymentWebhook() error = %v", err)
+	}
+	// A failure the provider sent earlier arrives late
+	if err := f.deliver("failed", reference, gateway.StatusFailed); err != nil {
+		t.Fatalf("late HandlePaymentWebhook() error = %v", err)
+	}
+
+	if bookingStatus, chargeStatus := f.statuses(t, reference); bookingStatus != "paid" || chargeStatus != "succeeded" {
+		t.Fatalf("after late failure booking payment is %q and charge %q, want paid and succeeded",
+			bookingStatus, chargeStatus)
+	}
+
+	var refunds int
+	err := f.db.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM payments WHERE booking_id = $1 AND type = 'refund'`, f.bookingID).Scan(&refunds)
+	if err != nil {
+		t.Fatalf("failed to count refunds: %v", err)
+	}
+	if refunds != 0 {
+		t.Fatalf("%d refunds issued, want none", refunds)
+	}
+}
+
+func TestPaymentWebhookLateFailureOfEarlierChargeKeepsNewerCharge(t *testing.T) {
+	f := newAsyncPaymentFixture(t)
+
+	first := f.pay(t)
+	if err := f.deliver("first_declined", first, gateway.StatusDeclined); err != nil {
+		t.Fatalf("HandlePaymentWebhook() error = %v", err)
+	}
+	retry := f.pay(t)
+
+	// A second failure event for the first charge arrives while the retry
+	// is in flight
+	if err := f.deliver("first_failed", first, gateway.StatusFailed); err != nil {
+		t.Fatalf("late HandlePaymentWebhook() error = %v", err)
+	}
+	if bookingStatus, chargeStatus := f.statuses(t, retry); bookingStatus != "processing" || chargeStatus != "pending" {
+		t.Fatalf("after late failure of the first charge booking payment is %q and retry charge %q, want processing and pending",
+			bookingStatus, chargeStatus)
+	}
+
+	if err := f.deliver("retry_succeeded", retry, gateway.StatusSucceeded); err != nil {
+		t.Fatalf("HandlePaymentWebhook() error = %v", err)
+	}
+	if bookingStatus, chargeStatus := f.statuses(t, retry); bookingStatus != "paid" || chargeStatus != "succeeded" {
+		t.Fatalf("after retry succeeded booking payment is %q and charge %q, want paid and succeeded",
+			bookingStatus, chargeStatus)
+	}
+}
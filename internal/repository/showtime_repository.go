@@ -0,0 +1,215 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"cinema-booking-system/internal/apperror"
+	"cinema-booking-system/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// showtimeDetailSelect is shared by every query that returns showtime details
+const showtimeDetailSelect = `
+	SELECT
+		st.id, st.movie_id, st.cinema_id, st.start_time, st.end_time,
+		st.created_at, st.updated_at,
+		m.title as movie_title,
+		m.rating as movie_rating,
+		m.duration_minutes,
+		c.name as cinema_name,
+		c.location as cinema_location
+	FROM showtimes st
+	INNER JOIN movies m ON st.movie_id = m.id
+	INNER JOIN cinemas c ON st.cinema_id = c.id
+`
+
+// ErrShowtimeOverlap is returned when a cinema already has a showtime in the
+// requested window
+var ErrShowtimeOverlap = apperror.Conflict("cinema already has a showtime in this time slot")
+
+// ShowtimeRepository handles showtime-related database operations
+type ShowtimeRepository struct {
+	db *pgxpool.Pool
+}
+
+// NewShowtimeRepository creates a new showtime repository
+func NewShowtimeRepository(db *pgxpool.Pool) *ShowtimeRepository {
+	return &ShowtimeRepository{db: db}
+}
+
+// Create inserts a new showtime into the database. It refuses with
+// ErrShowtimeOverlap when the cinema already has a showtime in that window.
+func (r *ShowtimeRepository) Create(ctx context.Context, showtime *models.Showtime) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// Lock the cinema so concurrent requests cannot both find the slot free
+	if err := lockCinema(ctx, tx, showtime.CinemaID); err != nil {
+		return err
+	}
+
+	var overlaps bool
+	err = tx.QueryRow(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM showtimes
+			WHERE cinema_id = $1 AND start_time < $3 AND end_time > $2
+		)
+	`, showtime.CinemaID, showtime.StartTime, showtime.EndTime).Scan(&overlaps)
+	if err != nil {
+		return fmt.Errorf("failed to check showtime overlap: %w", err)
+	}
+	if overlaps {
+		return ErrShowtimeOverlap
+	}
+
+	query := `
+		INSERT INTO showtimes (movie_id, cinema_id, start_time, end_time)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at, updated_at
+	`
+
+	err = tx.QueryRow(ctx, query,
+		showtime.MovieID,
+		showtime.CinemaID,
+		showtime.StartTime,
+		showtime.EndTime,
+	).Scan(&showtime.ID, &showtime.CreatedAt, &showtime.UpdatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create showtime: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit showtime: %w", err)
+	}
+
+	return nil
+}
+
+// GetByID retrieves a showtime with movie and cinema details by ID
+func (r *ShowtimeRepository) GetByID(ctx context.Context, id int) (*models.ShowtimeDetail, error) {
+	query := showtimeDetailSelect + `WHERE st.id = $1`
+
+	showtime, err := scanShowtimeDetail(r.db.QueryRow(ctx, query, id))
+	if err == pgx.ErrNoRows {
+		return nil, apperror.NotFound("showtime not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get showtime: %w", err)
+	}
+
+	return showtime, nil
+}
+
+// GetByCinema retrieves showtimes for a cinema on a date (YYYY-MM-DD),
+// or all upcoming showtimes when date is empty
+func (r *ShowtimeRepository) GetByCinema(ctx context.Context, cinemaID int, date string) ([]*models.ShowtimeDetail, error) {
+	query := showtimeDetailSelect + `
+		WHERE st.cinema_id = $1
+		  AND (
+			($2::timestamptz IS NULL AND st.start_time >= CURRENT_TIMESTAMP)
+			OR (st.start_time >= $2 AND st.start_time < $3)
+		  )
+		ORDER BY st.start_time
+	`
+
+	from, to, err := dayBounds(date)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.queryShowtimes(ctx, query, cinemaID, from, to)
+}
+
+// GetByMovie retrieves showtimes for a movie on a date (YYYY-MM-DD),
+// or all upcoming showtimes when date is empty
+func (r *ShowtimeRepository) GetByMovie(ctx context.Context, movieID int, date string) ([]*models.ShowtimeDetail, error) {
+	query := showtimeDetailSelect + `
+		WHERE st.movie_id = $1
+		  AND (
+			($2::timestamptz IS NULL AND st.start_time >= CURRENT_TIMESTAMP)
+			OR (st.start_time >= $2 AND st.start_time < $3)
+		  )
+		ORDER BY st.start_time, c.name
+	`
+
+	from, to, err := dayBounds(date)
+	if err != nil {
+		return nil, err
+	}
+
+	return r.queryShowtimes(ctx, query, movieID, from, to)
+}
+
+// dayBounds returns the start of a YYYY-MM-DD date and of the day after it in
+// the server's time zone, or nil bounds when date is empty. The day is
+// worked out here rather than with ::date in SQL, which would use the
+// database session's time zone.
+func dayBounds(date string) (*time.Time, *time.Time, error) {
+	if date == "" {
+		return nil, nil, nil
+	}
+
+	from, err := time.ParseInLocation("2006-01-02", date, time.Local)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid showtime date %q: %w", date, err)
+	}
+	to := from.AddDate(0, 0, 1)
+
+	return &from, &to, nil
+}
+
+// queryShowtimes runs a showtime detail query and collects the results
+func (r *ShowtimeRepository) queryShowtimes(ctx context.Context, query string, args ...interface{}) ([]*models.ShowtimeDetail, error) {
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get showtimes: %w", err)
+	}
+	defer rows.Close()
+
+	var showtimes []*models.ShowtimeDetail
+	for rows.Next() {
+		showtime, err := scanShowtimeDetail(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan showtime: %w", err)
+		}
+		showtimes = append(showtimes, showtime)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating showtimes: %w", err)
+	}
+
+	return showtimes, nil
+}
+
+// scanShowtimeDetail scans a single row produced by showtimeDetailSelect
+func scanShowtimeDetail(row pgx.Row) (*models.ShowtimeDetail, error) {
+	var showtime models.ShowtimeDetail
+	err := row.Scan(
+		&showtime.ID,
+		&showtime.MovieID,
+		&showtime.CinemaID,
+		&showtime.StartTime,
+		&showtime.EndTime,
+		&showtime.CreatedAt,
+		&showtime.UpdatedAt,
+		&showtime.MovieTitle,
+		&showtime.MovieRating,
+		&showtime.DurationMinutes,
+		&showtime.CinemaName,
+		&showtime.CinemaLocation,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &showtime, nil
+}
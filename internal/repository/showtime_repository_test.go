@@ -0,0 +1,143 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"cinema-booking-system/internal/apperror"
+	"cinema-booking-system/internal/config"
+	"cinema-booking-system/internal/dto"
+	"cinema-booking-system/internal/models"
+	"cinema-booking-system/internal/repository"
+	"cinema-booking-system/internal/service"
+
+	"go.uber.org/zap"
+)
+
+func TestShowtimeTimesOutsideUTC(t *testing.T) {
+	db := openTestDB(t)
+	useLocalZone(t)
+	f := newBookingFixture(t, db, 1)
+	ctx := context.Background()
+
+	showtimeRepo := repository.NewShowtimeRepository(db)
+	showtimeService := service.NewShowtimeService(
+		showtimeRepo,
+		repository.NewMovieRepository(db),
+		repository.NewCinemaRepository(db),
+		zap.NewNop(),
+	)
+
+	// 00:30 in the server's zone falls on the previous day in UTC and in
+	// the database session's zone
+	date := time.Now().In(testLocalZone).AddDate(0, 0, 3)
+	want := time.Date(date.Year(), date.Month(), date.Day(), 0, 30, 0, 0, testLocalZone)
+
+	created, err := showtimeService.CreateShowtime(ctx, &models.User{Role: models.RoleAdmin}, &dto.CreateShowtimeRequest{
+		MovieID:  f.movieID,
+		CinemaID: f.cinemaID,
+		Date:     want.Format("2006-01-02"),
+		Time:     "00:30",
+	})
+	if err != nil {
+		t.Fatalf("CreateShowtime() error = %v", err)
+	}
+	if !created.StartTime.Equal(want) {
+		t.Errorf("created StartTime = %v, want %v", created.StartTime, want)
+	}
+
+	got, err := showtimeRepo.GetByID(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if !got.StartTime.Equal(want) {
+		t.Errorf("stored StartTime = %v, want %v", got.StartTime, want)
+	}
+	if wantEnd := want.Add(2 * time.Hour); !got.EndTime.Equal(wantEnd) {
+		t.Errorf("stored EndTime = %v, want %v", got.EndTime, wantEnd)
+	}
+
+	onDate, err := showtimeRepo.GetByCinema(ctx, f.cinemaID, want.Format("2006-01-02"))
+	if err != nil {
+		t.Fatalf("GetByCinema() error = %v", err)
+	}
+	if len(onDate) != 1 || onDate[0].ID != created.ID {
+		t.Errorf("GetByCinema(%s) returned %d showtimes, want only showtime %d",
+			want.Format("2006-01-02"), len(onDate), created.ID)
+	}
+}
+
+func TestCreateBookingRejectsStartedShowtimeOutsideUTC(t *testing.T) {
+	db := openTestDB(t)
+	useLocalZone(t)
+	f := newBookingFixture(t, db, 1)
+	ctx := context.Background()
+
+	// Started an hour ago; misread as UTC wall clock it would seem hours away
+	start := time.Now().In(testLocalZone).Add(-time.Hour)
+	showtimeRepo := repository.NewShowtimeRepository(db)
+	started := &models.Showtime{
+		MovieID:   f.movieID,
+		CinemaID:  f.cinemaID,
+		StartTime: start,
+		EndTime:   start.Add(2 * time.Hour),
+	}
+	if err := showtimeRepo.Create(ctx, started); err != nil {
+		t.Fatalf("failed to create showtime: %v", err)
+	}
+
+	cfg := &config.Config{Booking: config.BookingConfig{HoldMinutes: 15, ProcessingGraceMinutes: 30}}
+	bookingService := service.NewBookingService(
+		repository.NewBookingRepository(db),
+		repository.NewCinemaRepository(db),
+		showtimeRepo,
+		repository.NewPaymentRepository(db),
+		nil,
+		cfg,
+		zap.NewNop(),
+	)
+
+	_, err := bookingService.CreateBooking(ctx, f.userIDs[0], &dto.BookingRequest{
+		ShowtimeID:    started.ID,
+		SeatIDs:       []int{f.seatID},
+		PaymentMethod: f.paymentMethodID,
+	})
+	if code := apperror.CodeOf(err); code != apperror.CodeConflict {
+		t.Fatalf("CreateBooking() error = %v (code %q), want code %q", err, code, apperror.CodeConflict)
+	}
+}
+
+func TestCreateShowtimeConcurrentOverlap(t *testing.T) {
+	db := openTestDB(t)
+	f := newBookingFixture(t, db, 0)
+	repo := repository.NewShowtimeRepository(db)
+
+	// Every request starts at a different minute, so all of them overlap
+	// but none is a duplicate
+	start := time.Now().Add(72 * time.Hour).Truncate(time.Minute)
+	errs := race(func(i int) error {
+		showtimeStart := start.Add(time.Duration(i) * time.Minute)
+		return repo.Create(context.Background(), &models.Showtime{
+			MovieID:   f.movieID,
+			CinemaID:  f.cinemaID,
+			StartTime: showtimeStart,
+			EndTime:   showtimeStart.Add(2 * time.Hour),
+		})
+	})
+
+	created := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			created++
+		case errors.Is(err, repository.ErrShowtimeOverlap):
+		default:
+			t.Fatalf("Create() #%d error = %v", i, err)
+		}
+	}
+	if created != 1 {
+		t.Fatalf("%d overlapping showtimes created, want exactly 1", created)
+	}
+}
@@ -2,15 +2,40 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"cinema-booking-system/internal/apperror"
 	"cinema-booking-system/internal/models"
 
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
+// userSelect is shared by every query that returns a user
+const userSelect = `
+	SELECT id, username, email, password_hash, COALESCE(full_name, ''), role,
+		   COALESCE(locale, ''), email_verified_at, totp_secret, totp_enabled_at, created_at, updated_at
+	FROM users
+`
+
+// tokenSelect is shared by every query that returns sessions
+const tokenSelect = `
+	SELECT id, user_id, token, COALESCE(user_agent, ''), COALESCE(ip_address, ''),
+		   expires_at, created_at
+	FROM tokens
+`
+
+// ErrRefreshTokenReused is returned when an already rotated refresh token is
+// presented again; the whole session has been revoked by then
+var ErrRefreshTokenReused = apperror.Unauthorized("refresh token reuse detected")
+
+// ErrEmailTaken is returned when an email change is confirmed for an address
+// that another account has claimed in the meantime
+var ErrEmailTaken = apperror.Conflict("email already exists")
+
 // UserRepository handles user-related database operations
 type UserRepository struct {
 	db *pgxpool.Pool
@@ -26,7 +51,7 @@ func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 	query := `
 		INSERT INTO users (username, email, password_hash, full_name)
 		VALUES ($1, $2, $3, $4)
-		RETURNING id, created_at, updated_at
+		RETURNING id, role, created_at, updated_at
 	`
 
 	err := r.db.QueryRow(ctx, query,
@@ -34,7 +59,7 @@ func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 		user.Email,
 		user.PasswordHash,
 		user.FullName,
-	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	).Scan(&user.ID, &user.Role, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
 		return fmt.Errorf("failed to create user: %w", err)
@@ -45,25 +70,39 @@ func (r *UserRepository) Create(ctx context.Context, user *models.User) error {
 
 // GetByUsername retrieves a user by username
 func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
-	query := `
-		SELECT id, username, email, password_hash, full_name, created_at, updated_at
-		FROM users
-		WHERE username = $1
-	`
+	return r.getUser(ctx, userSelect+`WHERE username = $1`, username)
+}
+
+// GetByEmail retrieves a user by email
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.getUser(ctx, userSelect+`WHERE email = $1`, email)
+}
 
+// GetByID retrieves a user by ID
+func (r *UserRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
+	return r.getUser(ctx, userSelect+`WHERE id = $1`, id)
+}
+
+// getUser runs a single-user query built on userSelect
+func (r *UserRepository) getUser(ctx context.Context, query string, args ...interface{}) (*models.User, error) {
 	var user models.User
-	err := r.db.QueryRow(ctx, query, username).Scan(
+	err := r.db.QueryRow(ctx, query, args...).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Email,
 		&user.PasswordHash,
 		&user.FullName,
+		&user.Role,
+		&user.Locale,
+		&user.EmailVerifiedAt,
+		&user.TOTPSecret,
+		&user.TOTPEnabledAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
 
 	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("user not found")
+		return nil, apperror.NotFound("user not found")
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user: %w", err)
@@ -72,75 +111,205 @@ func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*m
 	return &user, nil
 }
 
-// GetByEmail retrieves a user by email
-func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+// UpdateRole changes the role of a user. A user who stops being a cinema
+// manager loses their cinema assignments in the same transaction.
+func (r *UserRepository) UpdateRole(ctx context.Context, userID int, role string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	query := `
-		SELECT id, username, email, password_hash, full_name, created_at, updated_at
-		FROM users
-		WHERE email = $1
+		UPDATE users
+		SET role = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2
 	`
 
-	var user models.User
-	err := r.db.QueryRow(ctx, query, email).Scan(
-		&user.ID,
-		&user.Username,
-		&user.Email,
-		&user.PasswordHash,
-		&user.FullName,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	result, err := tx.Exec(ctx, query, role, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update user role: %w", err)
+	}
 
-	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("user not found")
+	if result.RowsAffected() == 0 {
+		return apperror.NotFound("user not found")
+	}
+
+	if role != models.RoleCinemaManager {
+		if _, err := tx.Exec(ctx, `DELETE FROM cinema_managers WHERE user_id = $1`, userID); err != nil {
+			return fmt.Errorf("failed to remove cinema assignments: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit role update: %w", err)
 	}
+
+	return nil
+}
+
+// UpdateFullName changes the display name of a user
+func (r *UserRepository) UpdateFullName(ctx context.Context, userID int, fullName string) error {
+	query := `
+		UPDATE users
+		SET full_name = NULLIF($1, ''), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2
+	`
+
+	result, err := r.db.Exec(ctx, query, fullName, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user: %w", err)
+		return fmt.Errorf("failed to update full name: %w", err)
 	}
 
-	return &user, nil
+	if result.RowsAffected() == 0 {
+		return apperror.NotFound("user not found")
+	}
+
+	return nil
 }
 
-// GetByID retrieves a user by ID
-func (r *UserRepository) GetByID(ctx context.Context, id int) (*models.User, error) {
+// UpdateLocale changes the preferred response language of a user. An empty
+// locale clears the preference.
+func (r *UserRepository) UpdateLocale(ctx context.Context, userID int, locale string) error {
 	query := `
-		SELECT id, username, email, password_hash, full_name, created_at, updated_at
-		FROM users
-		WHERE id = $1
+		UPDATE users
+		SET locale = NULLIF($1, ''), updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2
 	`
 
-	var user models.User
-	err := r.db.QueryRow(ctx, query, id).Scan(
-		&user.ID,
-		&user.Username,
-		&user.Email,
-		&user.PasswordHash,
-		&user.FullName,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	result, err := r.db.Exec(ctx, query, locale, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update locale: %w", err)
+	}
 
-	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("user not found")
+	if result.RowsAffected() == 0 {
+		return apperror.NotFound("user not found")
+	}
+
+	return nil
+}
+
+// ChangePassword sets a new password hash and deletes every other session of
+// the user, keeping the one whose access token hash is keepTokenHash. Unused
+// password reset links are discarded as well. It returns the number of
+// sessions revoked.
+func (r *UserRepository) ChangePassword(ctx context.Context, userID int, passwordHash, keepTokenHash string) (int64, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
 	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		UPDATE users
+		SET password_hash = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2
+	`, passwordHash, userID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user: %w", err)
+		return 0, fmt.Errorf("failed to update password: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return 0, apperror.NotFound("user not found")
 	}
 
-	return &user, nil
+	result, err = tx.Exec(ctx, `DELETE FROM tokens WHERE user_id = $1 AND token <> $2`, userID, keepTokenHash)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sessions: %w", err)
+	}
+	revoked := result.RowsAffected()
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM password_reset_tokens
+		WHERE user_id = $1 AND used_at IS NULL
+	`, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete reset tokens: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit password change: %w", err)
+	}
+
+	return revoked, nil
 }
 
-// CreateToken stores a new authentication token
+// Anonymise erases the personal data of a user while keeping the row, so
+// their bookings and payments are preserved. Unpaid reservations are
+// cancelled to release their seats, and every session, pending token and
+// cinema assignment of the user is removed.
+func (r *UserRepository) Anonymise(ctx context.Context, userID int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		UPDATE users
+		SET username = $2 || id,
+			email = $2 || id || '@deleted.invalid',
+			full_name = NULL,
+			password_hash = '',
+			role = 'customer',
+			email_verified_at = NULL,
+			totp_secret = NULL,
+			totp_enabled_at = NULL,
+			totp_last_step = NULL,
+			deleted_at = CURRENT_TIMESTAMP,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND deleted_at IS NULL
+	`, userID, models.DeletedUsernamePrefix)
+	if err != nil {
+		return fmt.Errorf("failed to anonymise user: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return apperror.NotFound("user not found")
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE bookings
+		SET booking_status = 'cancelled', payment_status = 'cancelled', updated_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1
+		  AND booking_status = 'reserved'
+		  AND payment_status IN ('pending', 'failed')
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to cancel reservations: %w", err)
+	}
+
+	for _, table := range []string{
+		"tokens",
+		"password_reset_tokens",
+		"email_verification_tokens",
+		"idempotency_keys",
+		"cinema_managers",
+		"recovery_codes",
+	} {
+		if _, err := tx.Exec(ctx, `DELETE FROM `+table+` WHERE user_id = $1`, userID); err != nil {
+			return fmt.Errorf("failed to delete %s: %w", table, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit account deletion: %w", err)
+	}
+
+	return nil
+}
+
+// CreateToken stores a new session
 func (r *UserRepository) CreateToken(ctx context.Context, token *models.Token) error {
 	query := `
-		INSERT INTO tokens (user_id, token, expires_at)
-		VALUES ($1, $2, $3)
+		INSERT INTO tokens (user_id, token, user_agent, ip_address, expires_at)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5)
 		RETURNING id, created_at
 	`
 
 	err := r.db.QueryRow(ctx, query,
 		token.UserID,
-		token.Token,
+		token.TokenHash,
+		token.UserAgent,
+		token.IPAddress,
 		token.ExpiresAt,
 	).Scan(&token.ID, &token.CreatedAt)
 
@@ -151,45 +320,92 @@ func (r *UserRepository) CreateToken(ctx context.Context, token *models.Token) e
 	return nil
 }
 
-// GetTokenByValue retrieves a token by its value
-func (r *UserRepository) GetTokenByValue(ctx context.Context, tokenValue string) (*models.Token, error) {
-	query := `
-		SELECT id, user_id, token, expires_at, created_at
-		FROM tokens
+// GetTokenByHash retrieves an unexpired session by the hash of its access token
+func (r *UserRepository) GetTokenByHash(ctx context.Context, tokenHash string) (*models.Token, error) {
+	query := tokenSelect + `
 		WHERE token = $1 AND expires_at > $2
 	`
 
-	var token models.Token
-	err := r.db.QueryRow(ctx, query, tokenValue, time.Now()).Scan(
-		&token.ID,
-		&token.UserID,
-		&token.Token,
-		&token.ExpiresAt,
-		&token.CreatedAt,
-	)
-
+	token, err := scanToken(r.db.QueryRow(ctx, query, tokenHash, time.Now()))
 	if err == pgx.ErrNoRows {
-		return nil, fmt.Errorf("token not found or expired")
+		return nil, apperror.NotFound("token not found or expired")
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get token: %w", err)
 	}
 
-	return &token, nil
+	return token, nil
+}
+
+// GetUserTokens retrieves the unexpired sessions of a user, newest first
+func (r *UserRepository) GetUserTokens(ctx context.Context, userID int) ([]*models.Token, error) {
+	query := tokenSelect + `
+		WHERE user_id = $1 AND expires_at > $2
+		ORDER BY created_at DESC
+	`
+
+	rows, err := r.db.Query(ctx, query, userID, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []*models.Token
+	for rows.Next() {
+		token, err := scanToken(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan token: %w", err)
+		}
+		tokens = append(tokens, token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating tokens: %w", err)
+	}
+
+	return tokens, nil
 }
 
-// DeleteToken removes a token from the database
-func (r *UserRepository) DeleteToken(ctx context.Context, tokenValue string) error {
+// DeleteToken removes a session by the hash of its access token
+func (r *UserRepository) DeleteToken(ctx context.Context, tokenHash string) error {
 	query := `DELETE FROM tokens WHERE token = $1`
 
-	_, err := r.db.Exec(ctx, query, tokenValue)
+	_, err := r.db.Exec(ctx, query, tokenHash)
+	if err != nil {
+		return fmt.Errorf("failed to delete token: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteUserToken removes one session of a user
+func (r *UserRepository) DeleteUserToken(ctx context.Context, userID, tokenID int) error {
+	query := `DELETE FROM tokens WHERE id = $1 AND user_id = $2`
+
+	result, err := r.db.Exec(ctx, query, tokenID, userID)
 	if err != nil {
 		return fmt.Errorf("failed to delete token: %w", err)
 	}
 
+	if result.RowsAffected() == 0 {
+		return apperror.NotFound("session not found")
+	}
+
 	return nil
 }
 
+// DeleteUserTokens removes every session of a user
+func (r *UserRepository) DeleteUserTokens(ctx context.Context, userID int) (int64, error) {
+	query := `DELETE FROM tokens WHERE user_id = $1`
+
+	result, err := r.db.Exec(ctx, query, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete tokens: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
 // DeleteExpiredTokens removes all expired tokens
 func (r *UserRepository) DeleteExpiredTokens(ctx context.Context) error {
 	query := `DELETE FROM tokens WHERE expires_at < $1`
@@ -201,3 +417,515 @@ func (r *UserRepository) DeleteExpiredTokens(ctx context.Context) error {
 
 	return nil
 }
+
+// CreateRefreshToken stores a new refresh token for a session
+func (r *UserRepository) CreateRefreshToken(ctx context.Context, refreshToken *models.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (token_id, user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`
+
+	err := r.db.QueryRow(ctx, query,
+		refreshToken.TokenID,
+		refreshToken.UserID,
+		refreshToken.TokenHash,
+		refreshToken.ExpiresAt,
+	).Scan(&refreshToken.ID, &refreshToken.CreatedAt)
+
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	return nil
+}
+
+// ConsumeRefreshToken marks a refresh token as used and returns it. If the
+// token had already been used, the session it belongs to is deleted, which
+// revokes its access token and every refresh token in the chain, and
+// ErrRefreshTokenReused is returned.
+func (r *UserRepository) ConsumeRefreshToken(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var refreshToken models.RefreshToken
+	err = tx.QueryRow(ctx, `
+		SELECT id, token_id, user_id, token_hash, expires_at, used_at, created_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+		FOR UPDATE
+	`, tokenHash).Scan(
+		&refreshToken.ID,
+		&refreshToken.TokenID,
+		&refreshToken.UserID,
+		&refreshToken.TokenHash,
+		&refreshToken.ExpiresAt,
+		&refreshToken.UsedAt,
+		&refreshToken.CreatedAt,
+	)
+	if err == pgx.ErrNoRows {
+		return nil, apperror.NotFound("refresh token not found")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	if refreshToken.UsedAt != nil {
+		if _, err := tx.Exec(ctx, `DELETE FROM tokens WHERE id = $1`, refreshToken.TokenID); err != nil {
+			return nil, fmt.Errorf("failed to revoke session: %w", err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return nil, fmt.Errorf("failed to commit session revocation: %w", err)
+		}
+		return &refreshToken, ErrRefreshTokenReused
+	}
+
+	if refreshToken.ExpiresAt.Before(time.Now()) {
+		return nil, fmt.Errorf("refresh token expired")
+	}
+
+	err = tx.QueryRow(ctx, `
+		UPDATE refresh_tokens
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING used_at
+	`, refreshToken.ID).Scan(&refreshToken.UsedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to mark refresh token used: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit refresh token: %w", err)
+	}
+
+	return &refreshToken, nil
+}
+
+// scanToken scans a single row produced by tokenSelect
+func scanToken(row pgx.Row) (*models.Token, error) {
+	var token models.Token
+	err := row.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.UserAgent,
+		&token.IPAddress,
+		&token.ExpiresAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &token, nil
+}
+
+// RotateSession replaces the access token hash of a session, extends it to
+// the new refresh token's expiry and stores that refresh token. The session
+// never outlives maxLifetime from its creation: the refresh token's expiry
+// is cut short to that, and a session past it is not rotated.
+func (r *UserRepository) RotateSession(ctx context.Context, tokenID int, accessTokenHash string, refreshToken *models.RefreshToken, maxLifetime time.Duration) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, `
+		UPDATE tokens
+		SET token = $1, expires_at = LEAST($2, created_at + make_interval(secs => $4))
+		WHERE id = $3 AND created_at + make_interval(secs => $4) > CURRENT_TIMESTAMP
+		RETURNING expires_at
+	`, accessTokenHash, refreshToken.ExpiresAt, tokenID, maxLifetime.Seconds()).Scan(&refreshToken.ExpiresAt)
+	if err == pgx.ErrNoRows {
+		return apperror.NotFound("session not found")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update session: %w", err)
+	}
+
+	err = tx.QueryRow(ctx, `
+		INSERT INTO refresh_tokens (token_id, user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, tokenID, refreshToken.UserID, refreshToken.TokenHash, refreshToken.ExpiresAt).Scan(&refreshToken.ID, &refreshToken.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+	refreshToken.TokenID = tokenID
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit session rotation: %w", err)
+	}
+
+	return nil
+}
+
+// CreatePasswordResetToken stores a new reset token for a user, discarding
+// any earlier unused ones so only the latest link works
+func (r *UserRepository) CreatePasswordResetToken(ctx context.Context, userID int, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM password_reset_tokens
+		WHERE user_id = $1 AND used_at IS NULL
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete old reset tokens: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO password_reset_tokens (user_id, token_hash, expires_at)
+		VALUES ($1, $2, $3)
+	`, userID, tokenHash, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create reset token: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit reset token: %w", err)
+	}
+
+	return nil
+}
+
+// GetByPasswordResetToken retrieves the user a valid, unused reset token belongs to
+func (r *UserRepository) GetByPasswordResetToken(ctx context.Context, tokenHash string) (*models.User, error) {
+	return r.getUser(ctx, userSelect+`
+		WHERE id = (
+			SELECT user_id FROM password_reset_tokens
+			WHERE token_hash = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		)
+	`, tokenHash)
+}
+
+// ResetPassword consumes a valid reset token, sets the new password hash and
+// deletes every session of the user. It returns the user's ID.
+func (r *UserRepository) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var userID int
+	err = tx.QueryRow(ctx, `
+		UPDATE password_reset_tokens
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		RETURNING user_id
+	`, tokenHash).Scan(&userID)
+	if err == pgx.ErrNoRows {
+		return 0, apperror.NotFound("reset token not found or expired")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to consume reset token: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE users
+		SET password_hash = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2
+	`, passwordHash, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to update password: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM tokens WHERE user_id = $1`, userID); err != nil {
+		return 0, fmt.Errorf("failed to delete sessions: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit password reset: %w", err)
+	}
+
+	return userID, nil
+}
+
+// CreateEmailVerificationToken stores a token that confirms ownership of an
+// email address for a user, discarding the user's earlier unused tokens
+func (r *UserRepository) CreateEmailVerificationToken(ctx context.Context, userID int, email, tokenHash string, expiresAt time.Time) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		DELETE FROM email_verification_tokens
+		WHERE user_id = $1 AND used_at IS NULL
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to delete old verification tokens: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		INSERT INTO email_verification_tokens (user_id, email, token_hash, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`, userID, email, tokenHash, expiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to create verification token: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit verification token: %w", err)
+	}
+
+	return nil
+}
+
+// GetLastVerificationSentAt returns when the latest verification token of a
+// user was created, or nil if none was ever sent
+func (r *UserRepository) GetLastVerificationSentAt(ctx context.Context, userID int) (*time.Time, error) {
+	query := `SELECT MAX(created_at) FROM email_verification_tokens WHERE user_id = $1`
+
+	var sentAt *time.Time
+	if err := r.db.QueryRow(ctx, query, userID).Scan(&sentAt); err != nil {
+		return nil, fmt.Errorf("failed to get last verification: %w", err)
+	}
+
+	return sentAt, nil
+}
+
+// GetPendingEmail returns the address of a user's outstanding email change,
+// or an empty string if there is none
+func (r *UserRepository) GetPendingEmail(ctx context.Context, userID int) (string, error) {
+	query := `
+		SELECT evt.email
+		FROM email_verification_tokens evt
+		INNER JOIN users u ON evt.user_id = u.id
+		WHERE evt.user_id = $1
+		  AND evt.email <> u.email
+		  AND evt.used_at IS NULL
+		  AND evt.expires_at > CURRENT_TIMESTAMP
+		ORDER BY evt.created_at DESC
+		LIMIT 1
+	`
+
+	var email string
+	err := r.db.QueryRow(ctx, query, userID).Scan(&email)
+	if err == pgx.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get pending email: %w", err)
+	}
+
+	return email, nil
+}
+
+// VerifyEmail consumes a valid verification token and marks the email it was
+// issued for as the user's verified address. It returns the user's ID.
+func (r *UserRepository) VerifyEmail(ctx context.Context, tokenHash string) (int, error) {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var userID int
+	var email string
+	err = tx.QueryRow(ctx, `
+		UPDATE email_verification_tokens
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+		RETURNING user_id, email
+	`, tokenHash).Scan(&userID, &email)
+	if err == pgx.ErrNoRows {
+		return 0, apperror.NotFound("verification token not found or expired")
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to consume verification token: %w", err)
+	}
+
+	_, err = tx.Exec(ctx, `
+		UPDATE users
+		SET email = $1, email_verified_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2
+	`, email, userID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+			return 0, ErrEmailTaken
+		}
+		return 0, fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return 0, fmt.Errorf("failed to commit email verification: %w", err)
+	}
+
+	return userID, nil
+}
+
+// SetPendingTOTPSecret stores a new TOTP secret for a user who has not
+// enabled two-factor authentication yet, replacing any earlier pending one
+func (r *UserRepository) SetPendingTOTPSecret(ctx context.Context, userID int, secret string) error {
+	query := `
+		UPDATE users
+		SET totp_secret = $1, totp_last_step = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND totp_enabled_at IS NULL
+	`
+
+	result, err := r.db.Exec(ctx, query, secret, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set totp secret: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("two-factor authentication already enabled")
+	}
+
+	return nil
+}
+
+// EnableTOTP turns on two-factor authentication for a user with a pending
+// secret, recording the step of the code that confirmed it, and replaces the
+// user's recovery codes
+func (r *UserRepository) EnableTOTP(ctx context.Context, userID int, step int64, recoveryCodeHashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, `
+		UPDATE users
+		SET totp_enabled_at = CURRENT_TIMESTAMP, totp_last_step = $1, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL
+	`, step, userID)
+	if err != nil {
+		return fmt.Errorf("failed to enable totp: %w", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("no pending two-factor setup")
+	}
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, recoveryCodeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit totp enrolment: %w", err)
+	}
+
+	return nil
+}
+
+// DisableTOTP turns off two-factor authentication for a user and deletes
+// their recovery codes
+func (r *UserRepository) DisableTOTP(ctx context.Context, userID int) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	_, err = tx.Exec(ctx, `
+		UPDATE users
+		SET totp_secret = NULL, totp_enabled_at = NULL, totp_last_step = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to disable totp: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit totp removal: %w", err)
+	}
+
+	return nil
+}
+
+// UseTOTPStep records that a code for the given time step was accepted and
+// reports false if a code for that step or a later one was already used
+func (r *UserRepository) UseTOTPStep(ctx context.Context, userID int, step int64) (bool, error) {
+	query := `
+		UPDATE users
+		SET totp_last_step = $1
+		WHERE id = $2 AND (totp_last_step IS NULL OR totp_last_step < $1)
+	`
+
+	result, err := r.db.Exec(ctx, query, step, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to record totp step: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// UseRecoveryCode marks an unused recovery code of a user as used and reports
+// whether it was valid
+func (r *UserRepository) UseRecoveryCode(ctx context.Context, userID int, codeHash string) (bool, error) {
+	query := `
+		UPDATE recovery_codes
+		SET used_at = CURRENT_TIMESTAMP
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	result, err := r.db.Exec(ctx, query, userID, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %w", err)
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+// ReplaceRecoveryCodes discards every recovery code of a user and stores new ones
+func (r *UserRepository) ReplaceRecoveryCodes(ctx context.Context, userID int, codeHashes []string) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit recovery codes: %w", err)
+	}
+
+	return nil
+}
+
+// CountRecoveryCodes returns how many unused recovery codes a user has left
+func (r *UserRepository) CountRecoveryCodes(ctx context.Context, userID int) (int, error) {
+	query := `SELECT COUNT(*) FROM recovery_codes WHERE user_id = $1 AND used_at IS NULL`
+
+	var count int
+	if err := r.db.QueryRow(ctx, query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+
+	return count, nil
+}
+
+// replaceRecoveryCodes swaps the recovery codes of a user within tx
+func replaceRecoveryCodes(ctx context.Context, tx pgx.Tx, userID int, codeHashes []string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	_, err := tx.Exec(ctx, `
+		INSERT INTO recovery_codes (user_id, code_hash)
+		SELECT $1, unnest($2::text[])
+	`, userID, codeHashes)
+	if err != nil {
+		return fmt.Errorf("failed to create recovery codes: %w", err)
+	}
+
+	return nil
+}
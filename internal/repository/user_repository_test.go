@@ -0,0 +1,266 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"cinema-booking-system/internal/apperror"
+	"cinema-booking-system/internal/models"
+	"cinema-booking-system/internal/repository"
+	"cinema-booking-system/internal/utils"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// newUser creates a user and removes it when the test ends
+func newUser(t *testing.T, db *pgxpool.Pool, repo *repository.UserRepository) *models.User {
+	t.Helper()
+	ctx := context.Background()
+	suffix := time.Now().UnixNano()
+
+	user := &models.User{
+		Username:     fmt.Sprintf("user_%d", suffix),
+		Email:        fmt.Sprintf("user_%d@example.com", suffix),
+		PasswordHash: "x",
+	}
+	if err := repo.Create(ctx, user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	t.Cleanup(func() {
+		if _, err := db.Exec(ctx, `DELETE FROM users WHERE id = $1`, user.ID); err != nil {
+			t.Errorf("failed to clean up user: %v", err)
+		}
+	})
+
+	return user
+}
+
+// newSession creates a user with a session logged into at loggedInAt
+func newSession(t *testing.T, db *pgxpool.Pool, repo *repository.UserRepository, loggedInAt time.Time) *models.Token {
+	t.Helper()
+	ctx := context.Background()
+	suffix := time.Now().UnixNano()
+	user := newUser(t, db, repo)
+
+	session := &models.Token{
+		UserID:    user.ID,
+		TokenHash: utils.HashToken(fmt.Sprintf("access_%d", suffix)),
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+	}
+	if err := repo.CreateToken(ctx, session); err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	if _, err := db.Exec(ctx, `UPDATE tokens SET created_at = $1 WHERE id = $2`, loggedInAt, session.ID); err != nil {
+		t.Fatalf("failed to backdate session: %v", err)
+	}
+
+	return session
+}
+
+func TestRotateSessionKeepsMaximumLifetime(t *testing.T) {
+	db := openTestDB(t)
+	useLocalZone(t)
+	repo := repository.NewUserRepository(db)
+	ctx := context.Background()
+
+	// PostgreSQL keeps microseconds
+	loggedInAt := time.Now().Add(-50 * time.Minute).Truncate(time.Microsecond)
+	session := newSession(t, db, repo, loggedInAt)
+
+	refreshToken := &models.RefreshToken{
+		UserID:    session.UserID,
+		TokenHash: utils.HashToken(fmt.Sprintf("refresh_%d", session.ID)),
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+	}
+	if err := repo.RotateSession(ctx, session.ID, utils.HashToken("rotated"), refreshToken, time.Hour); err != nil {
+		t.Fatalf("RotateSession() error = %v", err)
+	}
+
+	wantExpiry := loggedInAt.Add(time.Hour)
+	if !refreshToken.ExpiresAt.Equal(wantExpiry) {
+		t.Errorf("refresh token ExpiresAt = %v, want the session's maximum %v", refreshToken.ExpiresAt, wantExpiry)
+	}
+
+	var createdAt, expiresAt time.Time
+	err := db.QueryRow(ctx, `SELECT created_at, expires_at FROM tokens WHERE id = $1`, session.ID).Scan(&createdAt, &expiresAt)
+	if err != nil {
+		t.Fatalf("failed to get session: %v", err)
+	}
+	if !createdAt.Equal(loggedInAt) {
+		t.Errorf("session created_at = %v after rotation, want %v", createdAt, loggedInAt)
+	}
+	if !expiresAt.Equal(wantExpiry) {
+		t.Errorf("session expires_at = %v, want %v", expiresAt, wantExpiry)
+	}
+}
+
+func TestRotateSessionRefusesSessionPastMaximumLifetime(t *testing.T) {
+	db := openTestDB(t)
+	repo := repository.NewUserRepository(db)
+	ctx := context.Background()
+
+	session := newSession(t, db, repo, time.Now().Add(-2*time.Hour))
+
+	refreshToken := &models.RefreshToken{
+		UserID:    session.UserID,
+		TokenHash: utils.HashToken(fmt.Sprintf("refresh_%d", session.ID)),
+		ExpiresAt: time.Now().Add(24 * time.Hour),
+	}
+	err := repo.RotateSession(ctx, session.ID, utils.HashToken("rotated"), refreshToken, time.Hour)
+	if code := apperror.CodeOf(err); code != apperror.CodeNotFound {
+		t.Fatalf("RotateSession() error = %v (code %q), want code %q", err, code, apperror.CodeNotFound)
+	}
+}
+
+func TestUpdateRoleRemovesCinemaAssignments(t *testing.T) {
+	db := openTestDB(t)
+	f := newBookingFixture(t, db, 1)
+	userRepo := repository.NewUserRepository(db)
+	cinemaRepo := repository.NewCinemaRepository(db)
+	ctx := context.Background()
+	managerID := f.userIDs[0]
+
+	if err := userRepo.UpdateRole(ctx, managerID, models.RoleCinemaManager); err != nil {
+		t.Fatalf("UpdateRole() error = %v", err)
+	}
+	if err := cinemaRepo.AddManager(ctx, f.cinemaID, managerID); err != nil {
+		t.Fatalf("AddManager() error = %v", err)
+	}
+
+	if err := userRepo.UpdateRole(ctx, managerID, models.RoleStaff); err != nil {
+		t.Fatalf("UpdateRole() error = %v", err)
+	}
+	managed, err := cinemaRepo.IsManagedBy(ctx, f.cinemaID, managerID)
+	if err != nil {
+		t.Fatalf("IsManagedBy() error = %v", err)
+	}
+	if managed {
+		t.Fatal("demoted user still manages the cinema")
+	}
+
+	if err := cinemaRepo.AddManager(ctx, f.cinemaID, managerID); !errors.Is(err, repository.ErrNotCinemaManager) {
+		t.Fatalf("AddManager() for demoted user error = %v, want ErrNotCinemaManager", err)
+	}
+}
+
+func TestUpdateRoleConcurrentWithAddManager(t *testing.T) {
+	db := openTestDB(t)
+	f := newBookingFixture(t, db, concurrentBookers)
+	userRepo := repository.NewUserRepository(db)
+	cinemaRepo := repository.NewCinemaRepository(db)
+	ctx := context.Background()
+
+	for _, userID := range f.userIDs {
+		if err := userRepo.UpdateRole(ctx, userID, models.RoleCinemaManager); err != nil {
+			t.Fatalf("UpdateRole() error = %v", err)
+		}
+	}
+
+	// Every manager is assigned and demoted at the same time
+	errs := race(func(i int) error {
+		userID := f.userIDs[i/2*2]
+		if i%2 == 0 {
+			return userRepo.UpdateRole(ctx, userID, models.RoleCustomer)
+		}
+		if err := cinemaRepo.AddManager(ctx, f.cinemaID, userID); err != nil && !errors.Is(err, repository.ErrNotCinemaManager) {
+			return err
+		}
+		return nil
+	})
+	for i, err := range errs {
+		if err != nil {
+			t.Fatalf("#%d error = %v", i, err)
+		}
+	}
+
+	var stray int
+	err := db.QueryRow(ctx, `SELECT COUNT(*) FROM cinema_managers WHERE cinema_id = $1`, f.cinemaID).Scan(&stray)
+	if err != nil {
+		t.Fatalf("failed to count cinema managers: %v", err)
+	}
+	if stray != 0 {
+		t.Fatalf("%d demoted users still manage the cinema, want none", stray)
+	}
+}
+
+func TestPasswordResetTokenExpiryOutsideUTC(t *testing.T) {
+	db := openTestDB(t)
+	useLocalZone(t)
+	repo := repository.NewUserRepository(db)
+	ctx := context.Background()
+	user := newUser(t, db, repo)
+	live := utils.HashToken(fmt.Sprintf("reset_live_%d", user.ID))
+	lapsed := utils.HashToken(fmt.Sprintf("reset_lapsed_%d", user.ID))
+
+	// Misread as wall clock in another zone, a live token would seem
+	// expired or a lapsed one live for hours
+	if err := repo.CreatePasswordResetToken(ctx, user.ID, live, time.Now().Add(10*time.Minute)); err != nil {
+		t.Fatalf("CreatePasswordResetToken() error = %v", err)
+	}
+	got, err := repo.GetByPasswordResetToken(ctx, live)
+	if err != nil {
+		t.Fatalf("GetByPasswordResetToken() error = %v for a token expiring in 10 minutes", err)
+	}
+	if got.ID != user.ID {
+		t.Fatalf("GetByPasswordResetToken() user = %d, want %d", got.ID, user.ID)
+	}
+
+	if err := repo.CreatePasswordResetToken(ctx, user.ID, lapsed, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("CreatePasswordResetToken() error = %v", err)
+	}
+	if _, err := repo.GetByPasswordResetToken(ctx, lapsed); apperror.CodeOf(err) != apperror.CodeNotFound {
+		t.Fatalf("GetByPasswordResetToken() error = %v for a lapsed token, want not found", err)
+	}
+	if _, err := repo.ResetPassword(ctx, lapsed, "y"); apperror.CodeOf(err) != apperror.CodeNotFound {
+		t.Fatalf("ResetPassword() error = %v for a lapsed token, want not found", err)
+	}
+}
+
+func TestEmailVerificationOutsideUTC(t *testing.T) {
+	db := openTestDB(t)
+	useLocalZone(t)
+	repo := repository.NewUserRepository(db)
+	ctx := context.Background()
+	user := newUser(t, db, repo)
+	newEmail := fmt.Sprintf("changed_%d@example.com", user.ID)
+	live := utils.HashToken(fmt.Sprintf("verify_live_%d", user.ID))
+	lapsed := utils.HashToken(fmt.Sprintf("verify_lapsed_%d", user.ID))
+
+	if err := repo.CreateEmailVerificationToken(ctx, user.ID, newEmail, live, time.Now().Add(10*time.Minute)); err != nil {
+		t.Fatalf("CreateEmailVerificationToken() error = %v", err)
+	}
+
+	// The resend throttle waits from this time; read in the wrong zone it
+	// would be hours off
+	sentAt, err := repo.GetLastVerificationSentAt(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetLastVerificationSentAt() error = %v", err)
+	}
+	if sentAt == nil {
+		t.Fatal("GetLastVerificationSentAt() = nil after sending a token")
+	}
+	if since := time.Since(*sentAt); since < -time.Minute || since > time.Minute {
+		t.Fatalf("GetLastVerificationSentAt() = %v, %v from now, want about now", *sentAt, since)
+	}
+
+	pending, err := repo.GetPendingEmail(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("GetPendingEmail() error = %v", err)
+	}
+	if pending != newEmail {
+		t.Fatalf("GetPendingEmail() = %q for a token expiring in 10 minutes, want %q", pending, newEmail)
+	}
+
+	if err := repo.CreateEmailVerificationToken(ctx, user.ID, newEmail, lapsed, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("CreateEmailVerificationToken() error = %v", err)
+	}
+	if pending, err := repo.GetPendingEmail(ctx, user.ID); err != nil || pending != "" {
+		t.Fatalf("GetPendingEmail() = %q, %v for a lapsed token, want no pending email", pending, err)
+	}
+	if _, err := repo.VerifyEmail(ctx, lapsed); apperror.CodeOf(err) != apperror.CodeNotFound {
+		t.Fatalf("VerifyEmail() error = %v for a lapsed token, want not found", err)
+	}
+}
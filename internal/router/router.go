@@ -3,6 +3,7 @@ package router
 import (
 	"cinema-booking-system/internal/handler"
 	"cinema-booking-system/internal/middleware"
+	"cinema-booking-system/internal/models"
 
 	"github.com/go-chi/chi/v5"
 	chiMiddleware "github.com/go-chi/chi/v5/middleware"
@@ -12,28 +13,55 @@ import (
 func SetupRouter(
 	authHandler *handler.AuthHandler,
 	cinemaHandler *handler.CinemaHandler,
+	movieHandler *handler.MovieHandler,
+	showtimeHandler *handler.ShowtimeHandler,
 	bookingHandler *handler.BookingHandler,
 	paymentHandler *handler.PaymentHandler,
+	userHandler *handler.UserHandler,
+	docsHandler *handler.DocsHandler,
 	authMiddleware *middleware.AuthMiddleware,
+	realIPMiddleware *middleware.RealIPMiddleware,
 	loggingMiddleware *middleware.LoggingMiddleware,
+	localeMiddleware *middleware.LocaleMiddleware,
+	idempotencyMiddleware *middleware.IdempotencyMiddleware,
 ) *chi.Mux {
 	r := chi.NewRouter()
 
 	// Global middlewares
 	r.Use(chiMiddleware.RequestID)
-	r.Use(chiMiddleware.RealIP)
+	r.Use(realIPMiddleware.Resolve)
 	r.Use(chiMiddleware.Recoverer)
 	r.Use(loggingMiddleware.Log)
+	r.Use(localeMiddleware.Negotiate)
+
+	// Public signing keys for verifying access tokens
+	r.Get("/.well-known/jwks.json", authHandler.JWKS)
 
 	// API routes
 	r.Route("/api", func(r chi.Router) {
+		// API documentation
+		r.Get("/openapi.json", docsHandler.OpenAPI)
+		r.Get("/docs", docsHandler.SwaggerUI)
+		r.Get("/docs/{asset}", docsHandler.SwaggerAsset)
+
 		// Public routes (no authentication required)
 		r.Post("/register", authHandler.Register)
 		r.Post("/login", authHandler.Login)
+		r.Post("/login/2fa", authHandler.LoginTwoFactor)
+		r.Post("/token/refresh", authHandler.RefreshToken)
+		r.Post("/password/forgot", authHandler.ForgotPassword)
+		r.Post("/password/reset", authHandler.ResetPassword)
+		r.Get("/verify-email", authHandler.VerifyEmail)
 		r.Get("/cinemas", cinemaHandler.GetAllCinemas)
 		r.Get("/cinemas/{cinemaId}", cinemaHandler.GetCinemaByID)
-		r.Get("/cinemas/{cinemaId}/seats", cinemaHandler.GetSeatsAvailability)
+		r.Get("/cinemas/{cinemaId}/showtimes", showtimeHandler.GetCinemaShowtimes)
+		r.Get("/movies", movieHandler.GetAllMovies)
+		r.Get("/movies/{movieId}", movieHandler.GetMovieByID)
+		r.Get("/movies/{movieId}/showtimes", movieHandler.GetMovieShowtimes)
+		r.Get("/showtimes/{showtimeId}", showtimeHandler.GetShowtimeByID)
+		r.Get("/showtimes/{showtimeId}/seats", showtimeHandler.GetSeatsAvailability)
 		r.Get("/payment-methods", paymentHandler.GetAllPaymentMethods)
+		r.Post("/payments/webhook/{provider}", bookingHandler.PaymentWebhook)
 
 		// Protected routes (authentication required)
 		r.Group(func(r chi.Router) {
@@ -41,13 +69,59 @@ func SetupRouter(
 
 			// Authentication
 			r.Post("/logout", authHandler.Logout)
+			r.Get("/user/sessions", authHandler.GetSessions)
+			r.Delete("/user/sessions", authHandler.RevokeAllSessions)
+			r.Delete("/user/sessions/{sessionId}", authHandler.RevokeSession)
+			r.Post("/verify-email/resend", authHandler.ResendVerification)
+
+			// Profile
+			r.Get("/user/me", authHandler.GetProfile)
+			r.Patch("/user/me", authHandler.UpdateProfile)
+			r.Put("/user/password", authHandler.ChangePassword)
+			r.Get("/user/export", authHandler.ExportAccount)
+			r.Delete("/user", authHandler.DeleteAccount)
+
+			// Two-factor authentication
+			r.Post("/user/2fa/setup", authHandler.SetupTwoFactor)
+			r.Post("/user/2fa/confirm", authHandler.ConfirmTwoFactor)
+			r.Post("/user/2fa/disable", authHandler.DisableTwoFactor)
+			r.Post("/user/2fa/recovery-codes", authHandler.RegenerateRecoveryCodes)
 
 			// Booking
-			r.Post("/booking", bookingHandler.CreateBooking)
+			r.With(authMiddleware.RequireVerifiedEmail, idempotencyMiddleware.Handle).Post("/booking", bookingHandler.CreateBooking)
 			r.Get("/user/bookings", bookingHandler.GetUserBookings)
+			r.Post("/bookings/{bookingId}/cancel", bookingHandler.CancelBooking)
 
 			// Payment
-			r.Post("/pay", bookingHandler.ProcessPayment)
+			r.With(authMiddleware.RequireVerifiedEmail, idempotencyMiddleware.Handle).Post("/pay", bookingHandler.ProcessPayment)
+
+			// Back office
+			r.Route("/admin", func(r chi.Router) {
+				r.With(authMiddleware.RequireRole(models.RoleStaff, models.RoleCinemaManager, models.RoleAdmin)).
+					Get("/showtimes/{showtimeId}/bookings", bookingHandler.GetShowtimeBookings)
+
+				// Cinema managers are scoped to their cinemas by the services
+				r.Group(func(r chi.Router) {
+					r.Use(authMiddleware.RequireRole(models.RoleCinemaManager, models.RoleAdmin))
+
+					r.Post("/showtimes", showtimeHandler.CreateShowtime)
+					r.Put("/cinemas/{cinemaId}", cinemaHandler.UpdateCinema)
+					r.Put("/cinemas/{cinemaId}/seats", cinemaHandler.DefineSeatLayout)
+					r.Delete("/cinemas/{cinemaId}/seats/{seatId}", cinemaHandler.DeleteSeat)
+				})
+
+				r.Group(func(r chi.Router) {
+					r.Use(authMiddleware.RequireRole(models.RoleAdmin))
+
+					r.Post("/movies", movieHandler.CreateMovie)
+					r.Post("/cinemas", cinemaHandler.CreateCinema)
+					r.Delete("/cinemas/{cinemaId}", cinemaHandler.DeleteCinema)
+					r.Put("/users/{userId}/role", userHandler.UpdateUserRole)
+					r.Post("/users/{userId}/unlock", userHandler.UnlockUser)
+					r.Post("/cinemas/{cinemaId}/managers", userHandler.AssignCinemaManager)
+					r.Delete("/cinemas/{cinemaId}/managers/{userId}", userHandler.RemoveCinemaManager)
+				})
+			})
 		})
 	})
 
@@ -0,0 +1,18 @@
+package router
+
+import (
+	"testing"
+
+	"cinema-booking-system/internal/openapi"
+)
+
+// TestRoutesMatchOpenAPI fails when a route is added or changed without
+// updating internal/openapi/openapi.json, or the document lists an operation
+// the router does not serve
+func TestRoutesMatchOpenAPI(t *testing.T) {
+	r := SetupRouter(nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, nil)
+
+	if err := openapi.CheckRoutes(r); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,25 @@
+package service
+
+import (
+	"context"
+
+	"cinema-booking-system/internal/apperror"
+	"cinema-booking-system/internal/models"
+	"cinema-booking-system/internal/repository"
+)
+
+// ErrCinemaAccessDenied is returned when a user may not manage a cinema
+var ErrCinemaAccessDenied = apperror.Forbidden("you do not have access to this cinema")
+
+// canManageCinema reports whether a user may manage a cinema. Admins manage
+// every cinema; cinema managers only those assigned to them.
+func canManageCinema(ctx context.Context, cinemaRepo *repository.CinemaRepository, user *models.User, cinemaID int) (bool, error) {
+	switch user.Role {
+	case models.RoleAdmin:
+		return true, nil
+	case models.RoleCinemaManager:
+		return cinemaRepo.IsManagedBy(ctx, cinemaID, user.ID)
+	default:
+		return false, nil
+	}
+}
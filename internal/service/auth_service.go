@@ -2,54 +2,90 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
+	"cinema-booking-system/internal/apperror"
 	"cinema-booking-system/internal/config"
 	"cinema-booking-system/internal/dto"
+	"cinema-booking-system/internal/mailer"
 	"cinema-booking-system/internal/models"
 	"cinema-booking-system/internal/repository"
+	"cinema-booking-system/internal/signing"
+	"cinema-booking-system/internal/utils"
 
 	"github.com/golang-jwt/jwt/v5"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
 )
 
+// throttledError is returned when an action is attempted again too soon
+func throttledError(retryAfter time.Duration) error {
+	return apperror.RateLimited(retryAfter, "too many requests, please try again in %d seconds", int(retryAfter.Seconds()+0.5))
+}
+
+// ErrEmailAlreadyVerified is returned when verification is requested for a verified email
+var ErrEmailAlreadyVerified = apperror.Conflict("email is already verified")
+
 // AuthService handles authentication-related business logic
 type AuthService struct {
-	userRepo *repository.UserRepository
-	config   *config.Config
-	logger   *zap.Logger
+	userRepo     *repository.UserRepository
+	bookingRepo  *repository.BookingRepository
+	throttleRepo *repository.LoginThrottleRepository
+	keys         *signing.KeySet
+	mailer       mailer.Mailer
+	config       *config.Config
+	logger       *zap.Logger
 }
 
 // NewAuthService creates a new authentication service
-func NewAuthService(userRepo *repository.UserRepository, cfg *config.Config, logger *zap.Logger) *AuthService {
+func NewAuthService(
+	userRepo *repository.UserRepository,
+	bookingRepo *repository.BookingRepository,
+	throttleRepo *repository.LoginThrottleRepository,
+	keys *signing.KeySet,
+	mailer mailer.Mailer,
+	cfg *config.Config,
+	logger *zap.Logger,
+) *AuthService {
 	return &AuthService{
-		userRepo: userRepo,
-		config:   cfg,
-		logger:   logger,
+		userRepo:     userRepo,
+		bookingRepo:  bookingRepo,
+		throttleRepo: throttleRepo,
+		keys:         keys,
+		mailer:       mailer,
+		config:       cfg,
+		logger:       logger,
 	}
 }
 
+// loginThrottle is a counter that failed logins are tracked against
+type loginThrottle struct {
+	scope       string
+	subject     string
+	maxAttempts int
+}
+
 // Register creates a new user account
 func (s *AuthService) Register(ctx context.Context, req *dto.RegisterRequest) (*models.User, error) {
 	// Check if username already exists
 	existingUser, _ := s.userRepo.GetByUsername(ctx, req.Username)
 	if existingUser != nil {
-		return nil, fmt.Errorf("username already exists")
+		return nil, apperror.Conflict("username already exists")
 	}
 
 	// Check if email already exists
 	existingUser, _ = s.userRepo.GetByEmail(ctx, req.Email)
 	if existingUser != nil {
-		return nil, fmt.Errorf("email already exists")
+		return nil, apperror.Conflict("email already exists")
 	}
 
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
 		s.logger.Error("Failed to hash password", zap.Error(err))
-		return nil, fmt.Errorf("failed to hash password")
+		return nil, apperror.Internal("failed to hash password")
 	}
 
 	// Create user
@@ -62,74 +98,616 @@ func (s *AuthService) Register(ctx context.Context, req *dto.RegisterRequest) (*
 
 	if err := s.userRepo.Create(ctx, user); err != nil {
 		s.logger.Error("Failed to create user", zap.Error(err))
-		return nil, fmt.Errorf("failed to create user")
+		return nil, apperror.Internal("failed to create user")
+	}
+
+	// Registration succeeds even if the email cannot be sent; the user can resend it
+	if err := s.sendVerificationEmail(ctx, user, user.Email); err != nil {
+		s.logger.Error("Failed to send verification email", zap.Int("user_id", user.ID), zap.Error(err))
 	}
 
 	s.logger.Info("User registered successfully", zap.String("username", user.Username))
 	return user, nil
 }
 
-// Login authenticates a user and returns a token
-func (s *AuthService) Login(ctx context.Context, req *dto.LoginRequest) (*dto.LoginResponse, error) {
+// VerifyEmail confirms an email address using a verification token
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) error {
+	userID, err := s.userRepo.VerifyEmail(ctx, utils.HashToken(token))
+	if errors.Is(err, repository.ErrEmailTaken) {
+		return apperror.Conflict("email is already used by another account")
+	}
+	if err != nil {
+		s.logger.Warn("Email verification failed", zap.Error(err))
+		return apperror.BadRequest("invalid or expired verification token")
+	}
+
+	s.logger.Info("Email verified", zap.Int("user_id", userID))
+	return nil
+}
+
+// ResendVerification sends a new verification email, at most once per
+// configured interval. A pending email change takes precedence over the
+// current address.
+func (s *AuthService) ResendVerification(ctx context.Context, user *models.User) error {
+	email, err := s.userRepo.GetPendingEmail(ctx, user.ID)
+	if err != nil {
+		s.logger.Error("Failed to get pending email", zap.Int("user_id", user.ID), zap.Error(err))
+		return apperror.Internal("failed to send verification email")
+	}
+	if email == "" {
+		if user.EmailVerifiedAt != nil {
+			return ErrEmailAlreadyVerified
+		}
+		email = user.Email
+	}
+
+	lastSentAt, err := s.userRepo.GetLastVerificationSentAt(ctx, user.ID)
+	if err != nil {
+		s.logger.Error("Failed to get last verification", zap.Int("user_id", user.ID), zap.Error(err))
+		return apperror.Internal("failed to send verification email")
+	}
+	if lastSentAt != nil {
+		if wait := time.Until(lastSentAt.Add(s.config.GetVerificationResendInterval())); wait > 0 {
+			return throttledError(wait)
+		}
+	}
+
+	if err := s.sendVerificationEmail(ctx, user, email); err != nil {
+		s.logger.Error("Failed to send verification email", zap.Int("user_id", user.ID), zap.Error(err))
+		return apperror.Internal("failed to send verification email")
+	}
+
+	return nil
+}
+
+// GetProfile returns the account of the logged-in user
+func (s *AuthService) GetProfile(ctx context.Context, user *models.User) (*dto.ProfileResponse, error) {
+	pendingEmail, err := s.userRepo.GetPendingEmail(ctx, user.ID)
+	if err != nil {
+		s.logger.Error("Failed to get pending email", zap.Int("user_id", user.ID), zap.Error(err))
+		return nil, apperror.Internal("failed to get profile")
+	}
+
+	var recoveryCodesLeft *int
+	if user.TOTPEnabledAt != nil {
+		count, err := s.userRepo.CountRecoveryCodes(ctx, user.ID)
+		if err != nil {
+			s.logger.Error("Failed to count recovery codes", zap.Int("user_id", user.ID), zap.Error(err))
+			return nil, apperror.Internal("failed to get profile")
+		}
+		recoveryCodesLeft = &count
+	}
+
+	return &dto.ProfileResponse{
+		ID:                user.ID,
+		Username:          user.Username,
+		Email:             user.Email,
+		EmailVerifiedAt:   user.EmailVerifiedAt,
+		PendingEmail:      pendingEmail,
+		TwoFactorEnabled:  user.TOTPEnabledAt != nil,
+		RecoveryCodesLeft: recoveryCodesLeft,
+		FullName:          user.FullName,
+		Role:              user.Role,
+		Locale:            user.Locale,
+		CreatedAt:         user.CreatedAt,
+		UpdatedAt:         user.UpdatedAt,
+	}, nil
+}
+
+// UpdateProfile applies the fields present in req. A new email address is
+// not stored until it is verified through the link mailed to it.
+func (s *AuthService) UpdateProfile(ctx context.Context, user *models.User, req *dto.UpdateProfileRequest) (*dto.ProfileResponse, error) {
+	if req.FullName == nil && req.Email == nil && req.Locale == nil {
+		return nil, apperror.BadRequest("no profile changes provided")
+	}
+
+	if req.Email != nil && *req.Email != user.Email {
+		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+			s.logger.Warn("Email change with invalid password", zap.Int("user_id", user.ID))
+			return nil, apperror.BadRequest("current password is incorrect")
+		}
+
+		existingUser, _ := s.userRepo.GetByEmail(ctx, *req.Email)
+		if existingUser != nil {
+			return nil, apperror.Conflict("email already exists")
+		}
+	}
+
+	if req.FullName != nil {
+		if err := s.userRepo.UpdateFullName(ctx, user.ID, *req.FullName); err != nil {
+			s.logger.Error("Failed to update full name", zap.Int("user_id", user.ID), zap.Error(err))
+			return nil, apperror.Internal("failed to update profile")
+		}
+	}
+
+	if req.Locale != nil {
+		if err := s.userRepo.UpdateLocale(ctx, user.ID, *req.Locale); err != nil {
+			s.logger.Error("Failed to update locale", zap.Int("user_id", user.ID), zap.Error(err))
+			return nil, apperror.Internal("failed to update profile")
+		}
+	}
+
+	if req.Email != nil && *req.Email != user.Email {
+		if err := s.sendVerificationEmail(ctx, user, *req.Email); err != nil {
+			s.logger.Error("Failed to send verification email", zap.Int("user_id", user.ID), zap.Error(err))
+			return nil, apperror.Internal("failed to send verification email")
+		}
+		s.logger.Info("Email change requested", zap.Int("user_id", user.ID))
+	}
+
+	updated, err := s.userRepo.GetByID(ctx, user.ID)
+	if err != nil {
+		s.logger.Error("Failed to reload user", zap.Int("user_id", user.ID), zap.Error(err))
+		return nil, apperror.Internal("failed to update profile")
+	}
+
+	return s.GetProfile(ctx, updated)
+}
+
+// ExportAccount collects the personal data held about the logged-in user
+func (s *AuthService) ExportAccount(ctx context.Context, user *models.User, currentToken string) (*dto.AccountExportResponse, error) {
+	profile, err := s.GetProfile(ctx, user)
+	if err != nil {
+		return nil, apperror.Internal("failed to export account")
+	}
+
+	sessions, err := s.GetSessions(ctx, user.ID, currentToken)
+	if err != nil {
+		return nil, apperror.Internal("failed to export account")
+	}
+
+	bookings, err := s.bookingRepo.GetUserBookings(ctx, user.ID)
+	if err != nil {
+		s.logger.Error("Failed to get user bookings", zap.Int("user_id", user.ID), zap.Error(err))
+		return nil, apperror.Internal("failed to export account")
+	}
+	if bookings == nil {
+		bookings = []*models.BookingDetail{}
+	}
+
+	s.logger.Info("Account exported", zap.Int("user_id", user.ID))
+
+	return &dto.AccountExportResponse{
+		ExportedAt: time.Now(),
+		Profile:    profile,
+		Sessions:   sessions,
+		Bookings:   bookings,
+	}, nil
+}
+
+// DeleteAccount anonymises the logged-in user after checking their password.
+// Bookings and payments are kept for accounting but no longer identify them.
+func (s *AuthService) DeleteAccount(ctx context.Context, user *models.User, req *dto.DeleteAccountRequest) error {
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		s.logger.Warn("Account deletion with invalid password", zap.Int("user_id", user.ID))
+		return apperror.BadRequest("password is incorrect")
+	}
+
+	if err := s.userRepo.Anonymise(ctx, user.ID); err != nil {
+		s.logger.Error("Failed to delete account", zap.Int("user_id", user.ID), zap.Error(err))
+		return apperror.Internal("failed to delete account")
+	}
+
+	s.logger.Info("Account deleted", zap.Int("user_id", user.ID))
+	return nil
+}
+
+// ChangePassword replaces the password of the logged-in user after checking
+// the current one, and logs out every other session
+func (s *AuthService) ChangePassword(ctx context.Context, user *models.User, currentToken string, req *dto.ChangePasswordRequest) error {
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.CurrentPassword)); err != nil {
+		s.logger.Warn("Password change with invalid password", zap.Int("user_id", user.ID))
+		return apperror.BadRequest("current password is incorrect")
+	}
+
+	if err := s.checkPersonalInfo(user, req.NewPassword); err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		s.logger.Error("Failed to hash password", zap.Error(err))
+		return apperror.Internal("failed to hash password")
+	}
+
+	revoked, err := s.userRepo.ChangePassword(ctx, user.ID, string(hashedPassword), utils.HashToken(currentToken))
+	if err != nil {
+		s.logger.Error("Failed to change password", zap.Int("user_id", user.ID), zap.Error(err))
+		return apperror.Internal("failed to change password")
+	}
+
+	s.logger.Info("Password changed", zap.Int("user_id", user.ID), zap.Int64("sessions_revoked", revoked))
+	return nil
+}
+
+// sendVerificationEmail issues a verification token for email and mails the
+// confirmation link to that address
+func (s *AuthService) sendVerificationEmail(ctx context.Context, user *models.User, email string) error {
+	token, err := utils.GenerateRandomToken()
+	if err != nil {
+		return err
+	}
+
+	ttl := s.config.GetEmailVerificationTTL()
+	if err := s.userRepo.CreateEmailVerificationToken(ctx, user.ID, email, utils.HashToken(token), time.Now().Add(ttl)); err != nil {
+		return err
+	}
+
+	msg := mailer.Message{
+		To:      email,
+		Subject: "Verify your email address",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"Please confirm your email address by opening the link below within %d hours:\n\n"+
+			"%s/api/verify-email?token=%s",
+			user.Username, int(ttl.Hours()), s.config.App.BaseURL, token),
+	}
+
+	return s.mailer.Send(ctx, msg)
+}
+
+// Login authenticates a user and returns a token. The user agent and IP
+// address are recorded on the new session.
+//
+// When the user has two-factor authentication enabled, no session is created
+// yet: a short-lived challenge is returned instead, to be completed with
+// VerifyTwoFactorLogin.
+//
+// Attempts are counted per username and per IP address before the password
+// is checked, and uncounted again when it is right; once either reaches its
+// limit, logins for it are locked with exponential backoff and a
+// rate_limited error is returned until the lock ends.
+func (s *AuthService) Login(ctx context.Context, req *dto.LoginRequest, userAgent, ipAddress string) (*dto.LoginResponse, *dto.TwoFactorChallengeResponse, error) {
+	throttles := s.loginThrottles(req.Username, ipAddress)
+
+	// Count the attempt up front, refusing it while the username or IP
+	// address is locked out
+	attempts, err := s.beginLoginAttempt(ctx, throttles)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Get user by username
 	user, err := s.userRepo.GetByUsername(ctx, req.Username)
 	if err != nil {
 		s.logger.Warn("Login attempt with invalid username", zap.String("username", req.Username))
-		return nil, fmt.Errorf("invalid username or password")
+		s.logLoginLocks(throttles, attempts)
+		return nil, nil, apperror.Unauthorized("invalid username or password")
 	}
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
 		s.logger.Warn("Login attempt with invalid password", zap.String("username", req.Username))
-		return nil, fmt.Errorf("invalid username or password")
+		s.logLoginLocks(throttles, attempts)
+		return nil, nil, apperror.Unauthorized("invalid username or password")
 	}
+	s.releaseLoginAttempt(ctx, throttles, attempts)
 
-	// Generate JWT token
-	expiresAt := time.Now().Add(s.config.GetJWTExpiration())
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
-		"user_id":  user.ID,
-		"username": user.Username,
-		"exp":      expiresAt.Unix(),
-		"iat":      time.Now().Unix(),
-	})
+	if user.TOTPEnabledAt != nil {
+		challenge, err := s.generateChallengeToken(user)
+		if err != nil {
+			s.logger.Error("Failed to sign challenge token", zap.Error(err))
+			return nil, nil, apperror.Internal("failed to generate token")
+		}
+
+		s.logger.Info("Two-factor challenge issued", zap.String("username", user.Username))
+		return nil, challenge, nil
+	}
+
+	loginResponse, err := s.createSession(ctx, user, userAgent, ipAddress)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return loginResponse, nil, nil
+}
+
+// createSession completes a login: it clears the username's failed attempts
+// and issues a new session with its access and refresh tokens
+func (s *AuthService) createSession(ctx context.Context, user *models.User, userAgent, ipAddress string) (*dto.LoginResponse, error) {
+	// The IP counter is left alone so one valid account cannot mask guessing
+	// at others
+	if _, err := s.throttleRepo.Reset(ctx, repository.ThrottleScopeUsername, user.Username); err != nil {
+		s.logger.Error("Failed to reset login throttle", zap.Error(err))
+	}
 
-	tokenString, err := token.SignedString([]byte(s.config.JWT.Secret))
+	// Generate JWT token
+	tokenString, expiresAt, err := s.generateAccessToken(user)
 	if err != nil {
 		s.logger.Error("Failed to sign token", zap.Error(err))
-		return nil, fmt.Errorf("failed to generate token")
+		return nil, apperror.Internal("failed to generate token")
+	}
+
+	refreshString, refreshToken, err := s.newRefreshToken(user.ID)
+	if err != nil {
+		s.logger.Error("Failed to generate refresh token", zap.Error(err))
+		return nil, apperror.Internal("failed to generate token")
 	}
 
-	// Store token in database
+	// Store session in database; it lives as long as its refresh token chain
 	tokenModel := &models.Token{
 		UserID:    user.ID,
-		Token:     tokenString,
-		ExpiresAt: expiresAt,
+		TokenHash: utils.HashToken(tokenString),
+		UserAgent: userAgent,
+		IPAddress: ipAddress,
+		ExpiresAt: refreshToken.ExpiresAt,
 	}
 
 	if err := s.userRepo.CreateToken(ctx, tokenModel); err != nil {
 		s.logger.Error("Failed to store token", zap.Error(err))
-		return nil, fmt.Errorf("failed to store token")
+		return nil, apperror.Internal("failed to store token")
+	}
+
+	refreshToken.TokenID = tokenModel.ID
+	if err := s.userRepo.CreateRefreshToken(ctx, refreshToken); err != nil {
+		s.logger.Error("Failed to store refresh token", zap.Error(err))
+		return nil, apperror.Internal("failed to store token")
 	}
 
 	s.logger.Info("User logged in successfully", zap.String("username", user.Username))
 
 	return &dto.LoginResponse{
-		Token:     tokenString,
-		ExpiresAt: expiresAt.Format(time.RFC3339),
+		Token:            tokenString,
+		ExpiresAt:        expiresAt.Format(time.RFC3339),
+		RefreshToken:     refreshString,
+		RefreshExpiresAt: refreshToken.ExpiresAt.Format(time.RFC3339),
 		User: dto.UserResponse{
 			ID:       user.ID,
 			Username: user.Username,
 			Email:    user.Email,
 			FullName: user.FullName,
+			Role:     user.Role,
 		},
 	}, nil
 }
 
+// loginThrottles returns the counters a login attempt is tracked against
+func (s *AuthService) loginThrottles(username, ipAddress string) []loginThrottle {
+	throttles := []loginThrottle{
+		{scope: repository.ThrottleScopeUsername, subject: username, maxAttempts: s.config.Auth.LoginMaxAttempts},
+	}
+	if ipAddress != "" {
+		throttles = append(throttles, loginThrottle{
+			scope:       repository.ThrottleScopeIP,
+			subject:     ipAddress,
+			maxAttempts: s.config.Auth.LoginMaxAttemptsPerIP,
+		})
+	}
+
+	return throttles
+}
+
+// beginLoginAttempt counts an attempt against each throttle before the
+// credentials are checked. It returns a rate_limited error, uncounting the
+// attempt again, if any throttle is locked.
+func (s *AuthService) beginLoginAttempt(ctx context.Context, throttles []loginThrottle) ([]*repository.LoginAttempt, error) {
+	attempts := make([]*repository.LoginAttempt, 0, len(throttles))
+	for _, throttle := range throttles {
+		maxAttempts := throttle.maxAttempts
+		attempt, err := s.throttleRepo.BeginAttempt(ctx, throttle.scope, throttle.subject, s.config.GetLoginAttemptWindow(),
+			func(failures int) time.Duration {
+				return s.config.GetLoginLockout(failures, maxAttempts)
+			})
+		if err != nil {
+			s.logger.Error("Failed to count login attempt", zap.Error(err))
+			s.releaseLoginAttempt(ctx, throttles, attempts)
+			return nil, apperror.Internal("failed to login")
+		}
+
+		if !attempt.Allowed {
+			s.logger.Warn("Login attempt while locked",
+				zap.String("scope", throttle.scope),
+				zap.String("subject", throttle.subject))
+			s.releaseLoginAttempt(ctx, throttles, attempts)
+			return nil, throttledError(time.Until(*attempt.LockedUntil))
+		}
+
+		attempts = append(attempts, attempt)
+	}
+
+	return attempts, nil
+}
+
+// releaseLoginAttempt uncounts an attempt whose credentials were valid from
+// the throttles it was counted against
+func (s *AuthService) releaseLoginAttempt(ctx context.Context, throttles []loginThrottle, attempts []*repository.LoginAttempt) {
+	for i, attempt := range attempts {
+		if err := s.throttleRepo.ReleaseAttempt(ctx, throttles[i].scope, throttles[i].subject, attempt); err != nil {
+			s.logger.Error("Failed to release login attempt", zap.Error(err))
+		}
+	}
+}
+
+// logLoginLocks logs the locks a failed attempt placed by reaching a limit
+func (s *AuthService) logLoginLocks(throttles []loginThrottle, attempts []*repository.LoginAttempt) {
+	for i, attempt := range attempts {
+		if attempt.LockedUntil == nil {
+			continue
+		}
+
+		s.logger.Warn("Login locked after repeated failures",
+			zap.String("scope", throttles[i].scope),
+			zap.String("subject", throttles[i].subject),
+			zap.Int("attempts", attempt.Attempts),
+			zap.Duration("lockout", time.Until(*attempt.LockedUntil)))
+	}
+}
+
+// ForgotPassword emails a password reset link if the address belongs to an
+// account. It reports success either way so callers cannot probe which
+// emails are registered.
+func (s *AuthService) ForgotPassword(ctx context.Context, req *dto.ForgotPasswordRequest) error {
+	user, err := s.userRepo.GetByEmail(ctx, req.Email)
+	if err != nil {
+		s.logger.Info("Password reset requested for unknown email")
+		return nil
+	}
+
+	token, err := utils.GenerateRandomToken()
+	if err != nil {
+		s.logger.Error("Failed to generate reset token", zap.Error(err))
+		return nil
+	}
+
+	ttl := s.config.GetPasswordResetTTL()
+	if err := s.userRepo.CreatePasswordResetToken(ctx, user.ID, utils.HashToken(token), time.Now().Add(ttl)); err != nil {
+		s.logger.Error("Failed to store reset token", zap.Int("user_id", user.ID), zap.Error(err))
+		return nil
+	}
+
+	msg := mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\n"+
+			"We received a request to reset your password. Use the link below within %d minutes:\n\n"+
+			"%s/reset-password?token=%s\n\n"+
+			"If you did not request this, you can ignore this email.",
+			user.Username, int(ttl.Minutes()), s.config.App.BaseURL, token),
+	}
+	if err := s.mailer.Send(ctx, msg); err != nil {
+		s.logger.Error("Failed to send reset email", zap.Int("user_id", user.ID), zap.Error(err))
+		return nil
+	}
+
+	s.logger.Info("Password reset email sent", zap.Int("user_id", user.ID))
+	return nil
+}
+
+// ResetPassword sets a new password using a reset token and logs the user
+// out of every session
+func (s *AuthService) ResetPassword(ctx context.Context, req *dto.ResetPasswordRequest) error {
+	user, err := s.userRepo.GetByPasswordResetToken(ctx, utils.HashToken(req.Token))
+	if err != nil {
+		s.logger.Warn("Password reset failed", zap.Error(err))
+		return apperror.BadRequest("invalid or expired reset token")
+	}
+
+	if err := s.checkPersonalInfo(user, req.NewPassword); err != nil {
+		return err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		s.logger.Error("Failed to hash password", zap.Error(err))
+		return apperror.Internal("failed to hash password")
+	}
+
+	userID, err := s.userRepo.ResetPassword(ctx, utils.HashToken(req.Token), string(hashedPassword))
+	if err != nil {
+		s.logger.Warn("Password reset failed", zap.Error(err))
+		return apperror.BadRequest("invalid or expired reset token")
+	}
+
+	s.logger.Info("Password reset successfully", zap.Int("user_id", userID))
+	return nil
+}
+
+// checkPersonalInfo applies the password policy's personal information rule to
+// requests that do not carry the username and email themselves
+func (s *AuthService) checkPersonalInfo(user *models.User, password string) error {
+	if s.config.Password.DisallowPersonalInfo && utils.PasswordContainsPersonalInfo(password, user.Username, user.Email) {
+		return apperror.Validation("new_password must not contain your username or email")
+	}
+	return nil
+}
+
+// RefreshToken exchanges a refresh token for a new access and refresh token.
+// Each refresh token can be used once; presenting a used one revokes the
+// whole session, since it means the token chain has leaked. Refreshing never
+// keeps a session alive past its maximum lifetime from login.
+func (s *AuthService) RefreshToken(ctx context.Context, req *dto.RefreshTokenRequest) (*dto.TokenResponse, error) {
+	used, err := s.userRepo.ConsumeRefreshToken(ctx, utils.HashToken(req.RefreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenReused) {
+			s.logger.Warn("Refresh token reuse detected, session revoked",
+				zap.Int("user_id", used.UserID),
+				zap.Int("token_id", used.TokenID))
+		} else {
+			s.logger.Warn("Invalid refresh token", zap.Error(err))
+		}
+		return nil, apperror.Unauthorized("invalid or expired refresh token")
+	}
+
+	user, err := s.userRepo.GetByID(ctx, used.UserID)
+	if err != nil {
+		s.logger.Error("User not found", zap.Int("user_id", used.UserID))
+		return nil, apperror.Unauthorized("invalid or expired refresh token")
+	}
+
+	tokenString, expiresAt, err := s.generateAccessToken(user)
+	if err != nil {
+		s.logger.Error("Failed to sign token", zap.Error(err))
+		return nil, apperror.Internal("failed to generate token")
+	}
+
+	refreshString, refreshToken, err := s.newRefreshToken(user.ID)
+	if err != nil {
+		s.logger.Error("Failed to generate refresh token", zap.Error(err))
+		return nil, apperror.Internal("failed to generate token")
+	}
+
+	if err := s.userRepo.RotateSession(ctx, used.TokenID, utils.HashToken(tokenString), refreshToken, s.config.GetSessionMaxLifetime()); err != nil {
+		s.logger.Warn("Failed to rotate session", zap.Int("token_id", used.TokenID), zap.Error(err))
+		return nil, apperror.Unauthorized("invalid or expired refresh token")
+	}
+
+	s.logger.Info("Token refreshed", zap.Int("user_id", user.ID), zap.Int("token_id", used.TokenID))
+
+	return &dto.TokenResponse{
+		Token:            tokenString,
+		ExpiresAt:        expiresAt.Format(time.RFC3339),
+		RefreshToken:     refreshString,
+		RefreshExpiresAt: refreshToken.ExpiresAt.Format(time.RFC3339),
+	}, nil
+}
+
+// generateAccessToken signs a short-lived JWT for a user
+func (s *AuthService) generateAccessToken(user *models.User) (string, time.Time, error) {
+	// A random ID keeps tokens issued to the same user within a second distinct
+	jti, err := utils.GenerateRandomToken()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(s.config.GetAccessTokenTTL())
+	tokenString, err := s.keys.Sign(jwt.MapClaims{
+		"jti":      jti,
+		"user_id":  user.ID,
+		"username": user.Username,
+		"role":     user.Role,
+		"exp":      expiresAt.Unix(),
+		"iat":      now.Unix(),
+	})
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	return tokenString, expiresAt, nil
+}
+
+// newRefreshToken generates a refresh token and its storable model; the
+// caller sets TokenID once the session is known
+func (s *AuthService) newRefreshToken(userID int) (string, *models.RefreshToken, error) {
+	token, err := utils.GenerateRandomToken()
+	if err != nil {
+		return "", nil, err
+	}
+
+	// A new session cannot be kept for longer than its maximum lifetime
+	ttl := min(s.config.GetRefreshTokenTTL(), s.config.GetSessionMaxLifetime())
+
+	return token, &models.RefreshToken{
+		UserID:    userID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}, nil
+}
+
 // Logout invalidates a user's token
 func (s *AuthService) Logout(ctx context.Context, tokenString string) error {
-	if err := s.userRepo.DeleteToken(ctx, tokenString); err != nil {
+	if err := s.userRepo.DeleteToken(ctx, utils.HashToken(tokenString)); err != nil {
 		s.logger.Error("Failed to delete token", zap.Error(err))
-		return fmt.Errorf("failed to logout")
+		return apperror.Internal("failed to logout")
 	}
 
 	s.logger.Info("User logged out successfully")
@@ -138,29 +716,80 @@ func (s *AuthService) Logout(ctx context.Context, tokenString string) error {
 
 // ValidateToken verifies and validates a JWT token
 func (s *AuthService) ValidateToken(ctx context.Context, tokenString string) (*models.User, error) {
-	// Parse and validate JWT
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method")
-		}
-		return []byte(s.config.JWT.Secret), nil
-	})
-
+	// Parse and validate JWT with the key named by its kid
+	token, err := s.keys.Parse(tokenString, jwt.MapClaims{})
 	if err != nil || !token.Valid {
-		return nil, fmt.Errorf("invalid token")
+		return nil, apperror.Unauthorized("invalid token")
 	}
 
 	// Check if token exists in database
-	tokenModel, err := s.userRepo.GetTokenByValue(ctx, tokenString)
+	tokenModel, err := s.userRepo.GetTokenByHash(ctx, utils.HashToken(tokenString))
 	if err != nil {
-		return nil, fmt.Errorf("token not found or expired")
+		return nil, apperror.Unauthorized("token not found or expired")
 	}
 
-	// Get user
+	// Get user; the stored role is authoritative over the one in the claims,
+	// so role changes take effect without waiting for tokens to expire
 	user, err := s.userRepo.GetByID(ctx, tokenModel.UserID)
 	if err != nil {
-		return nil, fmt.Errorf("user not found")
+		return nil, apperror.NotFound("user not found")
 	}
 
 	return user, nil
 }
+
+// JWKS returns the public keys access tokens can be verified with
+func (s *AuthService) JWKS() *signing.JWKS {
+	return s.keys.JWKS()
+}
+
+// GetSessions lists the active sessions of a user, flagging the one that
+// made the request
+func (s *AuthService) GetSessions(ctx context.Context, userID int, currentToken string) ([]*dto.SessionResponse, error) {
+	tokens, err := s.userRepo.GetUserTokens(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to get sessions", zap.Int("user_id", userID), zap.Error(err))
+		return nil, apperror.Internal("failed to get sessions")
+	}
+
+	currentHash := utils.HashToken(currentToken)
+	sessions := make([]*dto.SessionResponse, 0, len(tokens))
+	for _, token := range tokens {
+		sessions = append(sessions, &dto.SessionResponse{
+			ID:        token.ID,
+			UserAgent: token.UserAgent,
+			IPAddress: token.IPAddress,
+			CreatedAt: token.CreatedAt,
+			ExpiresAt: token.ExpiresAt,
+			Current:   token.TokenHash == currentHash,
+		})
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession logs out one session of a user
+func (s *AuthService) RevokeSession(ctx context.Context, userID, sessionID int) error {
+	if err := s.userRepo.DeleteUserToken(ctx, userID, sessionID); err != nil {
+		s.logger.Warn("Failed to revoke session",
+			zap.Int("user_id", userID),
+			zap.Int("session_id", sessionID),
+			zap.Error(err))
+		return err
+	}
+
+	s.logger.Info("Session revoked", zap.Int("user_id", userID), zap.Int("session_id", sessionID))
+	return nil
+}
+
+// RevokeAllSessions logs a user out everywhere, including the current session
+func (s *AuthService) RevokeAllSessions(ctx context.Context, userID int) error {
+	count, err := s.userRepo.DeleteUserTokens(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to revoke sessions", zap.Int("user_id", userID), zap.Error(err))
+		return apperror.Internal("failed to revoke sessions")
+	}
+
+	s.logger.Info("All sessions revoked", zap.Int("user_id", userID), zap.Int64("count", count))
+	return nil
+}
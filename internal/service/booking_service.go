@@ -2,107 +2,156 @@ package service
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
 
+	"cinema-booking-system/internal/apperror"
+	"cinema-booking-system/internal/config"
 	"cinema-booking-system/internal/dto"
+	"cinema-booking-system/internal/gateway"
 	"cinema-booking-system/internal/models"
 	"cinema-booking-system/internal/repository"
 
 	"go.uber.org/zap"
 )
 
+var (
+	// ErrSeatTaken is returned when a requested seat is already held by another booking
+	ErrSeatTaken = apperror.Conflict("seat already taken")
+	// ErrPaymentDeclined is returned when the payment gateway refuses a charge
+	ErrPaymentDeclined = apperror.PaymentDeclined("payment declined")
+	// ErrPaymentTimeout is returned when the payment gateway does not answer in time
+	ErrPaymentTimeout = apperror.Timeout("payment gateway timed out, please try again")
+	// ErrUnknownProvider is returned when a webhook names an unsupported provider
+	ErrUnknownProvider = apperror.NotFound("unknown payment provider")
+	// ErrInvalidSignature is returned when a webhook fails signature verification
+	ErrInvalidSignature = apperror.Unauthorized("invalid webhook signature")
+	// ErrInvalidWebhookPayload is returned when a signed webhook cannot be decoded
+	ErrInvalidWebhookPayload = apperror.BadRequest("invalid webhook payload")
+	// ErrPaymentNotFound is returned when a webhook references an unknown payment
+	ErrPaymentNotFound = apperror.NotFound("payment not found")
+)
+
 // BookingService handles booking-related business logic
 type BookingService struct {
-	bookingRepo *repository.BookingRepository
-	cinemaRepo  *repository.CinemaRepository
-	paymentRepo *repository.PaymentRepository
-	logger      *zap.Logger
+	bookingRepo  *repository.BookingRepository
+	cinemaRepo   *repository.CinemaRepository
+	showtimeRepo *repository.ShowtimeRepository
+	paymentRepo  *repository.PaymentRepository
+	gateways     *gateway.Registry
+	config       *config.Config
+	logger       *zap.Logger
 }
 
 // NewBookingService creates a new booking service
 func NewBookingService(
 	bookingRepo *repository.BookingRepository,
 	cinemaRepo *repository.CinemaRepository,
+	showtimeRepo *repository.ShowtimeRepository,
 	paymentRepo *repository.PaymentRepository,
+	gateways *gateway.Registry,
+	cfg *config.Config,
 	logger *zap.Logger,
 ) *BookingService {
 	return &BookingService{
-		bookingRepo: bookingRepo,
-		cinemaRepo:  cinemaRepo,
-		paymentRepo: paymentRepo,
-		logger:      logger,
+		bookingRepo:  bookingRepo,
+		cinemaRepo:   cinemaRepo,
+		showtimeRepo: showtimeRepo,
+		paymentRepo:  paymentRepo,
+		gateways:     gateways,
+		config:       cfg,
+		logger:       logger,
 	}
 }
 
-// CreateBooking creates a new seat reservation
+// CreateBooking reserves one or more seats for a showtime in a single booking
 func (s *BookingService) CreateBooking(ctx context.Context, userID int, req *dto.BookingRequest) (*models.Booking, error) {
-	// Validate cinema exists
-	cinema, err := s.cinemaRepo.GetByID(ctx, req.CinemaID)
+	// Validate showtime exists and has not started yet
+	showtime, err := s.showtimeRepo.GetByID(ctx, req.ShowtimeID)
 	if err != nil {
-		s.logger.Error("Cinema not found", zap.Int("cinema_id", req.CinemaID))
-		return nil, fmt.Errorf("cinema not found")
+		s.logger.Error("Failed to get showtime", zap.Int("showtime_id", req.ShowtimeID), zap.Error(err))
+		return nil, err
 	}
 
-	// Validate seat exists and belongs to cinema
-	seat, err := s.cinemaRepo.GetSeatByID(ctx, req.SeatID)
+	if !showtime.StartTime.After(time.Now()) {
+		s.logger.Warn("Showtime already started", zap.Int("showtime_id", req.ShowtimeID))
+		return nil, apperror.Conflict("showtime has already started")
+	}
+
+	// Validate seats exist and belong to the showtime's cinema
+	seats, err := s.cinemaRepo.GetSeatsByIDs(ctx, req.SeatIDs)
 	if err != nil {
-		s.logger.Error("Seat not found", zap.Int("seat_id", req.SeatID))
-		return nil, fmt.Errorf("seat not found")
+		s.logger.Error("Failed to get seats", zap.Ints("seat_ids", req.SeatIDs), zap.Error(err))
+		return nil, apperror.Internal("failed to get seats")
 	}
 
-	if seat.CinemaID != cinema.ID {
-		s.logger.Error("Seat does not belong to cinema",
-			zap.Int("seat_id", req.SeatID),
-			zap.Int("cinema_id", req.CinemaID))
-		return nil, fmt.Errorf("seat does not belong to the specified cinema")
+	if len(seats) != len(req.SeatIDs) {
+		s.logger.Error("Seat not found", zap.Ints("seat_ids", req.SeatIDs))
+		return nil, apperror.NotFound("seat not found")
+	}
+
+	bookingSeats := make([]*models.BookingSeat, 0, len(seats))
+	var totalAmount float64
+	for _, seat := range seats {
+		if seat.CinemaID != showtime.CinemaID {
+			s.logger.Error("Seat does not belong to showtime cinema",
+				zap.Int("seat_id", seat.ID),
+				zap.Int("showtime_id", req.ShowtimeID),
+				zap.Int("cinema_id", showtime.CinemaID))
+			return nil, apperror.Newf(apperror.CodeValidation, "seat %s does not belong to the showtime's cinema", seat.SeatNumber)
+		}
+
+		bookingSeats = append(bookingSeats, &models.BookingSeat{
+			SeatID:     seat.ID,
+			SeatNumber: seat.SeatNumber,
+			SeatType:   seat.SeatType,
+			Price:      seat.Price,
+		})
+		totalAmount += seat.Price
 	}
 
 	// Validate payment method
 	isValid, err := s.paymentRepo.ValidatePaymentMethod(ctx, req.PaymentMethod)
 	if err != nil || !isValid {
 		s.logger.Error("Invalid payment method", zap.Int("payment_method_id", req.PaymentMethod))
-		return nil, fmt.Errorf("invalid payment method")
-	}
-
-	// Check seat availability
-	isAvailable, err := s.bookingRepo.CheckSeatAvailability(ctx, req.CinemaID, req.SeatID, req.Date, req.Time+":00")
-	if err != nil {
-		s.logger.Error("Failed to check seat availability", zap.Error(err))
-		return nil, fmt.Errorf("failed to check seat availability")
-	}
-
-	if !isAvailable {
-		s.logger.Warn("Seat already booked",
-			zap.Int("cinema_id", req.CinemaID),
-			zap.Int("seat_id", req.SeatID),
-			zap.String("date", req.Date),
-			zap.String("time", req.Time))
-		return nil, fmt.Errorf("seat is already booked for the specified time")
+		return nil, apperror.Validation("invalid payment method")
 	}
 
-	// Create booking
+	// Create booking and all seats atomically; seats are held until paid or the hold lapses
+	holdExpiresAt := time.Now().Add(s.config.GetBookingHoldTTL())
 	booking := &models.Booking{
 		UserID:          userID,
-		CinemaID:        req.CinemaID,
-		SeatID:          req.SeatID,
-		BookingDate:     req.Date,
-		BookingTime:     req.Time + ":00",
+		CinemaID:        showtime.CinemaID,
+		ShowtimeID:      showtime.ID,
 		PaymentMethodID: &req.PaymentMethod,
 		PaymentStatus:   "pending",
-		TotalAmount:     seat.Price,
+		TotalAmount:     totalAmount,
 		BookingStatus:   "reserved",
+		HoldExpiresAt:   &holdExpiresAt,
+		Seats:           bookingSeats,
 	}
 
-	if err := s.bookingRepo.Create(ctx, booking); err != nil {
+	if err := s.bookingRepo.CreateWithSeats(ctx, booking, s.config.GetBookingProcessingGrace()); err != nil {
+		var seatErr *repository.SeatTakenError
+		if errors.As(err, &seatErr) {
+			s.logger.Warn("Seat already booked",
+				zap.Int("showtime_id", req.ShowtimeID),
+				zap.Ints("seat_ids", seatErr.SeatIDs))
+			return nil, apperror.Wrapf(ErrSeatTaken, apperror.CodeConflict, "seat already taken for this showtime: %s", seatNumbers(seats, seatErr.SeatIDs))
+		}
 		s.logger.Error("Failed to create booking", zap.Error(err))
-		return nil, fmt.Errorf("failed to create booking")
+		return nil, apperror.Internal("failed to create booking")
 	}
 
 	s.logger.Info("Booking created successfully",
 		zap.Int("booking_id", booking.ID),
 		zap.Int("user_id", userID),
-		zap.Int("cinema_id", req.CinemaID),
-		zap.Int("seat_id", req.SeatID))
+		zap.Int("showtime_id", req.ShowtimeID),
+		zap.Ints("seat_ids", req.SeatIDs),
+		zap.Float64("total_amount", totalAmount))
 
 	return booking, nil
 }
@@ -112,7 +161,40 @@ func (s *BookingService) GetUserBookings(ctx context.Context, userID int) ([]*mo
 	bookings, err := s.bookingRepo.GetUserBookings(ctx, userID)
 	if err != nil {
 		s.logger.Error("Failed to get user bookings", zap.Int("user_id", userID), zap.Error(err))
-		return nil, fmt.Errorf("failed to get bookings")
+		return nil, apperror.Internal("failed to get bookings")
+	}
+
+	return bookings, nil
+}
+
+// GetShowtimeBookings retrieves the active bookings of a showtime for
+// back-office users. Cinema managers only see their own cinemas.
+func (s *BookingService) GetShowtimeBookings(ctx context.Context, user *models.User, showtimeID int) ([]*models.BookingDetail, error) {
+	showtime, err := s.showtimeRepo.GetByID(ctx, showtimeID)
+	if err != nil {
+		s.logger.Error("Failed to get showtime", zap.Int("showtime_id", showtimeID), zap.Error(err))
+		return nil, err
+	}
+
+	// Staff work the box office of every cinema
+	if user.Role != models.RoleStaff {
+		allowed, err := canManageCinema(ctx, s.cinemaRepo, user, showtime.CinemaID)
+		if err != nil {
+			s.logger.Error("Failed to check cinema access", zap.Int("cinema_id", showtime.CinemaID), zap.Error(err))
+			return nil, apperror.Internal("failed to get bookings")
+		}
+		if !allowed {
+			s.logger.Warn("Showtime bookings access denied",
+				zap.Int("user_id", user.ID),
+				zap.Int("showtime_id", showtimeID))
+			return nil, ErrCinemaAccessDenied
+		}
+	}
+
+	bookings, err := s.bookingRepo.GetShowtimeBookings(ctx, showtimeID)
+	if err != nil {
+		s.logger.Error("Failed to get showtime bookings", zap.Int("showtime_id", showtimeID), zap.Error(err))
+		return nil, apperror.Internal("failed to get bookings")
 	}
 
 	return bookings, nil
@@ -123,8 +205,8 @@ func (s *BookingService) ProcessPayment(ctx context.Context, userID int, req *dt
 	// Get booking
 	booking, err := s.bookingRepo.GetByID(ctx, req.BookingID)
 	if err != nil {
-		s.logger.Error("Booking not found", zap.Int("booking_id", req.BookingID))
-		return nil, fmt.Errorf("booking not found")
+		s.logger.Error("Failed to get booking", zap.Int("booking_id", req.BookingID), zap.Error(err))
+		return nil, err
 	}
 
 	// Verify booking belongs to user
@@ -132,35 +214,87 @@ func (s *BookingService) ProcessPayment(ctx context.Context, userID int, req *dt
 		s.logger.Warn("User attempting to pay for another user's booking",
 			zap.Int("user_id", userID),
 			zap.Int("booking_id", req.BookingID))
-		return nil, fmt.Errorf("unauthorized")
+		return nil, apperror.Forbidden("you do not have access to this booking")
 	}
 
 	// Check if already paid
 	if booking.PaymentStatus == "paid" {
 		s.logger.Warn("Booking already paid", zap.Int("booking_id", req.BookingID))
-		return nil, fmt.Errorf("booking is already paid")
+		return nil, apperror.Conflict("booking is already paid")
 	}
 
-	// Validate payment method
-	isValid, err := s.paymentRepo.ValidatePaymentMethod(ctx, req.PaymentMethod)
-	if err != nil || !isValid {
+	// Check if an asynchronous payment is still awaiting confirmation
+	if booking.PaymentStatus == "processing" {
+		s.logger.Warn("Booking payment already processing", zap.Int("booking_id", req.BookingID))
+		return nil, apperror.Conflict("payment is already being processed")
+	}
+
+	// Check the reservation is still being held
+	if booking.BookingStatus == "expired" ||
+		(booking.HoldExpiresAt != nil && !booking.HoldExpiresAt.After(time.Now())) {
+		s.logger.Warn("Booking hold expired", zap.Int("booking_id", req.BookingID))
+		return nil, apperror.Conflict("booking hold has expired, please book again")
+	}
+
+	if booking.BookingStatus != "reserved" {
+		s.logger.Warn("Booking cannot be paid",
+			zap.Int("booking_id", req.BookingID),
+			zap.String("booking_status", booking.BookingStatus))
+		return nil, apperror.Conflict("booking cannot be paid")
+	}
+
+	// Validate payment method and resolve its gateway
+	method, err := s.paymentRepo.GetPaymentMethodByID(ctx, req.PaymentMethod)
+	if err != nil || !method.IsActive {
 		s.logger.Error("Invalid payment method", zap.Int("payment_method_id", req.PaymentMethod))
-		return nil, fmt.Errorf("invalid payment method")
+		return nil, apperror.Validation("invalid payment method")
 	}
 
-	// In a real application, you would integrate with a payment gateway here
-	// For this example, we'll just update the status to "paid"
+	gw, err := s.gateways.Get(method.Provider)
+	if err != nil {
+		s.logger.Error("Payment gateway unavailable",
+			zap.Int("payment_method_id", method.ID),
+			zap.String("provider", method.Provider),
+			zap.Error(err))
+		return nil, apperror.Unavailable("payment method is currently unavailable")
+	}
 
-	// Update payment status
-	if err := s.bookingRepo.UpdatePaymentStatus(ctx, req.BookingID, "paid"); err != nil {
-		s.logger.Error("Failed to update payment status", zap.Error(err))
-		return nil, fmt.Errorf("failed to process payment")
+	// Charge the customer through the gateway
+	payment, err := s.charge(ctx, gw, booking, method, req.PaymentDetails)
+	if err != nil {
+		return nil, err
 	}
 
-	// Update booking status
-	if err := s.bookingRepo.UpdateBookingStatus(ctx, req.BookingID, "paid"); err != nil {
-		s.logger.Error("Failed to update booking status", zap.Error(err))
-		return nil, fmt.Errorf("failed to process payment")
+	// Asynchronous payments keep the seats until the provider's webhook arrives
+	if payment.Status == string(gateway.StatusPending) {
+		if err := s.bookingRepo.MarkPaymentProcessing(ctx, req.BookingID, method.ID, payment.ID); err != nil {
+			if errors.Is(err, repository.ErrBookingNotPayable) {
+				s.logger.Warn("Booking no longer payable", zap.Int("booking_id", req.BookingID))
+				return nil, apperror.Conflict("booking hold has expired, please book again")
+			}
+			s.logger.Error("Failed to update payment status", zap.Error(err))
+			return nil, apperror.Internal("failed to process payment")
+		}
+
+		s.logger.Info("Payment awaiting provider confirmation",
+			zap.Int("booking_id", req.BookingID),
+			zap.Int("user_id", userID),
+			zap.String("provider", payment.Provider))
+
+		updatedBooking, _ := s.bookingRepo.GetByID(ctx, req.BookingID)
+		return updatedBooking, nil
+	}
+
+	// Mark booking as paid; refund the charge if the hold lapsed or another
+	// payment got there first
+	if err := s.bookingRepo.MarkPaid(ctx, req.BookingID, method.ID, payment.ID); err != nil {
+		if errors.Is(err, repository.ErrBookingNotPayable) {
+			s.logger.Warn("Booking no longer payable, refunding charge", zap.Int("booking_id", req.BookingID))
+			s.refund(ctx, booking, payment, "reservation expired during payment")
+			return nil, apperror.Conflict("booking hold has expired, please book again")
+		}
+		s.logger.Error("Failed to update payment status", zap.Error(err))
+		return nil, apperror.Internal("failed to process payment")
 	}
 
 	s.logger.Info("Payment processed successfully",
@@ -171,3 +305,393 @@ func (s *BookingService) ProcessPayment(ctx context.Context, userID int, req *dt
 	updatedBooking, _ := s.bookingRepo.GetByID(ctx, req.BookingID)
 	return updatedBooking, nil
 }
+
+// CancelBooking cancels a user's booking, refunding it when already paid
+func (s *BookingService) CancelBooking(ctx context.Context, userID, bookingID int) (*dto.CancelBookingResponse, error) {
+	// Get booking
+	booking, err := s.bookingRepo.GetByID(ctx, bookingID)
+	if err != nil {
+		s.logger.Error("Failed to get booking", zap.Int("booking_id", bookingID), zap.Error(err))
+		return nil, err
+	}
+
+	// Verify booking belongs to user
+	if booking.UserID != userID {
+		s.logger.Warn("User attempting to cancel another user's booking",
+			zap.Int("user_id", userID),
+			zap.Int("booking_id", bookingID))
+		return nil, apperror.Forbidden("you do not have access to this booking")
+	}
+
+	// Apply cancellation policy
+	showtime, err := s.showtimeRepo.GetByID(ctx, booking.ShowtimeID)
+	if err != nil {
+		s.logger.Error("Failed to get showtime", zap.Int("showtime_id", booking.ShowtimeID), zap.Error(err))
+		return nil, apperror.Internal("failed to cancel booking")
+	}
+
+	cutoff := s.config.GetBookingCancelCutoff()
+	if time.Until(showtime.StartTime) < cutoff {
+		s.logger.Warn("Cancellation window closed",
+			zap.Int("booking_id", bookingID),
+			zap.Time("start_time", showtime.StartTime))
+		return nil, apperror.Newf(apperror.CodeConflict, "bookings cannot be cancelled less than %d hours before the showtime", s.config.Booking.CancelCutoffHours)
+	}
+
+	// Cancel booking and release its seats
+	wasPaid, err := s.bookingRepo.Cancel(ctx, bookingID)
+	if err != nil {
+		if errors.Is(err, repository.ErrBookingNotCancellable) {
+			s.logger.Warn("Booking not cancellable",
+				zap.Int("booking_id", bookingID),
+				zap.String("booking_status", booking.BookingStatus))
+			return nil, apperror.Newf(apperror.CodeConflict, "booking is already %s and cannot be cancelled", booking.BookingStatus)
+		}
+		s.logger.Error("Failed to cancel booking", zap.Int("booking_id", bookingID), zap.Error(err))
+		return nil, apperror.Internal("failed to cancel booking")
+	}
+
+	// Refund paid bookings through the gateway that charged them
+	var refund *models.Payment
+	if wasPaid {
+		// A missing charge (e.g. paid before gateways existed) is refunded manually
+		charge, err := s.paymentRepo.GetSucceededCharge(ctx, bookingID)
+		if err != nil {
+			s.logger.Warn("No gateway charge found for paid booking",
+				zap.Int("booking_id", bookingID),
+				zap.Error(err))
+		}
+		refund = s.refund(ctx, booking, charge, "cancelled by customer")
+	}
+
+	s.logger.Info("Booking cancelled successfully",
+		zap.Int("booking_id", bookingID),
+		zap.Int("user_id", userID),
+		zap.Bool("refund_requested", refund != nil))
+
+	// Get updated booking
+	updatedBooking, _ := s.bookingRepo.GetByID(ctx, bookingID)
+	return &dto.CancelBookingResponse{
+		Booking: updatedBooking,
+		Refund:  refund,
+	}, nil
+}
+
+// HandlePaymentWebhook applies an asynchronous payment notification from a
+// provider. Events are verified by the provider's gateway and applied at most
+// once per provider event ID.
+func (s *BookingService) HandlePaymentWebhook(ctx context.Context, provider string, payload []byte, header http.Header) error {
+	// Resolve a gateway that supports webhooks
+	gw, err := s.gateways.Get(provider)
+	if err != nil {
+		s.logger.Warn("Webhook for unknown provider", zap.String("provider", provider))
+		return ErrUnknownProvider
+	}
+
+	verifier, ok := gw.(gateway.WebhookVerifier)
+	if !ok {
+		s.logger.Warn("Webhook for provider without webhook support", zap.String("provider", provider))
+		return ErrUnknownProvider
+	}
+
+	// Verify signature and decode event
+	event, err := verifier.ParseWebhook(payload, header)
+	if err != nil {
+		if errors.Is(err, gateway.ErrInvalidSignature) {
+			s.logger.Warn("Webhook with invalid signature", zap.String("provider", provider))
+			return ErrInvalidSignature
+		}
+		s.logger.Warn("Invalid webhook payload", zap.String("provider", provider), zap.Error(err))
+		return ErrInvalidWebhookPayload
+	}
+
+	// Skip events that have already been applied
+	processed, err := s.paymentRepo.RecordWebhookEvent(ctx, provider, event.ID, payload)
+	if err != nil {
+		s.logger.Error("Failed to record webhook event", zap.String("event_id", event.ID), zap.Error(err))
+		return apperror.Internal("failed to process webhook")
+	}
+	if processed {
+		s.logger.Info("Duplicate webhook event ignored",
+			zap.String("provider", provider),
+			zap.String("event_id", event.ID))
+		return nil
+	}
+
+	payment, err := s.paymentRepo.GetPaymentByReference(ctx, provider, event.Reference)
+	if err != nil {
+		s.logger.Warn("Webhook references unknown payment",
+			zap.String("provider", provider),
+			zap.String("reference", event.Reference))
+		return ErrPaymentNotFound
+	}
+
+	if payment.Type == "charge" {
+		if err := s.applyChargeEvent(ctx, payment, event); err != nil {
+			return err
+		}
+	}
+
+	if err := s.paymentRepo.MarkWebhookEventProcessed(ctx, provider, event.ID); err != nil {
+		s.logger.Error("Failed to mark webhook event processed", zap.String("event_id", event.ID), zap.Error(err))
+		return apperror.Internal("failed to process webhook")
+	}
+
+	s.logger.Info("Webhook event processed",
+		zap.String("provider", provider),
+		zap.String("event_id", event.ID),
+		zap.Int("booking_id", payment.BookingID),
+		zap.String("status", string(event.Status)))
+
+	return nil
+}
+
+// applyChargeEvent moves a booking awaiting asynchronous payment to paid or
+// failed according to the provider's event. A succeeded charge that did not
+// pay for the booking is refunded. Every step is conditional, so re-applying
+// an event after a partial failure is safe.
+func (s *BookingService) applyChargeEvent(ctx context.Context, payment *models.Payment, event *gateway.WebhookEvent) error {
+	switch event.Status {
+	case gateway.StatusSucceeded:
+		payment.Status = string(event.Status)
+		s.recordPaymentResult(ctx, payment)
+
+		paidByCharge, err := s.bookingRepo.ConfirmPayment(ctx, payment.BookingID, payment.ID)
+		if err != nil {
+			s.logger.Error("Failed to confirm payment", zap.Int("booking_id", payment.BookingID), zap.Error(err))
+			return apperror.Internal("failed to process webhook")
+		}
+
+		if !paidByCharge {
+			// The booking was released or paid by another charge while the
+			// customer was paying; return the money
+			booking, err := s.bookingRepo.GetByID(ctx, payment.BookingID)
+			if err != nil {
+				s.logger.Error("Failed to get booking", zap.Int("booking_id", payment.BookingID), zap.Error(err))
+				return apperror.Internal("failed to process webhook")
+			}
+			s.logger.Warn("Payment confirmed for booking it did not pay for, refunding",
+				zap.Int("booking_id", booking.ID),
+				zap.Int("payment_id", payment.ID),
+				zap.String("booking_status", booking.BookingStatus))
+			s.refund(ctx, booking, payment, "payment confirmed after booking was released or paid")
+		}
+
+	case gateway.StatusDeclined, gateway.StatusFailed:
+		// A failure arriving after the charge succeeded is stale
+		if payment.Status == string(gateway.StatusSucceeded) {
+			s.logger.Warn("Ignoring failure webhook for succeeded charge",
+				zap.String("event_id", event.ID),
+				zap.Int("payment_id", payment.ID))
+			return nil
+		}
+
+		payment.Status = string(event.Status)
+		payment.Reason = event.FailureReason
+		s.recordPaymentResult(ctx, payment)
+
+		failed, err := s.bookingRepo.FailPayment(ctx, payment.BookingID, payment.ID)
+		if err != nil {
+			s.logger.Error("Failed to fail payment", zap.Int("booking_id", payment.BookingID), zap.Error(err))
+			return apperror.Internal("failed to process webhook")
+		}
+		if !failed {
+			s.logger.Info("Failure webhook left booking unchanged",
+				zap.String("event_id", event.ID),
+				zap.Int("booking_id", payment.BookingID),
+				zap.Int("payment_id", payment.ID))
+		}
+
+	default:
+		s.logger.Warn("Ignoring webhook with unsupported status",
+			zap.String("event_id", event.ID),
+			zap.String("status", string(event.Status)))
+	}
+
+	return nil
+}
+
+// charge collects payment for a booking and records the attempt. The returned
+// payment is either a succeeded charge or, for asynchronous providers, a
+// pending one; declines and gateway failures are reported as errors.
+func (s *BookingService) charge(
+	ctx context.Context,
+	gw gateway.PaymentGateway,
+	booking *models.Booking,
+	method *models.PaymentMethod,
+	details map[string]interface{},
+) (*models.Payment, error) {
+	// Record the attempt before calling the gateway so no charge goes untracked
+	payment := &models.Payment{
+		BookingID:       booking.ID,
+		PaymentMethodID: &method.ID,
+		Provider:        gw.Name(),
+		Type:            "charge",
+		Amount:          booking.TotalAmount,
+		Status:          "processing",
+	}
+
+	if err := s.paymentRepo.CreatePayment(ctx, payment); err != nil {
+		s.logger.Error("Failed to record payment attempt", zap.Int("booking_id", booking.ID), zap.Error(err))
+		return nil, apperror.Internal("failed to process payment")
+	}
+
+	gatewayCtx, cancel := context.WithTimeout(ctx, s.config.GetPaymentGatewayTimeout())
+	defer cancel()
+
+	result, err := gw.Charge(gatewayCtx, gateway.ChargeRequest{
+		BookingID: booking.ID,
+		Amount:    booking.TotalAmount,
+		Currency:  "IDR",
+		Details:   details,
+	})
+	if err != nil {
+		payment.Status = "failed"
+		if errors.Is(err, gateway.ErrTimeout) {
+			payment.Status = "timeout"
+		}
+		payment.Reason = err.Error()
+		s.recordPaymentResult(ctx, payment)
+
+		s.logger.Error("Payment gateway charge failed",
+			zap.Int("booking_id", booking.ID),
+			zap.String("provider", gw.Name()),
+			zap.Error(err))
+		if errors.Is(err, gateway.ErrTimeout) {
+			return nil, ErrPaymentTimeout
+		}
+		return nil, apperror.Internal("failed to process payment")
+	}
+
+	payment.ProviderReference = &result.Reference
+	payment.Status = string(result.Status)
+	payment.Reason = result.FailureReason
+	s.recordPaymentResult(ctx, payment)
+
+	switch result.Status {
+	case gateway.StatusSucceeded, gateway.StatusPending:
+		return payment, nil
+	case gateway.StatusDeclined:
+		s.logger.Warn("Payment declined",
+			zap.Int("booking_id", booking.ID),
+			zap.String("reason", result.FailureReason))
+		return nil, apperror.Wrapf(ErrPaymentDeclined, apperror.CodePaymentDeclined, "payment declined: %s", result.FailureReason)
+	default:
+		s.logger.Error("Unexpected payment gateway status",
+			zap.Int("booking_id", booking.ID),
+			zap.String("status", string(result.Status)))
+		return nil, apperror.Internal("failed to process payment")
+	}
+}
+
+// refund returns the money of a succeeded charge and records the attempt.
+// When the charge is unknown or its gateway is unavailable the refund is
+// recorded for manual processing and the booking's payment status becomes
+// 'refund_manual'. Refund problems are logged and reflected in the booking's
+// payment status rather than failing the caller, since the booking has
+// already been released or the refunded charge was a duplicate.
+func (s *BookingService) refund(ctx context.Context, booking *models.Booking, charge *models.Payment, reason string) *models.Payment {
+	refund := &models.Payment{
+		BookingID: booking.ID,
+		Provider:  "manual",
+		Type:      "refund",
+		Amount:    booking.TotalAmount,
+		Status:    "pending",
+		Reason:    reason,
+	}
+
+	if charge == nil || charge.ProviderReference == nil {
+		if err := s.paymentRepo.CreatePayment(ctx, refund); err != nil {
+			s.logger.Error("Failed to record manual refund", zap.Int("booking_id", booking.ID), zap.Error(err))
+		}
+		s.awaitManualRefund(ctx, booking)
+		return refund
+	}
+
+	refund.PaymentMethodID = charge.PaymentMethodID
+	refund.Provider = charge.Provider
+	refund.Amount = charge.Amount
+	refund.Status = "processing"
+
+	if err := s.paymentRepo.CreatePayment(ctx, refund); err != nil {
+		s.logger.Error("Failed to record refund attempt", zap.Int("booking_id", booking.ID), zap.Error(err))
+		return refund
+	}
+
+	gw, err := s.gateways.Get(charge.Provider)
+	if err != nil {
+		s.logger.Error("Payment gateway unavailable for refund",
+			zap.Int("booking_id", booking.ID),
+			zap.String("provider", charge.Provider),
+			zap.Error(err))
+		refund.Status = "pending"
+		s.recordPaymentResult(ctx, refund)
+		s.awaitManualRefund(ctx, booking)
+		return refund
+	}
+
+	gatewayCtx, cancel := context.WithTimeout(ctx, s.config.GetPaymentGatewayTimeout())
+	defer cancel()
+
+	result, err := gw.Refund(gatewayCtx, gateway.RefundRequest{
+		BookingID:       booking.ID,
+		ChargeReference: *charge.ProviderReference,
+		Amount:          charge.Amount,
+		Reason:          reason,
+	})
+
+	paymentStatus := "refund_failed"
+	switch {
+	case err != nil:
+		s.logger.Error("Payment gateway refund failed", zap.Int("booking_id", booking.ID), zap.Error(err))
+		refund.Status = "failed"
+		if errors.Is(err, gateway.ErrTimeout) {
+			refund.Status = "timeout"
+		}
+	case result.Status == gateway.StatusSucceeded:
+		refund.ProviderReference = &result.Reference
+		refund.Status = string(result.Status)
+		paymentStatus = "refunded"
+	default:
+		refund.ProviderReference = &result.Reference
+		refund.Status = string(result.Status)
+		refund.Reason = result.FailureReason
+	}
+	s.recordPaymentResult(ctx, refund)
+
+	if err := s.bookingRepo.UpdatePaymentStatus(ctx, booking.ID, paymentStatus); err != nil {
+		s.logger.Error("Failed to update payment status", zap.Int("booking_id", booking.ID), zap.Error(err))
+	}
+
+	return refund
+}
+
+// awaitManualRefund marks a booking whose refund nobody will settle
+// automatically, so operators can find it and pay it out by hand
+func (s *BookingService) awaitManualRefund(ctx context.Context, booking *models.Booking) {
+	s.logger.Warn("Refund needs manual processing", zap.Int("booking_id", booking.ID))
+	if err := s.bookingRepo.UpdatePaymentStatus(ctx, booking.ID, "refund_manual"); err != nil {
+		s.logger.Error("Failed to update payment status", zap.Int("booking_id", booking.ID), zap.Error(err))
+	}
+}
+
+// recordPaymentResult stores a gateway outcome, logging rather than failing on error
+func (s *BookingService) recordPaymentResult(ctx context.Context, payment *models.Payment) {
+	if err := s.paymentRepo.UpdatePaymentResult(ctx, payment); err != nil {
+		s.logger.Error("Failed to record payment result",
+			zap.Int("payment_id", payment.ID),
+			zap.String("status", payment.Status),
+			zap.Error(err))
+	}
+}
+
+// seatNumbers returns the comma-separated seat numbers of the given seat IDs
+func seatNumbers(seats []*models.Seat, seatIDs []int) string {
+	var numbers []string
+	for _, seat := range seats {
+		if slices.Contains(seatIDs, seat.ID) {
+			numbers = append(numbers, seat.SeatNumber)
+		}
+	}
+	return strings.Join(numbers, ", ")
+}
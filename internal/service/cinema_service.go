@@ -2,8 +2,10 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"cinema-booking-system/internal/apperror"
 	"cinema-booking-system/internal/dto"
 	"cinema-booking-system/internal/models"
 	"cinema-booking-system/internal/repository"
@@ -11,6 +13,9 @@ import (
 	"go.uber.org/zap"
 )
 
+// ErrCinemaInUse is returned when bookings reference a cinema or seat
+var ErrCinemaInUse = apperror.Conflict("cinema has bookings and cannot be deleted")
+
 // CinemaService handles cinema-related business logic
 type CinemaService struct {
 	cinemaRepo *repository.CinemaRepository
@@ -34,14 +39,14 @@ func (s *CinemaService) GetAllCinemas(ctx context.Context, page, pageSize int) (
 	cinemas, err := s.cinemaRepo.GetAll(ctx, pageSize, offset)
 	if err != nil {
 		s.logger.Error("Failed to get cinemas", zap.Error(err))
-		return nil, fmt.Errorf("failed to get cinemas")
+		return nil, apperror.Internal("failed to get cinemas")
 	}
 
 	// Get total count
 	totalCount, err := s.cinemaRepo.Count(ctx)
 	if err != nil {
 		s.logger.Error("Failed to count cinemas", zap.Error(err))
-		return nil, fmt.Errorf("failed to count cinemas")
+		return nil, apperror.Internal("failed to count cinemas")
 	}
 
 	// Calculate total pages
@@ -63,31 +68,149 @@ func (s *CinemaService) GetCinemaByID(ctx context.Context, id int) (*models.Cine
 	cinema, err := s.cinemaRepo.GetByID(ctx, id)
 	if err != nil {
 		s.logger.Error("Failed to get cinema", zap.Int("cinema_id", id), zap.Error(err))
-		return nil, fmt.Errorf("cinema not found")
+		return nil, err
 	}
 
 	return cinema, nil
 }
 
-// GetSeatsAvailability retrieves seat availability for a specific showtime
-func (s *CinemaService) GetSeatsAvailability(ctx context.Context, cinemaID int, date, time string) ([]*models.SeatAvailability, error) {
+// CreateCinema creates a new cinema without seats
+func (s *CinemaService) CreateCinema(ctx context.Context, req *dto.CinemaRequest) (*models.Cinema, error) {
+	cinema := &models.Cinema{
+		Name:        req.Name,
+		Location:    req.Location,
+		Description: req.Description,
+	}
+
+	if err := s.cinemaRepo.Create(ctx, cinema); err != nil {
+		s.logger.Error("Failed to create cinema", zap.Error(err))
+		return nil, apperror.Internal("failed to create cinema")
+	}
+
+	s.logger.Info("Cinema created", zap.Int("cinema_id", cinema.ID), zap.String("name", cinema.Name))
+	return cinema, nil
+}
+
+// UpdateCinema updates cinema details. Cinema managers may only update their own cinemas.
+func (s *CinemaService) UpdateCinema(ctx context.Context, user *models.User, cinemaID int, req *dto.CinemaRequest) (*models.Cinema, error) {
+	if err := s.checkAccess(ctx, user, cinemaID); err != nil {
+		return nil, err
+	}
+
+	cinema := &models.Cinema{
+		ID:          cinemaID,
+		Name:        req.Name,
+		Location:    req.Location,
+		Description: req.Description,
+	}
+
+	if err := s.cinemaRepo.Update(ctx, cinema); err != nil {
+		s.logger.Error("Failed to update cinema", zap.Int("cinema_id", cinemaID), zap.Error(err))
+		return nil, err
+	}
+
+	s.logger.Info("Cinema updated", zap.Int("cinema_id", cinemaID), zap.Int("user_id", user.ID))
+	return cinema, nil
+}
+
+// DeleteCinema deletes a cinema that has never been booked
+func (s *CinemaService) DeleteCinema(ctx context.Context, cinemaID int) error {
+	if err := s.cinemaRepo.Delete(ctx, cinemaID); err != nil {
+		if errors.Is(err, repository.ErrCinemaInUse) {
+			s.logger.Warn("Refusing to delete cinema with bookings", zap.Int("cinema_id", cinemaID))
+			return ErrCinemaInUse
+		}
+		s.logger.Error("Failed to delete cinema", zap.Int("cinema_id", cinemaID), zap.Error(err))
+		return err
+	}
+
+	s.logger.Info("Cinema deleted", zap.Int("cinema_id", cinemaID))
+	return nil
+}
+
+// DefineSeatLayout creates or reprices seats for ranges of rows. Seats are
+// numbered by row letter and position, e.g. A1..A15. Prices of existing
+// bookings are not affected.
+func (s *CinemaService) DefineSeatLayout(ctx context.Context, user *models.User, cinemaID int, req *dto.SeatLayoutRequest) ([]*models.Seat, error) {
+	if err := s.checkAccess(ctx, user, cinemaID); err != nil {
+		return nil, err
+	}
+
 	// Validate cinema exists
-	_, err := s.cinemaRepo.GetByID(ctx, cinemaID)
-	if err != nil {
-		s.logger.Error("Cinema not found", zap.Int("cinema_id", cinemaID))
-		return nil, fmt.Errorf("cinema not found")
+	if _, err := s.cinemaRepo.GetByID(ctx, cinemaID); err != nil {
+		s.logger.Error("Failed to get cinema", zap.Int("cinema_id", cinemaID), zap.Error(err))
+		return nil, err
 	}
 
-	// Get seat availability
-	seats, err := s.cinemaRepo.GetSeatsAvailability(ctx, cinemaID, date, time)
-	if err != nil {
-		s.logger.Error("Failed to get seat availability",
-			zap.Int("cinema_id", cinemaID),
-			zap.String("date", date),
-			zap.String("time", time),
-			zap.Error(err))
-		return nil, fmt.Errorf("failed to get seat availability")
+	// Expand row ranges into seats
+	var seats []*models.Seat
+	definedRows := make(map[byte]bool)
+	for _, rowRange := range req.Rows {
+		from, to := rowRange.FromRow[0], rowRange.ToRow[0]
+		if from > to {
+			return nil, apperror.Newf(apperror.CodeValidation, "invalid row range %s-%s", rowRange.FromRow, rowRange.ToRow)
+		}
+
+		for row := from; row <= to; row++ {
+			if definedRows[row] {
+				return nil, apperror.Newf(apperror.CodeValidation, "row %c is defined more than once", row)
+			}
+			definedRows[row] = true
+
+			for number := 1; number <= rowRange.SeatsPerRow; number++ {
+				seats = append(seats, &models.Seat{
+					SeatNumber: fmt.Sprintf("%c%d", row, number),
+					RowNumber:  string(row),
+					SeatType:   rowRange.SeatType,
+					Price:      rowRange.Price,
+				})
+			}
+		}
 	}
 
+	if err := s.cinemaRepo.UpsertSeats(ctx, cinemaID, seats); err != nil {
+		s.logger.Error("Failed to save seat layout", zap.Int("cinema_id", cinemaID), zap.Error(err))
+		return nil, apperror.Internal("failed to save seat layout")
+	}
+
+	s.logger.Info("Seat layout saved",
+		zap.Int("cinema_id", cinemaID),
+		zap.Int("seats", len(seats)),
+		zap.Int("user_id", user.ID))
+
 	return seats, nil
 }
+
+// DeleteSeat removes a seat that has never been booked
+func (s *CinemaService) DeleteSeat(ctx context.Context, user *models.User, cinemaID, seatID int) error {
+	if err := s.checkAccess(ctx, user, cinemaID); err != nil {
+		return err
+	}
+
+	if err := s.cinemaRepo.DeleteSeat(ctx, cinemaID, seatID); err != nil {
+		if errors.Is(err, repository.ErrCinemaInUse) {
+			s.logger.Warn("Refusing to delete seat with bookings", zap.Int("seat_id", seatID))
+			return apperror.Wrapf(ErrCinemaInUse, apperror.CodeConflict, "seat has bookings and cannot be deleted")
+		}
+		s.logger.Error("Failed to delete seat", zap.Int("seat_id", seatID), zap.Error(err))
+		return err
+	}
+
+	s.logger.Info("Seat deleted", zap.Int("cinema_id", cinemaID), zap.Int("seat_id", seatID))
+	return nil
+}
+
+// checkAccess verifies that a user may manage a cinema
+func (s *CinemaService) checkAccess(ctx context.Context, user *models.User, cinemaID int) error {
+	allowed, err := canManageCinema(ctx, s.cinemaRepo, user, cinemaID)
+	if err != nil {
+		s.logger.Error("Failed to check cinema access", zap.Int("cinema_id", cinemaID), zap.Error(err))
+		return apperror.Internal("failed to check cinema access")
+	}
+	if !allowed {
+		s.logger.Warn("Cinema access denied", zap.Int("user_id", user.ID), zap.Int("cinema_id", cinemaID))
+		return ErrCinemaAccessDenied
+	}
+
+	return nil
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"cinema-booking-system/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// HoldSweeper periodically expires unpaid reservations whose hold has lapsed,
+// including those whose payment confirmation never arrived. It also prunes
+// login throttles that no longer count towards a lockout.
+type HoldSweeper struct {
+	bookingRepo        *repository.BookingRepository
+	throttleRepo       *repository.LoginThrottleRepository
+	interval           time.Duration
+	processingGrace    time.Duration
+	loginAttemptWindow time.Duration
+	logger             *zap.Logger
+}
+
+// NewHoldSweeper creates a new hold sweeper
+func NewHoldSweeper(
+	bookingRepo *repository.BookingRepository,
+	throttleRepo *repository.LoginThrottleRepository,
+	interval time.Duration,
+	processingGrace time.Duration,
+	loginAttemptWindow time.Duration,
+	logger *zap.Logger,
+) *HoldSweeper {
+	return &HoldSweeper{
+		bookingRepo:        bookingRepo,
+		throttleRepo:       throttleRepo,
+		interval:           interval,
+		processingGrace:    processingGrace,
+		loginAttemptWindow: loginAttemptWindow,
+		logger:             logger,
+	}
+}
+
+// Run sweeps expired holds on every tick until ctx is cancelled
+func (s *HoldSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	s.logger.Info("Hold sweeper started", zap.Duration("interval", s.interval))
+
+	for {
+		s.sweep(ctx)
+
+		select {
+		case <-ctx.Done():
+			s.logger.Info("Hold sweeper stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sweep releases all lapsed holds and prunes stale login throttles once
+func (s *HoldSweeper) sweep(ctx context.Context) {
+	expired, err := s.bookingRepo.ExpireStaleHolds(ctx, s.processingGrace)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Error("Failed to expire stale holds", zap.Error(err))
+		}
+	} else if expired > 0 {
+		s.logger.Info("Expired unpaid reservations", zap.Int64("count", expired))
+	}
+
+	pruned, err := s.throttleRepo.DeleteStale(ctx, s.loginAttemptWindow)
+	if err != nil {
+		if ctx.Err() == nil {
+			s.logger.Error("Failed to prune login throttles", zap.Error(err))
+		}
+	} else if pruned > 0 {
+		s.logger.Info("Pruned stale login throttles", zap.Int64("count", pruned))
+	}
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"context"
+
+	"cinema-booking-system/internal/apperror"
+	"cinema-booking-system/internal/config"
+	"cinema-booking-system/internal/models"
+	"cinema-booking-system/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+var (
+	// ErrIdempotencyKeyInUse is returned while the original request for a key is still running
+	ErrIdempotencyKeyInUse = apperror.Conflict("a request with this Idempotency-Key is still being processed")
+	// ErrIdempotencyKeyMismatch is returned when a key is reused for a different request
+	ErrIdempotencyKeyMismatch = apperror.Unprocessable("idempotency key was already used for a different request")
+)
+
+// IdempotencyService handles Idempotency-Key business logic
+type IdempotencyService struct {
+	idempotencyRepo *repository.IdempotencyRepository
+	config          *config.Config
+	logger          *zap.Logger
+}
+
+// NewIdempotencyService creates a new idempotency service
+func NewIdempotencyService(idempotencyRepo *repository.IdempotencyRepository, cfg *config.Config, logger *zap.Logger) *IdempotencyService {
+	return &IdempotencyService{
+		idempotencyRepo: idempotencyRepo,
+		config:          cfg,
+		logger:          logger,
+	}
+}
+
+// Begin claims an idempotency key for a request. It returns nil when the
+// caller should process the request, or the stored record whose response
+// must be replayed instead.
+func (s *IdempotencyService) Begin(ctx context.Context, userID int, key, requestHash string) (*models.IdempotencyKey, error) {
+	record, acquired, err := s.idempotencyRepo.Acquire(ctx, userID, key, requestHash, s.config.GetIdempotencyTTL())
+	if err != nil {
+		s.logger.Error("Failed to acquire idempotency key", zap.Int("user_id", userID), zap.Error(err))
+		return nil, apperror.Internal("failed to process idempotency key")
+	}
+	if acquired {
+		return nil, nil
+	}
+
+	// Same key must always describe the same request
+	if record.RequestHash != requestHash {
+		s.logger.Warn("Idempotency key reused with different request",
+			zap.Int("user_id", userID),
+			zap.String("idempotency_key", key))
+		return nil, ErrIdempotencyKeyMismatch
+	}
+
+	if record.StatusCode == nil {
+		s.logger.Warn("Idempotency key still in flight",
+			zap.Int("user_id", userID),
+			zap.String("idempotency_key", key))
+		return nil, ErrIdempotencyKeyInUse
+	}
+
+	s.logger.Info("Replaying idempotent response",
+		zap.Int("user_id", userID),
+		zap.String("idempotency_key", key),
+		zap.Int("status", *record.StatusCode))
+
+	return record, nil
+}
+
+// Complete stores the response of a request so retries can replay it
+func (s *IdempotencyService) Complete(ctx context.Context, userID int, key string, statusCode int, body []byte) error {
+	if err := s.idempotencyRepo.Complete(ctx, userID, key, statusCode, body); err != nil {
+		s.logger.Error("Failed to store idempotent response", zap.Int("user_id", userID), zap.Error(err))
+		return apperror.Internal("failed to store idempotent response")
+	}
+
+	return nil
+}
+
+// Release frees a key whose request did not finish, allowing a retry
+func (s *IdempotencyService) Release(ctx context.Context, userID int, key string) error {
+	if err := s.idempotencyRepo.Release(ctx, userID, key); err != nil {
+		s.logger.Error("Failed to release idempotency key", zap.Int("user_id", userID), zap.Error(err))
+		return apperror.Internal("failed to release idempotency key")
+	}
+
+	return nil
+}
@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+
+	"cinema-booking-system/internal/apperror"
+	"cinema-booking-system/internal/dto"
+	"cinema-booking-system/internal/models"
+	"cinema-booking-system/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// MovieService handles movie-related business logic
+type MovieService struct {
+	movieRepo    *repository.MovieRepository
+	showtimeRepo *repository.ShowtimeRepository
+	logger       *zap.Logger
+}
+
+// NewMovieService creates a new movie service
+func NewMovieService(movieRepo *repository.MovieRepository, showtimeRepo *repository.ShowtimeRepository, logger *zap.Logger) *MovieService {
+	return &MovieService{
+		movieRepo:    movieRepo,
+		showtimeRepo: showtimeRepo,
+		logger:       logger,
+	}
+}
+
+// GetAllMovies retrieves all movies with pagination
+func (s *MovieService) GetAllMovies(ctx context.Context, page, pageSize int) (*dto.PaginatedResponse, error) {
+	// Calculate offset
+	offset := (page - 1) * pageSize
+
+	// Get movies
+	movies, err := s.movieRepo.GetAll(ctx, pageSize, offset)
+	if err != nil {
+		s.logger.Error("Failed to get movies", zap.Error(err))
+		return nil, apperror.Internal("failed to get movies")
+	}
+
+	// Get total count
+	totalCount, err := s.movieRepo.Count(ctx)
+	if err != nil {
+		s.logger.Error("Failed to count movies", zap.Error(err))
+		return nil, apperror.Internal("failed to count movies")
+	}
+
+	// Calculate total pages
+	totalPages := (totalCount + pageSize - 1) / pageSize
+
+	return &dto.PaginatedResponse{
+		Data: movies,
+		Pagination: dto.PaginationMeta{
+			CurrentPage: page,
+			PageSize:    pageSize,
+			TotalItems:  totalCount,
+			TotalPages:  totalPages,
+		},
+	}, nil
+}
+
+// GetMovieByID retrieves a movie by ID
+func (s *MovieService) GetMovieByID(ctx context.Context, id int) (*models.Movie, error) {
+	movie, err := s.movieRepo.GetByID(ctx, id)
+	if err != nil {
+		s.logger.Error("Failed to get movie", zap.Int("movie_id", id), zap.Error(err))
+		return nil, err
+	}
+
+	return movie, nil
+}
+
+// GetMovieShowtimes retrieves showtimes of a movie across all cinemas
+func (s *MovieService) GetMovieShowtimes(ctx context.Context, movieID int, date string) ([]*models.ShowtimeDetail, error) {
+	// Validate movie exists
+	if _, err := s.movieRepo.GetByID(ctx, movieID); err != nil {
+		s.logger.Error("Failed to get movie", zap.Int("movie_id", movieID), zap.Error(err))
+		return nil, err
+	}
+
+	showtimes, err := s.showtimeRepo.GetByMovie(ctx, movieID, date)
+	if err != nil {
+		s.logger.Error("Failed to get movie showtimes",
+			zap.Int("movie_id", movieID),
+			zap.String("date", date),
+			zap.Error(err))
+		return nil, apperror.Internal("failed to get showtimes")
+	}
+
+	return showtimes, nil
+}
+
+// CreateMovie adds a new movie to the catalogue
+func (s *MovieService) CreateMovie(ctx context.Context, req *dto.CreateMovieRequest) (*models.Movie, error) {
+	movie := &models.Movie{
+		Title:           req.Title,
+		Description:     req.Description,
+		DurationMinutes: req.DurationMinutes,
+		Rating:          req.Rating,
+		Language:        req.Language,
+	}
+
+	if err := s.movieRepo.Create(ctx, movie); err != nil {
+		s.logger.Error("Failed to create movie", zap.Error(err))
+		return nil, apperror.Internal("failed to create movie")
+	}
+
+	s.logger.Info("Movie created", zap.Int("movie_id", movie.ID), zap.String("title", movie.Title))
+	return movie, nil
+}
@@ -2,8 +2,8 @@ package service
 
 import (
 	"context"
-	"fmt"
 
+	"cinema-booking-system/internal/apperror"
 	"cinema-booking-system/internal/models"
 	"cinema-booking-system/internal/repository"
 
@@ -29,7 +29,7 @@ func (s *PaymentService) GetAllPaymentMethods(ctx context.Context) ([]*models.Pa
 	methods, err := s.paymentRepo.GetAllPaymentMethods(ctx)
 	if err != nil {
 		s.logger.Error("Failed to get payment methods", zap.Error(err))
-		return nil, fmt.Errorf("failed to get payment methods")
+		return nil, apperror.Internal("failed to get payment methods")
 	}
 
 	return methods, nil
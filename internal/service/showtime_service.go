@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"cinema-booking-system/internal/apperror"
+	"cinema-booking-system/internal/dto"
+	"cinema-booking-system/internal/models"
+	"cinema-booking-system/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// ErrShowtimeConflict is returned when a new showtime overlaps an existing one
+var ErrShowtimeConflict = apperror.Conflict("cinema already has a showtime in this time slot")
+
+// ShowtimeService handles showtime-related business logic
+type ShowtimeService struct {
+	showtimeRepo *repository.ShowtimeRepository
+	movieRepo    *repository.MovieRepository
+	cinemaRepo   *repository.CinemaRepository
+	logger       *zap.Logger
+}
+
+// NewShowtimeService creates a new showtime service
+func NewShowtimeService(showtimeRepo *repository.ShowtimeRepository, movieRepo *repository.MovieRepository, cinemaRepo *repository.CinemaRepository, logger *zap.Logger) *ShowtimeService {
+	return &ShowtimeService{
+		showtimeRepo: showtimeRepo,
+		movieRepo:    movieRepo,
+		cinemaRepo:   cinemaRepo,
+		logger:       logger,
+	}
+}
+
+// GetShowtimeByID retrieves a showtime by ID
+func (s *ShowtimeService) GetShowtimeByID(ctx context.Context, id int) (*models.ShowtimeDetail, error) {
+	showtime, err := s.showtimeRepo.GetByID(ctx, id)
+	if err != nil {
+		s.logger.Error("Failed to get showtime", zap.Int("showtime_id", id), zap.Error(err))
+		return nil, err
+	}
+
+	return showtime, nil
+}
+
+// GetCinemaShowtimes retrieves the schedule of a cinema
+func (s *ShowtimeService) GetCinemaShowtimes(ctx context.Context, cinemaID int, date string) ([]*models.ShowtimeDetail, error) {
+	// Validate cinema exists
+	if _, err := s.cinemaRepo.GetByID(ctx, cinemaID); err != nil {
+		s.logger.Error("Failed to get cinema", zap.Int("cinema_id", cinemaID), zap.Error(err))
+		return nil, err
+	}
+
+	showtimes, err := s.showtimeRepo.GetByCinema(ctx, cinemaID, date)
+	if err != nil {
+		s.logger.Error("Failed to get cinema showtimes",
+			zap.Int("cinema_id", cinemaID),
+			zap.String("date", date),
+			zap.Error(err))
+		return nil, apperror.Internal("failed to get showtimes")
+	}
+
+	return showtimes, nil
+}
+
+// GetSeatsAvailability retrieves seat availability for a specific showtime
+func (s *ShowtimeService) GetSeatsAvailability(ctx context.Context, showtimeID int) ([]*models.SeatAvailability, error) {
+	// Validate showtime exists
+	if _, err := s.showtimeRepo.GetByID(ctx, showtimeID); err != nil {
+		s.logger.Error("Failed to get showtime", zap.Int("showtime_id", showtimeID), zap.Error(err))
+		return nil, err
+	}
+
+	// Get seat availability
+	seats, err := s.cinemaRepo.GetSeatsAvailability(ctx, showtimeID)
+	if err != nil {
+		s.logger.Error("Failed to get seat availability",
+			zap.Int("showtime_id", showtimeID),
+			zap.Error(err))
+		return nil, apperror.Internal("failed to get seat availability")
+	}
+
+	return seats, nil
+}
+
+// CreateShowtime schedules a movie in a cinema. The end time is derived from
+// the movie's duration. Cinema managers may only schedule their own cinemas.
+func (s *ShowtimeService) CreateShowtime(ctx context.Context, user *models.User, req *dto.CreateShowtimeRequest) (*models.ShowtimeDetail, error) {
+	// Check access to cinema
+	allowed, err := canManageCinema(ctx, s.cinemaRepo, user, req.CinemaID)
+	if err != nil {
+		s.logger.Error("Failed to check cinema access", zap.Int("cinema_id", req.CinemaID), zap.Error(err))
+		return nil, apperror.Internal("failed to create showtime")
+	}
+	if !allowed {
+		s.logger.Warn("Showtime creation denied",
+			zap.Int("user_id", user.ID),
+			zap.Int("cinema_id", req.CinemaID))
+		return nil, ErrCinemaAccessDenied
+	}
+
+	// Validate cinema and movie exist
+	if _, err := s.cinemaRepo.GetByID(ctx, req.CinemaID); err != nil {
+		s.logger.Error("Failed to get cinema", zap.Int("cinema_id", req.CinemaID), zap.Error(err))
+		return nil, err
+	}
+
+	movie, err := s.movieRepo.GetByID(ctx, req.MovieID)
+	if err != nil {
+		s.logger.Error("Failed to get movie", zap.Int("movie_id", req.MovieID), zap.Error(err))
+		return nil, err
+	}
+
+	// Parse start time
+	startTime, err := time.ParseInLocation("2006-01-02 15:04", req.Date+" "+req.Time, time.Local)
+	if err != nil {
+		return nil, apperror.Validation("invalid date or time format")
+	}
+	if !startTime.After(time.Now()) {
+		return nil, apperror.Validation("showtime must start in the future")
+	}
+	endTime := startTime.Add(time.Duration(movie.DurationMinutes) * time.Minute)
+
+	showtime := &models.Showtime{
+		MovieID:   req.MovieID,
+		CinemaID:  req.CinemaID,
+		StartTime: startTime,
+		EndTime:   endTime,
+	}
+
+	// The repository refuses the showtime if the cinema is not free
+	if err := s.showtimeRepo.Create(ctx, showtime); err != nil {
+		if errors.Is(err, repository.ErrShowtimeOverlap) {
+			return nil, ErrShowtimeConflict
+		}
+		s.logger.Error("Failed to create showtime", zap.Error(err))
+		return nil, apperror.Internal("failed to create showtime")
+	}
+
+	s.logger.Info("Showtime created",
+		zap.Int("showtime_id", showtime.ID),
+		zap.Int("cinema_id", showtime.CinemaID),
+		zap.Int("movie_id", showtime.MovieID),
+		zap.Int("user_id", user.ID))
+
+	return s.showtimeRepo.GetByID(ctx, showtime.ID)
+}
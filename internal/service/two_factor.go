@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base32"
+	"fmt"
+	"strings"
+	"time"
+
+	"cinema-booking-system/internal/apperror"
+	"cinema-booking-system/internal/dto"
+	"cinema-booking-system/internal/models"
+	"cinema-booking-system/internal/utils"
+	"cinema-booking-system/pkg/totp"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	// challengeTokenType marks a JWT that only proves the password step of a
+	// two-factor login; it is never accepted as an access token
+	challengeTokenType = "2fa_challenge"
+
+	// recoveryCodeCount is how many recovery codes are issued at a time
+	recoveryCodeCount = 10
+
+	// totpSkew is how many 30 second steps of clock drift are tolerated
+	totpSkew = 1
+)
+
+// recoveryCodeEncoding renders recovery codes in lowercase base32
+var recoveryCodeEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// VerifyTwoFactorLogin completes a login started with Login by checking a
+// TOTP or recovery code against the challenge token. Wrong codes count
+// towards the same lockout as wrong passwords.
+func (s *AuthService) VerifyTwoFactorLogin(ctx context.Context, req *dto.TwoFactorLoginRequest, userAgent, ipAddress string) (*dto.LoginResponse, error) {
+	userID, err := s.parseChallengeToken(req.ChallengeToken)
+	if err != nil {
+		s.logger.Warn("Invalid challenge token", zap.Error(err))
+		return nil, apperror.Unauthorized("invalid or expired challenge token")
+	}
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil || user.TOTPEnabledAt == nil {
+		s.logger.Warn("Challenge token for user without two-factor", zap.Int("user_id", userID))
+		return nil, apperror.Unauthorized("invalid or expired challenge token")
+	}
+
+	throttles := s.loginThrottles(user.Username, ipAddress)
+	attempts, err := s.beginLoginAttempt(ctx, throttles)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := s.verifySecondFactor(ctx, user, req.Code)
+	if err != nil {
+		s.logger.Error("Failed to verify two-factor code", zap.Int("user_id", user.ID), zap.Error(err))
+		s.releaseLoginAttempt(ctx, throttles, attempts)
+		return nil, apperror.Internal("failed to login")
+	}
+	if !ok {
+		s.logger.Warn("Login attempt with invalid two-factor code", zap.String("username", user.Username))
+		s.logLoginLocks(throttles, attempts)
+		return nil, apperror.Unauthorized("invalid two-factor code")
+	}
+	s.releaseLoginAttempt(ctx, throttles, attempts)
+
+	return s.createSession(ctx, user, userAgent, ipAddress)
+}
+
+// SetupTwoFactor generates a pending TOTP secret for the logged-in user.
+// Two-factor authentication is only enabled once ConfirmTwoFactor succeeds.
+func (s *AuthService) SetupTwoFactor(ctx context.Context, user *models.User) (*dto.TwoFactorSetupResponse, error) {
+	if user.TOTPEnabledAt != nil {
+		return nil, apperror.Conflict("two-factor authentication is already enabled")
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		s.logger.Error("Failed to generate totp secret", zap.Error(err))
+		return nil, apperror.Internal("failed to set up two-factor authentication")
+	}
+
+	if err := s.userRepo.SetPendingTOTPSecret(ctx, user.ID, secret); err != nil {
+		s.logger.Error("Failed to store totp secret", zap.Int("user_id", user.ID), zap.Error(err))
+		return nil, apperror.Internal("failed to set up two-factor authentication")
+	}
+
+	s.logger.Info("Two-factor setup started", zap.Int("user_id", user.ID))
+
+	return &dto.TwoFactorSetupResponse{
+		Secret:     secret,
+		OTPAuthURI: totp.URI(s.config.Auth.TwoFactorIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmTwoFactor enables two-factor authentication once the user proves
+// their authenticator works, and returns their recovery codes
+func (s *AuthService) ConfirmTwoFactor(ctx context.Context, user *models.User, req *dto.TwoFactorCodeRequest) (*dto.RecoveryCodesResponse, error) {
+	if user.TOTPEnabledAt != nil {
+		return nil, apperror.Conflict("two-factor authentication is already enabled")
+	}
+	if user.TOTPSecret == nil {
+		return nil, apperror.Conflict("no pending two-factor setup")
+	}
+
+	step, ok := totp.Validate(*user.TOTPSecret, strings.TrimSpace(req.Code), time.Now(), totpSkew)
+	if !ok {
+		return nil, apperror.BadRequest("invalid two-factor code")
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		s.logger.Error("Failed to generate recovery codes", zap.Error(err))
+		return nil, apperror.Internal("failed to enable two-factor authentication")
+	}
+
+	if err := s.userRepo.EnableTOTP(ctx, user.ID, step, hashes); err != nil {
+		s.logger.Error("Failed to enable totp", zap.Int("user_id", user.ID), zap.Error(err))
+		return nil, apperror.Internal("failed to enable two-factor authentication")
+	}
+
+	s.logger.Info("Two-factor authentication enabled", zap.Int("user_id", user.ID))
+	return &dto.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// DisableTwoFactor turns off two-factor authentication after checking both
+// the password and a current TOTP or recovery code
+func (s *AuthService) DisableTwoFactor(ctx context.Context, user *models.User, req *dto.DisableTwoFactorRequest) error {
+	if user.TOTPEnabledAt == nil {
+		return apperror.Conflict("two-factor authentication is not enabled")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		s.logger.Warn("Two-factor disable with invalid password", zap.Int("user_id", user.ID))
+		return apperror.BadRequest("password is incorrect")
+	}
+
+	ok, err := s.verifySecondFactor(ctx, user, req.Code)
+	if err != nil {
+		s.logger.Error("Failed to verify two-factor code", zap.Int("user_id", user.ID), zap.Error(err))
+		return apperror.Internal("failed to disable two-factor authentication")
+	}
+	if !ok {
+		return apperror.BadRequest("invalid two-factor code")
+	}
+
+	if err := s.userRepo.DisableTOTP(ctx, user.ID); err != nil {
+		s.logger.Error("Failed to disable totp", zap.Int("user_id", user.ID), zap.Error(err))
+		return apperror.Internal("failed to disable two-factor authentication")
+	}
+
+	s.logger.Info("Two-factor authentication disabled", zap.Int("user_id", user.ID))
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the logged-in user
+// after checking a current TOTP or recovery code
+func (s *AuthService) RegenerateRecoveryCodes(ctx context.Context, user *models.User, req *dto.TwoFactorCodeRequest) (*dto.RecoveryCodesResponse, error) {
+	if user.TOTPEnabledAt == nil {
+		return nil, apperror.Conflict("two-factor authentication is not enabled")
+	}
+
+	ok, err := s.verifySecondFactor(ctx, user, req.Code)
+	if err != nil {
+		s.logger.Error("Failed to verify two-factor code", zap.Int("user_id", user.ID), zap.Error(err))
+		return nil, apperror.Internal("failed to regenerate recovery codes")
+	}
+	if !ok {
+		return nil, apperror.BadRequest("invalid two-factor code")
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		s.logger.Error("Failed to generate recovery codes", zap.Error(err))
+		return nil, apperror.Internal("failed to regenerate recovery codes")
+	}
+
+	if err := s.userRepo.ReplaceRecoveryCodes(ctx, user.ID, hashes); err != nil {
+		s.logger.Error("Failed to store recovery codes", zap.Int("user_id", user.ID), zap.Error(err))
+		return nil, apperror.Internal("failed to regenerate recovery codes")
+	}
+
+	s.logger.Info("Recovery codes regenerated", zap.Int("user_id", user.ID))
+	return &dto.RecoveryCodesResponse{RecoveryCodes: codes}, nil
+}
+
+// verifySecondFactor checks a TOTP code, or failing the format of one, a
+// recovery code. Accepted codes are consumed so they cannot be replayed.
+func (s *AuthService) verifySecondFactor(ctx context.Context, user *models.User, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+
+	if isTOTPCode(code) {
+		step, ok := totp.Validate(*user.TOTPSecret, code, time.Now(), totpSkew)
+		if !ok {
+			return false, nil
+		}
+		return s.userRepo.UseTOTPStep(ctx, user.ID, step)
+	}
+
+	used, err := s.userRepo.UseRecoveryCode(ctx, user.ID, utils.HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		return false, err
+	}
+	if used {
+		s.logger.Info("Recovery code used", zap.Int("user_id", user.ID))
+	}
+
+	return used, nil
+}
+
+// generateChallengeToken signs the short-lived token that links the password
+// step of a login to its two-factor step
+func (s *AuthService) generateChallengeToken(user *models.User) (*dto.TwoFactorChallengeResponse, error) {
+	now := time.Now()
+	expiresAt := now.Add(s.config.GetTwoFactorChallengeTTL())
+	tokenString, err := s.keys.Sign(jwt.MapClaims{
+		"typ":     challengeTokenType,
+		"user_id": user.ID,
+		"exp":     expiresAt.Unix(),
+		"iat":     now.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dto.TwoFactorChallengeResponse{
+		TwoFactorRequired: true,
+		ChallengeToken:    tokenString,
+		ExpiresAt:         expiresAt.Format(time.RFC3339),
+	}, nil
+}
+
+// parseChallengeToken validates a challenge token and returns its user ID
+func (s *AuthService) parseChallengeToken(tokenString string) (int, error) {
+	claims := jwt.MapClaims{}
+	token, err := s.keys.Parse(tokenString, claims)
+	if err != nil || !token.Valid {
+		return 0, fmt.Errorf("invalid token")
+	}
+
+	if typ, _ := claims["typ"].(string); typ != challengeTokenType {
+		return 0, fmt.Errorf("not a challenge token")
+	}
+
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return 0, fmt.Errorf("missing user id")
+	}
+
+	return int(userID), nil
+}
+
+// generateRecoveryCodes returns new recovery codes formatted as xxxxx-xxxxx
+// along with the hashes to store
+func generateRecoveryCodes() ([]string, []string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+
+		code := recoveryCodeEncoding.EncodeToString(b)[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = utils.HashToken(code)
+	}
+
+	return codes, hashes, nil
+}
+
+// normalizeRecoveryCode strips the formatting users may type around a code
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.ReplaceAll(code, "-", "")
+	return strings.ReplaceAll(code, " ", "")
+}
+
+// isTOTPCode reports whether code has the shape of a TOTP code
+func isTOTPCode(code string) bool {
+	if len(code) != totp.Digits {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"errors"
+
+	"cinema-booking-system/internal/apperror"
+	"cinema-booking-system/internal/models"
+	"cinema-booking-system/internal/repository"
+
+	"go.uber.org/zap"
+)
+
+// ErrNotCinemaManager is returned when a user without the cinema_manager role
+// is assigned to a cinema
+var ErrNotCinemaManager = apperror.Validation("user must have the cinema_manager role")
+
+// UserService handles user administration business logic
+type UserService struct {
+	userRepo     *repository.UserRepository
+	cinemaRepo   *repository.CinemaRepository
+	throttleRepo *repository.LoginThrottleRepository
+	logger       *zap.Logger
+}
+
+// NewUserService creates a new user service
+func NewUserService(userRepo *repository.UserRepository, cinemaRepo *repository.CinemaRepository, throttleRepo *repository.LoginThrottleRepository, logger *zap.Logger) *UserService {
+	return &UserService{
+		userRepo:     userRepo,
+		cinemaRepo:   cinemaRepo,
+		throttleRepo: throttleRepo,
+		logger:       logger,
+	}
+}
+
+// UpdateUserRole changes the role of a user
+func (s *UserService) UpdateUserRole(ctx context.Context, actorID, userID int, role string) (*models.User, error) {
+	// Prevent admins from locking themselves out
+	if actorID == userID {
+		return nil, apperror.Forbidden("you cannot change your own role")
+	}
+
+	if err := s.userRepo.UpdateRole(ctx, userID, role); err != nil {
+		s.logger.Error("Failed to update user role", zap.Int("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+
+	s.logger.Info("User role updated",
+		zap.Int("user_id", userID),
+		zap.String("role", role),
+		zap.Int("actor_id", actorID))
+
+	return s.userRepo.GetByID(ctx, userID)
+}
+
+// UnlockUser clears the failed login attempts and lockout of a user
+func (s *UserService) UnlockUser(ctx context.Context, actorID, userID int) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to get user", zap.Int("user_id", userID), zap.Error(err))
+		return err
+	}
+
+	cleared, err := s.throttleRepo.Reset(ctx, repository.ThrottleScopeUsername, user.Username)
+	if err != nil {
+		s.logger.Error("Failed to unlock user", zap.Int("user_id", userID), zap.Error(err))
+		return apperror.Internal("failed to unlock user")
+	}
+
+	s.logger.Info("User login unlocked",
+		zap.Int("user_id", userID),
+		zap.Bool("was_throttled", cleared),
+		zap.Int("actor_id", actorID))
+
+	return nil
+}
+
+// AssignCinemaManager makes a cinema manager responsible for a cinema
+func (s *UserService) AssignCinemaManager(ctx context.Context, cinemaID, userID int) error {
+	// Validate cinema exists
+	if _, err := s.cinemaRepo.GetByID(ctx, cinemaID); err != nil {
+		s.logger.Error("Failed to get cinema", zap.Int("cinema_id", cinemaID), zap.Error(err))
+		return err
+	}
+
+	// Only cinema managers can be assigned
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		s.logger.Error("Failed to get user", zap.Int("user_id", userID), zap.Error(err))
+		return err
+	}
+	if user.Role != models.RoleCinemaManager {
+		return ErrNotCinemaManager
+	}
+
+	// The repository checks the role again while it holds the user
+	if err := s.cinemaRepo.AddManager(ctx, cinemaID, userID); err != nil {
+		if errors.Is(err, repository.ErrNotCinemaManager) {
+			return ErrNotCinemaManager
+		}
+		s.logger.Error("Failed to assign cinema manager", zap.Error(err))
+		return apperror.Internal("failed to assign cinema manager")
+	}
+
+	s.logger.Info("Cinema manager assigned", zap.Int("cinema_id", cinemaID), zap.Int("user_id", userID))
+	return nil
+}
+
+// RemoveCinemaManager removes a manager from a cinema
+func (s *UserService) RemoveCinemaManager(ctx context.Context, cinemaID, userID int) error {
+	if err := s.cinemaRepo.RemoveManager(ctx, cinemaID, userID); err != nil {
+		s.logger.Error("Failed to remove cinema manager",
+			zap.Int("cinema_id", cinemaID),
+			zap.Int("user_id", userID),
+			zap.Error(err))
+		return err
+	}
+
+	s.logger.Info("Cinema manager removed", zap.Int("cinema_id", cinemaID), zap.Int("user_id", userID))
+	return nil
+}
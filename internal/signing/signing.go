@@ -0,0 +1,209 @@
+// Package signing holds the keys JWTs are signed and verified with.
+//
+// Keys are PEM files in a directory, each named after its key ID (kid):
+// "<kid>.pem" holds an RSA or Ed25519 private key and "<kid>.pub.pem" a
+// public key that is only used to verify tokens signed before it was
+// retired. Tokens are signed with the active key and carry its kid, so keys
+// can be rotated by adding a new file and switching the active key while
+// older keys keep verifying the tokens already issued.
+//
+// Without a key directory tokens are signed with the legacy HS256 secret.
+package signing
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"cinema-booking-system/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key is a key tokens are signed or verified with
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	public  crypto.PublicKey
+	private crypto.PrivateKey // nil for verify-only keys
+}
+
+// KeySet holds the active signing key and every key accepted for verification
+type KeySet struct {
+	active       *Key
+	keys         map[string]*Key
+	legacySecret []byte
+	legacyUntil  time.Time
+}
+
+// Load reads the signing keys configured in cfg
+func Load(cfg config.JWTConfig) (*KeySet, error) {
+	set := &KeySet{
+		keys:         make(map[string]*Key),
+		legacySecret: []byte(cfg.Secret),
+		legacyUntil:  cfg.LegacyHS256Until,
+	}
+
+	if cfg.SigningKeysDir == "" {
+		if cfg.Secret == "" {
+			return nil, errors.New("JWT_SECRET is required when no signing keys are configured")
+		}
+		return set, nil
+	}
+
+	paths, err := filepath.Glob(filepath.Join(cfg.SigningKeysDir, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list signing keys: %w", err)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		key, err := loadKey(path)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := set.keys[key.ID]; exists {
+			return nil, fmt.Errorf("duplicate signing key id %q", key.ID)
+		}
+		set.keys[key.ID] = key
+
+		// Without an explicit choice the last private key by kid signs
+		if cfg.ActiveKeyID == "" && key.private != nil {
+			set.active = key
+		}
+	}
+
+	if cfg.ActiveKeyID != "" {
+		set.active = set.keys[cfg.ActiveKeyID]
+		if set.active == nil || set.active.private == nil {
+			return nil, fmt.Errorf("active signing key %q has no private key in %s", cfg.ActiveKeyID, cfg.SigningKeysDir)
+		}
+	}
+	if set.active == nil {
+		return nil, fmt.Errorf("no private signing key found in %s", cfg.SigningKeysDir)
+	}
+
+	return set, nil
+}
+
+// ActiveKeyID returns the kid new tokens are signed with, or an empty string
+// when signing with the legacy HS256 secret
+func (s *KeySet) ActiveKeyID() string {
+	if s.active == nil {
+		return ""
+	}
+	return s.active.ID
+}
+
+// Sign signs claims with the active key
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	if s.active == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.legacySecret)
+	}
+
+	token := jwt.NewWithClaims(s.active.Method, claims)
+	token.Header["kid"] = s.active.ID
+	return token.SignedString(s.active.private)
+}
+
+// Parse verifies a token with the key named by its kid, or with the legacy
+// secret for HS256 tokens while those are still accepted, and decodes its
+// claims into claims
+func (s *KeySet) Parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, s.keyFunc,
+		jwt.WithValidMethods([]string{
+			jwt.SigningMethodHS256.Alg(),
+			jwt.SigningMethodRS256.Alg(),
+			jwt.SigningMethodEdDSA.Alg(),
+		}))
+}
+
+// keyFunc selects the verification key for a token
+func (s *KeySet) keyFunc(token *jwt.Token) (interface{}, error) {
+	if _, ok := token.Method.(*jwt.SigningMethodHMAC); ok {
+		if !s.acceptsLegacy() {
+			return nil, errors.New("legacy HS256 tokens are no longer accepted")
+		}
+		return s.legacySecret, nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, fmt.Errorf("signing method %s does not match key %q", token.Method.Alg(), kid)
+	}
+
+	return key.public, nil
+}
+
+// acceptsLegacy reports whether HS256 tokens are still valid: always while
+// they are the only kind issued, and until the migration window ends after
+// switching to asymmetric keys
+func (s *KeySet) acceptsLegacy() bool {
+	if s.active == nil {
+		return true
+	}
+	return len(s.legacySecret) > 0 && time.Now().Before(s.legacyUntil)
+}
+
+// loadKey parses a PEM key file named after its kid
+func loadKey(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read signing key %s: %w", path, err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("signing key %s is not PEM encoded", path)
+	}
+
+	name := strings.TrimSuffix(filepath.Base(path), ".pem")
+	key := &Key{ID: strings.TrimSuffix(name, ".pub")}
+
+	var parsed interface{}
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("signing key %s has unsupported PEM type %q", path, block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse signing key %s: %w", path, err)
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.Method, key.private, key.public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		key.Method, key.public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		key.Method, key.private, key.public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		key.Method, key.public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("signing key %s must be an RSA or Ed25519 key", path)
+	}
+
+	if rsaKey, ok := key.public.(*rsa.PublicKey); ok && rsaKey.N.BitLen() < 2048 {
+		return nil, fmt.Errorf("signing key %s: RSA keys must be at least 2048 bits", path)
+	}
+
+	return key, nil
+}
@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"cinema-booking-system/internal/config"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// maxPasswordBytes is the longest password bcrypt can hash
+const maxPasswordBytes = 72
+
+// minPersonalInfoLength is the shortest username or email part that is
+// checked for inside a password; shorter ones match too much by accident
+const minPersonalInfoLength = 3
+
+// PasswordPolicy holds the rules new passwords must satisfy
+type PasswordPolicy struct {
+	config.PasswordConfig
+	breached map[string]struct{}
+}
+
+// LoadPasswordPolicy builds the password policy from configuration, reading
+// the breached password list if one is configured
+func LoadPasswordPolicy(cfg config.PasswordConfig) (*PasswordPolicy, error) {
+	policy := &PasswordPolicy{PasswordConfig: cfg}
+
+	if cfg.BreachedListFile == "" {
+		return policy, nil
+	}
+
+	file, err := os.Open(cfg.BreachedListFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open breached password list: %w", err)
+	}
+	defer file.Close()
+
+	policy.breached = make(map[string]struct{})
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		policy.breached[strings.ToLower(line)] = struct{}{}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read breached password list: %w", err)
+	}
+
+	return policy, nil
+}
+
+// BreachedCount returns how many passwords are on the breached list
+func (p *PasswordPolicy) BreachedCount() int {
+	return len(p.breached)
+}
+
+// tags returns the validation tags the "password" alias expands to
+func (p *PasswordPolicy) tags() string {
+	tags := []string{
+		fmt.Sprintf("min=%d", p.MinLength),
+		fmt.Sprintf("password_bytes=%d", maxPasswordBytes),
+	}
+	if p.RequireUpper {
+		tags = append(tags, "password_upper")
+	}
+	if p.RequireLower {
+		tags = append(tags, "password_lower")
+	}
+	if p.RequireDigit {
+		tags = append(tags, "password_digit")
+	}
+	if p.RequireSymbol {
+		tags = append(tags, "password_symbol")
+	}
+	if p.DisallowPersonalInfo {
+		tags = append(tags, "password_personal")
+	}
+	if p.breached != nil {
+		tags = append(tags, "password_breached")
+	}
+
+	return strings.Join(tags, ",")
+}
+
+// register adds the password rules to v under the "password" tag
+func (p *PasswordPolicy) register(v *validator.Validate) {
+	v.RegisterValidation("password_upper", hasRune(unicode.IsUpper))
+	v.RegisterValidation("password_lower", hasRune(unicode.IsLower))
+	v.RegisterValidation("password_digit", hasRune(unicode.IsDigit))
+	v.RegisterValidation("password_symbol", hasRune(func(r rune) bool {
+		return unicode.IsPunct(r) || unicode.IsSymbol(r)
+	}))
+	v.RegisterValidation("password_bytes", validatePasswordBytes)
+	v.RegisterValidation("password_personal", validatePasswordPersonal)
+	v.RegisterValidation("password_breached", func(fl validator.FieldLevel) bool {
+		_, found := p.breached[strings.ToLower(fl.Field().String())]
+		return !found
+	})
+
+	v.RegisterAlias("password", p.tags())
+}
+
+// hasRune returns a validation func that passes when any rune matches
+func hasRune(match func(rune) bool) validator.Func {
+	return func(fl validator.FieldLevel) bool {
+		return strings.IndexFunc(fl.Field().String(), match) >= 0
+	}
+}
+
+// validatePasswordBytes passes a password no longer than the rule's
+// parameter in bytes. The built-in max rule counts runes, which lets
+// multibyte passwords past the limit bcrypt accepts.
+func validatePasswordBytes(fl validator.FieldLevel) bool {
+	limit, err := strconv.Atoi(fl.Param())
+	if err != nil {
+		return false
+	}
+	return len(fl.Field().String()) <= limit
+}
+
+// validatePasswordPersonal rejects a password that contains the Username or
+// Email of the struct it belongs to
+func validatePasswordPersonal(fl validator.FieldLevel) bool {
+	parent := fl.Parent()
+	if parent.Kind() != reflect.Struct {
+		return true
+	}
+
+	var personal []string
+	for _, name := range []string{"Username", "Email"} {
+		if field := parent.FieldByName(name); field.IsValid() && field.Kind() == fl.Field().Kind() {
+			personal = append(personal, field.String())
+		}
+	}
+
+	return !PasswordContainsPersonalInfo(fl.Field().String(), personal...)
+}
+
+// PasswordContainsPersonalInfo reports whether password contains any of the
+// given usernames or email addresses, or the local part of an email,
+// ignoring case
+func PasswordContainsPersonalInfo(password string, values ...string) bool {
+	password = strings.ToLower(password)
+
+	for _, value := range values {
+		value = strings.ToLower(strings.TrimSpace(value))
+		candidates := []string{value}
+		if local, _, found := strings.Cut(value, "@"); found {
+			candidates = append(candidates, local)
+		}
+
+		for _, candidate := range candidates {
+			if len(candidate) >= minPersonalInfoLength && strings.Contains(password, candidate) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
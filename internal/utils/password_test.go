@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"cinema-booking-system/internal/config"
+	"cinema-booking-system/internal/dto"
+	"cinema-booking-system/internal/i18n"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newTestValidator returns a validator with a minimal password policy
+func newTestValidator(t *testing.T) *Validator {
+	t.Helper()
+
+	policy, err := LoadPasswordPolicy(config.PasswordConfig{MinLength: 8})
+	if err != nil {
+		t.Fatalf("failed to load password policy: %v", err)
+	}
+	v, err := NewValidator(policy, 1<<20)
+	if err != nil {
+		t.Fatalf("failed to create validator: %v", err)
+	}
+	return v
+}
+
+func TestPasswordLimitCountsBytes(t *testing.T) {
+	v := newTestValidator(t)
+
+	tests := []struct {
+		name     string
+		password string
+		wantErr  bool
+	}{
+		{"ascii at limit", strings.Repeat("a", maxPasswordBytes), false},
+		{"ascii over limit", strings.Repeat("a", maxPasswordBytes+1), true},
+		// 30 runes but 90 bytes: short enough for max=72, too long for bcrypt
+		{"multibyte over limit", strings.Repeat("密", 30), true},
+		{"multibyte at limit", strings.Repeat("密", maxPasswordBytes/3), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Validate(&dto.RegisterRequest{
+				Username: "moviegoer",
+				Email:    "moviegoer@example.com",
+				Password: tt.password,
+			})
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("Validate(%d runes, %d bytes) = %v, want nil",
+						utf8.RuneCountInString(tt.password), len(tt.password), err)
+				}
+				if _, err := bcrypt.GenerateFromPassword([]byte(tt.password), bcrypt.MinCost); err != nil {
+					t.Fatalf("bcrypt rejected a password that passed validation: %v", err)
+				}
+				return
+			}
+
+			var validationErrs *ValidationErrors
+			if !errors.As(err, &validationErrs) {
+				t.Fatalf("Validate(%d runes, %d bytes) = %v, want *ValidationErrors",
+					utf8.RuneCountInString(tt.password), len(tt.password), err)
+			}
+			fields := validationErrs.Fields(i18n.English)
+			if len(fields) != 1 || fields[0].Field != "password" || fields[0].Rule != "password_bytes" {
+				t.Fatalf("Fields() = %+v, want a single password_bytes error on password", fields)
+			}
+			if want := "password must be at most 72 bytes long"; fields[0].Message != want {
+				t.Errorf("Message = %q, want %q", fields[0].Message, want)
+			}
+		})
+	}
+}
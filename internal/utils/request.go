@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strings"
+
+	"cinema-booking-system/internal/apperror"
+)
+
+// ClientIP returns the client address of a request without the port. The
+// RealIP middleware has already replaced RemoteAddr with the forwarded client
+// address when the request came through a trusted proxy.
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// DecodeAndValidate decodes the JSON body of r into a new T and validates it.
+// The body must be sent as application/json, must not be larger than the
+// validator's body limit and must not contain fields T does not declare.
+// Failures are returned as apperror errors or *ValidationErrors, ready for
+// RespondWithAppError.
+func DecodeAndValidate[T any](w http.ResponseWriter, r *http.Request, v *Validator) (*T, error) {
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		return nil, apperror.UnsupportedType("Content-Type must be application/json")
+	}
+
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, v.maxBodyBytes))
+	decoder.DisallowUnknownFields()
+
+	req := new(T)
+	if err := decoder.Decode(req); err != nil {
+		return nil, decodeError(err)
+	}
+
+	// Anything after the first JSON value is rejected too
+	if err := decoder.Decode(&struct{}{}); err != io.EOF {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, decodeError(err)
+		}
+		return nil, apperror.BadRequest("request body must contain a single JSON object")
+	}
+
+	if err := v.Validate(req); err != nil {
+		return nil, err
+	}
+
+	return req, nil
+}
+
+// decodeError describes why a request body could not be decoded
+func decodeError(err error) error {
+	var maxBytesErr *http.MaxBytesError
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return apperror.Newf(apperror.CodeTooLarge, "request body must not exceed %d bytes", maxBytesErr.Limit)
+	case errors.Is(err, io.EOF):
+		return apperror.BadRequest("request body is empty")
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return apperror.BadRequest("request body contains malformed JSON")
+	case errors.As(err, &typeErr):
+		return apperror.Newf(apperror.CodeBadRequest, "field %s must be of type %s", typeErr.Field, typeErr.Type)
+	}
+
+	// DisallowUnknownFields reports unknown fields with a plain error
+	if field, found := strings.CutPrefix(err.Error(), "json: unknown field "); found {
+		return apperror.Newf(apperror.CodeBadRequest, "request body contains unknown field %s", field)
+	}
+
+	return apperror.BadRequest("Invalid request body")
+}
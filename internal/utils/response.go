@@ -2,11 +2,52 @@ package utils
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
+	"cinema-booking-system/internal/apperror"
 	"cinema-booking-system/internal/dto"
+	"cinema-booking-system/internal/i18n"
 )
 
+// errorStatuses maps each error code to the HTTP status it is sent with
+var errorStatuses = map[apperror.Code]int{
+	apperror.CodeBadRequest:      http.StatusBadRequest,
+	apperror.CodeValidation:      http.StatusBadRequest,
+	apperror.CodeUnauthorized:    http.StatusUnauthorized,
+	apperror.CodePaymentDeclined: http.StatusPaymentRequired,
+	apperror.CodeForbidden:       http.StatusForbidden,
+	apperror.CodeNotFound:        http.StatusNotFound,
+	apperror.CodeConflict:        http.StatusConflict,
+	apperror.CodeTooLarge:        http.StatusRequestEntityTooLarge,
+	apperror.CodeUnsupportedType: http.StatusUnsupportedMediaType,
+	apperror.CodeUnprocessable:   http.StatusUnprocessableEntity,
+	apperror.CodeRateLimited:     http.StatusTooManyRequests,
+	apperror.CodeInternal:        http.StatusInternalServerError,
+	apperror.CodeUnavailable:     http.StatusServiceUnavailable,
+	apperror.CodeTimeout:         http.StatusGatewayTimeout,
+}
+
+// statusCodes maps HTTP statuses back to the code used when a handler
+// responds with a status directly
+var statusCodes = map[int]apperror.Code{
+	http.StatusBadRequest:            apperror.CodeBadRequest,
+	http.StatusUnauthorized:          apperror.CodeUnauthorized,
+	http.StatusPaymentRequired:       apperror.CodePaymentDeclined,
+	http.StatusForbidden:             apperror.CodeForbidden,
+	http.StatusNotFound:              apperror.CodeNotFound,
+	http.StatusConflict:              apperror.CodeConflict,
+	http.StatusRequestEntityTooLarge: apperror.CodeTooLarge,
+	http.StatusUnsupportedMediaType:  apperror.CodeUnsupportedType,
+	http.StatusUnprocessableEntity:   apperror.CodeUnprocessable,
+	http.StatusTooManyRequests:       apperror.CodeRateLimited,
+	http.StatusInternalServerError:   apperror.CodeInternal,
+	http.StatusServiceUnavailable:    apperror.CodeUnavailable,
+	http.StatusGatewayTimeout:        apperror.CodeTimeout,
+}
+
 // RespondWithJSON writes a JSON response
 func RespondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	w.Header().Set("Content-Type", "application/json")
@@ -14,31 +55,88 @@ func RespondWithJSON(w http.ResponseWriter, code int, payload interface{}) {
 	json.NewEncoder(w).Encode(payload)
 }
 
-// RespondWithSuccess sends a successful response
-func RespondWithSuccess(w http.ResponseWriter, code int, data interface{}, message string) {
+// RespondWithSuccess sends a successful response with the message in the
+// request's locale
+func RespondWithSuccess(w http.ResponseWriter, r *http.Request, code int, data interface{}, message string) {
 	response := dto.Response{
 		Success: true,
-		Message: message,
+		Message: translate(r, message),
 		Data:    data,
 	}
 	RespondWithJSON(w, code, response)
 }
 
-// RespondWithError sends an error response
-func RespondWithError(w http.ResponseWriter, code int, message string) {
+// RespondWithError sends an error response with the code matching the status
+// and the message in the request's locale
+func RespondWithError(w http.ResponseWriter, r *http.Request, code int, message string) {
+	errorCode, ok := statusCodes[code]
+	if !ok {
+		errorCode = apperror.CodeInternal
+	}
+
 	response := dto.ErrorResponse{
 		Success: false,
-		Error:   message,
+		Code:    string(errorCode),
+		Error:   translate(r, message),
 	}
 	RespondWithJSON(w, code, response)
 }
 
-// RespondWithValidationError sends a validation error response
-func RespondWithValidationError(w http.ResponseWriter, err error) {
+// RespondWithAppError sends the response for an error returned by a service
+// or by DecodeAndValidate. Errors without an apperror code are unexpected, so
+// their text is not shown.
+func RespondWithAppError(w http.ResponseWriter, r *http.Request, err error) {
+	var validationErrors *ValidationErrors
+	if errors.As(err, &validationErrors) {
+		RespondWithValidationError(w, r, err)
+		return
+	}
+
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) {
+		RespondWithError(w, r, http.StatusInternalServerError, "Internal server error")
+		return
+	}
+
+	status, ok := errorStatuses[appErr.Code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+
+	if appErr.RetryAfter > 0 {
+		seconds := int(math.Ceil(appErr.RetryAfter.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+	}
+
+	response := dto.ErrorResponse{
+		Success: false,
+		Code:    string(appErr.Code),
+		Error:   translate(r, appErr.Message, appErr.Args...),
+	}
+	RespondWithJSON(w, status, response)
+}
+
+// RespondWithValidationError sends a validation error response listing each
+// failed field when err is a *ValidationErrors
+func RespondWithValidationError(w http.ResponseWriter, r *http.Request, err error) {
 	response := dto.ErrorResponse{
 		Success: false,
-		Error:   "Validation failed",
+		Code:    string(apperror.CodeValidation),
+		Error:   translate(r, "Validation failed"),
 		Message: err.Error(),
 	}
+
+	var validationErrors *ValidationErrors
+	if errors.As(err, &validationErrors) {
+		locale := i18n.FromContext(r.Context())
+		response.Fields = validationErrors.Fields(locale)
+		response.Message = validationErrors.Message(locale)
+	}
+
 	RespondWithJSON(w, http.StatusBadRequest, response)
 }
+
+// translate returns message in the locale negotiated for the request
+func translate(r *http.Request, message string, args ...interface{}) string {
+	return i18n.T(i18n.FromContext(r.Context()), message, args...)
+}
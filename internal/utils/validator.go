@@ -2,57 +2,228 @@ package utils
 
 import (
 	"fmt"
+	"reflect"
 	"strings"
+	"time"
 
+	"cinema-booking-system/internal/dto"
+	"cinema-booking-system/internal/i18n"
+	"cinema-booking-system/internal/models"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/id"
+	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
+	entranslations "github.com/go-playground/validator/v10/translations/en"
+	idtranslations "github.com/go-playground/validator/v10/translations/id"
 )
 
+// dateLayout is the format of date fields such as a showtime's date
+const dateLayout = "2006-01-02"
+
+// layoutNames describes datetime layouts in the notation clients know
+var layoutNames = map[string]string{
+	dateLayout: "YYYY-MM-DD",
+	"15:04":    "HH:MM",
+}
+
+// customTranslations holds the messages of the rules this package adds and
+// of the built-in rules whose default message reads poorly to clients
+var customTranslations = map[string]map[string]string{
+	i18n.English: {
+		"datetime":          "{0} must be in the format {1}",
+		"not_past_date":     "{0} must not be in the past",
+		"locale":            "{0} must be one of: {1}",
+		"not_reserved":      "{0} must not start with {1}",
+		"password_bytes":    "{0} must be at most {1} bytes long",
+		"password_upper":    "{0} must contain an uppercase letter",
+		"password_lower":    "{0} must contain a lowercase letter",
+		"password_digit":    "{0} must contain a digit",
+		"password_symbol":   "{0} must contain a symbol",
+		"password_personal": "{0} must not contain your username or email",
+		"password_breached": "{0} is too common or has appeared in a data breach, choose another",
+	},
+	i18n.Indonesian: {
+		"datetime":          "{0} harus menggunakan format {1}",
+		"not_past_date":     "{0} tidak boleh di masa lalu",
+		"locale":            "{0} harus salah satu dari: {1}",
+		"not_reserved":      "{0} tidak boleh diawali {1}",
+		"password_bytes":    "{0} maksimal {1} byte",
+		"password_upper":    "{0} harus mengandung huruf besar",
+		"password_lower":    "{0} harus mengandung huruf kecil",
+		"password_digit":    "{0} harus mengandung angka",
+		"password_symbol":   "{0} harus mengandung simbol",
+		"password_personal": "{0} tidak boleh mengandung username atau email Anda",
+		"password_breached": "{0} terlalu umum atau pernah bocor dalam pelanggaran data, pilih yang lain",
+	},
+}
+
 // Validator wraps the go-playground validator
 type Validator struct {
-	validate *validator.Validate
+	validate    *validator.Validate
+	translators *ut.UniversalTranslator
+
+	// maxBodyBytes caps the request bodies DecodeAndValidate reads
+	maxBodyBytes int64
 }
 
-// NewValidator creates a new validator instance
-func NewValidator() *Validator {
-	return &Validator{
-		validate: validator.New(),
+// ValidationErrors lists every field of a request that failed validation.
+// Its messages are rendered in a locale when the response is written.
+type ValidationErrors struct {
+	errs        validator.ValidationErrors
+	translators *ut.UniversalTranslator
+}
+
+// Error joins the English messages of all failed fields
+func (e *ValidationErrors) Error() string {
+	return e.Message(i18n.English)
+}
+
+// Message joins the messages of all failed fields in locale
+func (e *ValidationErrors) Message(locale string) string {
+	fields := e.Fields(locale)
+	messages := make([]string, len(fields))
+	for i, field := range fields {
+		messages[i] = field.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Fields describes each failed field with its message in locale
+func (e *ValidationErrors) Fields(locale string) []dto.FieldError {
+	trans, _ := e.translators.GetTranslator(locale)
+
+	fields := make([]dto.FieldError, 0, len(e.errs))
+	for _, fieldErr := range e.errs {
+		fields = append(fields, dto.FieldError{
+			Field:   fieldPath(fieldErr),
+			Rule:    fieldErr.ActualTag(),
+			Param:   fieldErr.Param(),
+			Message: fieldErr.Translate(trans),
+		})
+	}
+	return fields
+}
+
+// NewValidator creates a new validator instance. The password policy is
+// available to request structs as the "password" tag, and request bodies
+// decoded with DecodeAndValidate are limited to maxBodyBytes.
+func NewValidator(policy *PasswordPolicy, maxBodyBytes int64) (*Validator, error) {
+	validate := validator.New()
+
+	// Report fields by the names clients send
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+
+	validate.RegisterValidation("not_past_date", validateNotPastDate)
+	validate.RegisterValidation("locale", validateLocale)
+	validate.RegisterValidation("not_reserved", validateNotReserved)
+	policy.register(validate)
+
+	translators := ut.New(en.New(), en.New(), id.New())
+	defaults := map[string]func(*validator.Validate, ut.Translator) error{
+		i18n.English:    entranslations.RegisterDefaultTranslations,
+		i18n.Indonesian: idtranslations.RegisterDefaultTranslations,
+	}
+	for locale, registerDefaults := range defaults {
+		trans, _ := translators.GetTranslator(locale)
+		if err := registerDefaults(validate, trans); err != nil {
+			return nil, fmt.Errorf("failed to register %s validation messages: %w", locale, err)
+		}
+
+		for tag, message := range customTranslations[locale] {
+			if err := validate.RegisterTranslation(tag, trans, addTranslation(tag, message), translateCustom); err != nil {
+				return nil, fmt.Errorf("failed to register %s message for %s: %w", locale, tag, err)
+			}
+		}
 	}
+
+	return &Validator{
+		validate:     validate,
+		translators:  translators,
+		maxBodyBytes: maxBodyBytes,
+	}, nil
 }
 
-// Validate validates a struct
+// Validate validates a struct. Failed fields are returned as *ValidationErrors.
 func (v *Validator) Validate(i interface{}) error {
 	if err := v.validate.Struct(i); err != nil {
-		return v.formatValidationError(err)
+		validationErrors, ok := err.(validator.ValidationErrors)
+		if !ok {
+			return err
+		}
+		return &ValidationErrors{errs: validationErrors, translators: v.translators}
 	}
 	return nil
 }
 
-// formatValidationError formats validation errors into a readable message
-func (v *Validator) formatValidationError(err error) error {
-	if validationErrors, ok := err.(validator.ValidationErrors); ok {
-		var messages []string
-		for _, e := range validationErrors {
-			messages = append(messages, v.formatFieldError(e))
-		}
-		return fmt.Errorf(strings.Join(messages, "; "))
+// fieldPath returns the JSON path of a failed field, e.g. "rows[0].from_row"
+func fieldPath(e validator.FieldError) string {
+	// The namespace starts with the name of the validated struct type
+	_, path, found := strings.Cut(e.Namespace(), ".")
+	if !found {
+		return e.Field()
+	}
+	return path
+}
+
+// addTranslation registers message under tag, replacing any default
+func addTranslation(tag, message string) validator.RegisterTranslationsFunc {
+	return func(trans ut.Translator) error {
+		return trans.Add(tag, message, true)
 	}
-	return err
 }
 
-// formatFieldError formats a single field error
-func (v *Validator) formatFieldError(e validator.FieldError) string {
-	field := strings.ToLower(e.Field())
+// translateCustom renders a message from customTranslations. It looks the
+// message up by ActualTag so rules inside an alias such as "password" are
+// found too.
+func translateCustom(trans ut.Translator, e validator.FieldError) string {
+	param := e.Param()
+	switch e.ActualTag() {
+	case "datetime":
+		if name, ok := layoutNames[param]; ok {
+			param = name
+		}
+	case "locale":
+		param = strings.Join(i18n.Supported, ", ")
+	case "not_reserved":
+		param = models.DeletedUsernamePrefix
+	}
+
+	message, err := trans.T(e.ActualTag(), e.Field(), param)
+	if err != nil {
+		return e.Error()
+	}
+	return message
+}
 
-	switch e.Tag() {
-	case "required":
-		return fmt.Sprintf("%s is required", field)
-	case "email":
-		return fmt.Sprintf("%s must be a valid email address", field)
-	case "min":
-		return fmt.Sprintf("%s must be at least %s characters long", field, e.Param())
-	case "max":
-		return fmt.Sprintf("%s must not exceed %s characters", field, e.Param())
-	default:
-		return fmt.Sprintf("%s is invalid", field)
+// validateNotPastDate passes a YYYY-MM-DD date that is today or later.
+// Malformed dates pass so that the datetime rule reports them.
+func validateNotPastDate(fl validator.FieldLevel) bool {
+	date, err := time.ParseInLocation(dateLayout, fl.Field().String(), time.Local)
+	if err != nil {
+		return true
 	}
+
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+	return !date.Before(today)
+}
+
+// validateLocale passes a supported locale, or an empty string to clear a
+// preference
+func validateLocale(fl validator.FieldLevel) bool {
+	locale := fl.Field().String()
+	return locale == "" || i18n.IsSupported(locale)
+}
+
+// validateNotReserved rejects usernames that anonymised accounts are renamed
+// to, in any letter case
+func validateNotReserved(fl validator.FieldLevel) bool {
+	return !strings.HasPrefix(strings.ToLower(fl.Field().String()), models.DeletedUsernamePrefix)
 }
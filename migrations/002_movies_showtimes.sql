@@ -0,0 +1,102 @@
+-- Create movies table
+CREATE TABLE IF NOT EXISTS movies (
+    id SERIAL PRIMARY KEY,
+    title VARCHAR(200) NOT NULL,
+    description TEXT,
+    duration_minutes INTEGER NOT NULL CHECK (duration_minutes > 0),
+    rating VARCHAR(10) NOT NULL DEFAULT 'SU',
+    language VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create showtimes table
+CREATE TABLE IF NOT EXISTS showtimes (
+    id SERIAL PRIMARY KEY,
+    movie_id INTEGER NOT NULL REFERENCES movies(id) ON DELETE CASCADE,
+    cinema_id INTEGER NOT NULL REFERENCES cinemas(id) ON DELETE CASCADE,
+    start_time TIMESTAMP NOT NULL,
+    end_time TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (end_time > start_time)
+);
+
+-- Link bookings to showtimes
+ALTER TABLE bookings ADD COLUMN showtime_id INTEGER REFERENCES showtimes(id) ON DELETE CASCADE;
+
+-- Move existing bookings onto placeholder showtimes so no history is lost
+DO $$
+DECLARE
+    legacy_movie_id INTEGER;
+BEGIN
+    IF EXISTS (SELECT 1 FROM bookings) THEN
+        INSERT INTO movies (title, description, duration_minutes, rating, language)
+        VALUES ('Legacy Screening', 'Placeholder for bookings made before showtimes existed', 120, 'SU', 'Indonesia')
+        RETURNING id INTO legacy_movie_id;
+
+        INSERT INTO showtimes (movie_id, cinema_id, start_time, end_time)
+        SELECT DISTINCT
+            legacy_movie_id,
+            cinema_id,
+            booking_date + booking_time,
+            booking_date + booking_time + INTERVAL '120 minutes'
+        FROM bookings;
+
+        UPDATE bookings b
+        SET showtime_id = st.id
+        FROM showtimes st
+        WHERE st.movie_id = legacy_movie_id
+          AND st.cinema_id = b.cinema_id
+          AND st.start_time = b.booking_date + b.booking_time;
+    END IF;
+END $$;
+
+ALTER TABLE bookings ALTER COLUMN showtime_id SET NOT NULL;
+ALTER TABLE bookings DROP CONSTRAINT IF EXISTS bookings_cinema_id_seat_id_booking_date_booking_time_key;
+DROP INDEX IF EXISTS idx_bookings_cinema_date_time;
+ALTER TABLE bookings DROP COLUMN booking_date;
+ALTER TABLE bookings DROP COLUMN booking_time;
+ALTER TABLE bookings ADD CONSTRAINT bookings_showtime_id_seat_id_key UNIQUE (showtime_id, seat_id);
+
+-- Create indexes for better performance
+CREATE INDEX idx_showtimes_cinema_start ON showtimes(cinema_id, start_time);
+CREATE INDEX idx_showtimes_movie_start ON showtimes(movie_id, start_time);
+CREATE INDEX idx_bookings_showtime_id ON bookings(showtime_id);
+
+-- Insert sample movies
+INSERT INTO movies (title, description, duration_minutes, rating, language) VALUES
+('Pengabdi Setan 2: Communion', 'Horror sequel set in a Jakarta apartment block', 119, '17+', 'Indonesia'),
+('Agak Laen', 'Comedy about four friends running a haunted house attraction', 119, '13+', 'Indonesia'),
+('Jumbo', 'Animated adventure about a boy and his magical storybook', 102, 'SU', 'Indonesia'),
+('Dune: Part Two', 'Paul Atreides unites with the Fremen', 166, '13+', 'English');
+
+-- Insert sample showtimes for the next 7 days
+DO $$
+DECLARE
+    day_offset INTEGER;
+    slot TIME;
+    cinema RECORD;
+    movie RECORD;
+BEGIN
+    FOR day_offset IN 0..6 LOOP
+        FOR cinema IN SELECT id FROM cinemas ORDER BY id LOOP
+            FOR slot IN SELECT unnest(ARRAY['13:00', '16:30', '20:00']::TIME[]) LOOP
+                SELECT id, duration_minutes INTO movie
+                FROM movies
+                WHERE title <> 'Legacy Screening'
+                ORDER BY id
+                OFFSET (cinema.id + day_offset) % 4
+                LIMIT 1;
+
+                INSERT INTO showtimes (movie_id, cinema_id, start_time, end_time)
+                VALUES (
+                    movie.id,
+                    cinema.id,
+                    CURRENT_DATE + day_offset + slot,
+                    CURRENT_DATE + day_offset + slot + make_interval(mins => movie.duration_minutes)
+                );
+            END LOOP;
+        END LOOP;
+    END LOOP;
+END $$;
@@ -0,0 +1,22 @@
+-- Create booking_seats table (one row per seat in a booking)
+CREATE TABLE IF NOT EXISTS booking_seats (
+    id SERIAL PRIMARY KEY,
+    booking_id INTEGER NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    showtime_id INTEGER NOT NULL REFERENCES showtimes(id) ON DELETE CASCADE,
+    seat_id INTEGER NOT NULL REFERENCES seats(id) ON DELETE CASCADE,
+    price DECIMAL(10, 2) NOT NULL,
+    UNIQUE(booking_id, seat_id)
+);
+
+-- Move existing single-seat bookings into line items
+INSERT INTO booking_seats (booking_id, showtime_id, seat_id, price)
+SELECT id, showtime_id, seat_id, total_amount
+FROM bookings;
+
+-- Bookings now act as the parent order; seats live in booking_seats
+ALTER TABLE bookings DROP CONSTRAINT IF EXISTS bookings_showtime_id_seat_id_key;
+ALTER TABLE bookings DROP COLUMN seat_id;
+
+-- Create indexes for better performance
+CREATE INDEX idx_booking_seats_showtime_seat ON booking_seats(showtime_id, seat_id);
+CREATE INDEX idx_booking_seats_booking_id ON booking_seats(booking_id);
@@ -0,0 +1,30 @@
+-- Track whether a booked seat is still held so a partial unique index can
+-- guarantee a seat is never held twice for the same showtime, while still
+-- allowing it to be booked again after a cancellation.
+ALTER TABLE booking_seats ADD COLUMN is_active BOOLEAN NOT NULL DEFAULT true;
+
+UPDATE booking_seats bs
+SET is_active = false
+FROM bookings b
+WHERE bs.booking_id = b.id
+  AND b.booking_status NOT IN ('reserved', 'paid');
+
+CREATE UNIQUE INDEX uniq_booking_seats_active
+    ON booking_seats(showtime_id, seat_id)
+    WHERE is_active;
+
+-- Keep booking_seats.is_active in sync with the parent booking status
+CREATE OR REPLACE FUNCTION sync_booking_seats_active() RETURNS TRIGGER AS $$
+BEGIN
+    UPDATE booking_seats
+    SET is_active = NEW.booking_status IN ('reserved', 'paid')
+    WHERE booking_id = NEW.id;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER trg_bookings_sync_seats
+    AFTER UPDATE OF booking_status ON bookings
+    FOR EACH ROW
+    WHEN (OLD.booking_status IS DISTINCT FROM NEW.booking_status)
+    EXECUTE FUNCTION sync_booking_seats_active();
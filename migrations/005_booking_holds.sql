@@ -0,0 +1,10 @@
+-- Unpaid reservations only hold their seats until hold_expires_at
+ALTER TABLE bookings ADD COLUMN hold_expires_at TIMESTAMP;
+
+UPDATE bookings
+SET hold_expires_at = created_at + INTERVAL '15 minutes'
+WHERE booking_status = 'reserved' AND payment_status = 'pending';
+
+-- Create indexes for better performance
+CREATE INDEX idx_bookings_reserved_hold ON bookings(hold_expires_at)
+    WHERE booking_status = 'reserved';
@@ -0,0 +1,30 @@
+-- Each payment method is processed by a gateway provider
+ALTER TABLE payment_methods ADD COLUMN provider VARCHAR(50) NOT NULL DEFAULT 'mock';
+
+-- Create payments table recording every charge and refund attempt
+CREATE TABLE IF NOT EXISTS payments (
+    id SERIAL PRIMARY KEY,
+    booking_id INTEGER NOT NULL REFERENCES bookings(id) ON DELETE CASCADE,
+    payment_method_id INTEGER REFERENCES payment_methods(id),
+    provider VARCHAR(50) NOT NULL,
+    provider_reference VARCHAR(255),
+    type VARCHAR(20) NOT NULL CHECK (type IN ('charge', 'refund')),
+    amount DECIMAL(10, 2) NOT NULL,
+    status VARCHAR(20) NOT NULL,
+    reason VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Refunds are now payments of type 'refund'
+INSERT INTO payments (booking_id, payment_method_id, provider, type, amount, status, reason, created_at, updated_at)
+SELECT r.booking_id, b.payment_method_id, 'manual', 'refund', r.amount, 'succeeded', r.reason, r.created_at, r.created_at
+FROM refunds r
+INNER JOIN bookings b ON r.booking_id = b.id;
+
+DROP TABLE refunds;
+
+-- Create indexes for better performance
+CREATE INDEX idx_payments_booking_id ON payments(booking_id);
+CREATE UNIQUE INDEX uniq_payments_provider_reference ON payments(provider, provider_reference)
+    WHERE provider_reference IS NOT NULL;
@@ -0,0 +1,13 @@
+-- E-wallets and bank transfers are confirmed asynchronously via webhook
+UPDATE payment_methods SET provider = 'mock_async' WHERE name IN ('E-Wallet', 'Bank Transfer');
+
+-- Create payment_webhook_events table for idempotent webhook processing
+CREATE TABLE IF NOT EXISTS payment_webhook_events (
+    id SERIAL PRIMARY KEY,
+    provider VARCHAR(50) NOT NULL,
+    event_id VARCHAR(255) NOT NULL,
+    payload JSONB NOT NULL,
+    received_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    processed_at TIMESTAMP,
+    UNIQUE(provider, event_id)
+);
@@ -0,0 +1,14 @@
+-- Add role to users
+ALTER TABLE users ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'customer'
+    CHECK (role IN ('customer', 'staff', 'cinema_manager', 'admin'));
+
+-- Create cinema_managers table scoping managers to their cinemas
+CREATE TABLE IF NOT EXISTS cinema_managers (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    cinema_id INTEGER NOT NULL REFERENCES cinemas(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, cinema_id)
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_cinema_managers_cinema_id ON cinema_managers(cinema_id);
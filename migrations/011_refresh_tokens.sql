@@ -0,0 +1,16 @@
+-- A row in tokens is now a login session; expires_at is when the session
+-- (its refresh token chain) ends, while the access JWT carries its own expiry
+
+-- Create refresh_tokens table (one chain of rotated tokens per session)
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    token_id INTEGER NOT NULL REFERENCES tokens(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash CHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_refresh_tokens_token_id ON refresh_tokens(token_id);
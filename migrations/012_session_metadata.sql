@@ -0,0 +1,7 @@
+-- Store only SHA-256 hashes of access tokens
+UPDATE tokens SET token = encode(sha256(token::bytea), 'hex');
+ALTER TABLE tokens ALTER COLUMN token TYPE VARCHAR(64);
+
+-- Capture where each session was created
+ALTER TABLE tokens ADD COLUMN user_agent TEXT;
+ALTER TABLE tokens ADD COLUMN ip_address VARCHAR(45);
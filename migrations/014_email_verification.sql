@@ -0,0 +1,19 @@
+-- Track when a user's email address was confirmed
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMP;
+
+-- Accounts created before verification existed are trusted as-is
+UPDATE users SET email_verified_at = created_at;
+
+-- Create email_verification_tokens table; email is the address being confirmed
+CREATE TABLE IF NOT EXISTS email_verification_tokens (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email VARCHAR(100) NOT NULL,
+    token_hash CHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes for better performance
+CREATE INDEX idx_email_verification_tokens_user_id ON email_verification_tokens(user_id);
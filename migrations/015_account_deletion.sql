@@ -0,0 +1,9 @@
+-- Deleted accounts are anonymised instead of removed so their bookings and
+-- payments remain available for accounting
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMP;
+
+-- Refuse hard deletes of users that still own bookings
+ALTER TABLE bookings DROP CONSTRAINT bookings_user_id_fkey;
+ALTER TABLE bookings
+    ADD CONSTRAINT bookings_user_id_fkey
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
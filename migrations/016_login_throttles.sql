@@ -0,0 +1,9 @@
+-- Create login_throttles table tracking failed logins per username and per IP
+CREATE TABLE IF NOT EXISTS login_throttles (
+    scope VARCHAR(10) NOT NULL CHECK (scope IN ('username', 'ip')),
+    subject VARCHAR(100) NOT NULL,
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    locked_until TIMESTAMP,
+    last_failed_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (scope, subject)
+);
@@ -0,0 +1,17 @@
+-- Optional TOTP two-factor authentication. The secret is stored while
+-- enrolment is pending and totp_enabled_at is set once the user confirms it.
+ALTER TABLE users ADD COLUMN totp_secret VARCHAR(64);
+ALTER TABLE users ADD COLUMN totp_enabled_at TIMESTAMP;
+
+-- Time step of the last accepted code, so a code cannot be replayed
+ALTER TABLE users ADD COLUMN totp_last_step BIGINT;
+
+-- Create recovery_codes table (single-use, hashed)
+CREATE TABLE IF NOT EXISTS recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash CHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, code_hash)
+);
@@ -0,0 +1,2 @@
+-- Preferred language of API responses; NULL follows Accept-Language
+ALTER TABLE users ADD COLUMN locale VARCHAR(5);
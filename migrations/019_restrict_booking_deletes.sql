@@ -0,0 +1,36 @@
+-- Booking and payment history is kept for accounting. Deleting a cinema,
+-- showtime, seat or booking must never remove it as a side effect.
+ALTER TABLE showtimes DROP CONSTRAINT showtimes_cinema_id_fkey;
+ALTER TABLE showtimes
+    ADD CONSTRAINT showtimes_cinema_id_fkey
+    FOREIGN KEY (cinema_id) REFERENCES cinemas(id) ON DELETE RESTRICT;
+
+ALTER TABLE bookings DROP CONSTRAINT bookings_cinema_id_fkey;
+ALTER TABLE bookings
+    ADD CONSTRAINT bookings_cinema_id_fkey
+    FOREIGN KEY (cinema_id) REFERENCES cinemas(id) ON DELETE RESTRICT;
+
+ALTER TABLE bookings DROP CONSTRAINT bookings_showtime_id_fkey;
+ALTER TABLE bookings
+    ADD CONSTRAINT bookings_showtime_id_fkey
+    FOREIGN KEY (showtime_id) REFERENCES showtimes(id) ON DELETE RESTRICT;
+
+ALTER TABLE booking_seats DROP CONSTRAINT booking_seats_booking_id_fkey;
+ALTER TABLE booking_seats
+    ADD CONSTRAINT booking_seats_booking_id_fkey
+    FOREIGN KEY (booking_id) REFERENCES bookings(id) ON DELETE RESTRICT;
+
+ALTER TABLE booking_seats DROP CONSTRAINT booking_seats_showtime_id_fkey;
+ALTER TABLE booking_seats
+    ADD CONSTRAINT booking_seats_showtime_id_fkey
+    FOREIGN KEY (showtime_id) REFERENCES showtimes(id) ON DELETE RESTRICT;
+
+ALTER TABLE booking_seats DROP CONSTRAINT booking_seats_seat_id_fkey;
+ALTER TABLE booking_seats
+    ADD CONSTRAINT booking_seats_seat_id_fkey
+    FOREIGN KEY (seat_id) REFERENCES seats(id) ON DELETE RESTRICT;
+
+ALTER TABLE payments DROP CONSTRAINT payments_booking_id_fkey;
+ALTER TABLE payments
+    ADD CONSTRAINT payments_booking_id_fkey
+    FOREIGN KEY (booking_id) REFERENCES bookings(id) ON DELETE RESTRICT;
@@ -0,0 +1,13 @@
+-- Record which charge paid each booking so any other succeeded charge for
+-- the same booking can be recognised as a duplicate and refunded
+ALTER TABLE bookings ADD COLUMN paid_payment_id INTEGER REFERENCES payments(id) ON DELETE RESTRICT;
+
+UPDATE bookings b
+SET paid_payment_id = (
+    SELECT p.id
+    FROM payments p
+    WHERE p.booking_id = b.id AND p.type = 'charge' AND p.status = 'succeeded'
+    ORDER BY p.created_at DESC
+    LIMIT 1
+)
+WHERE b.booking_status IN ('paid', 'cancelled');
@@ -0,0 +1,6 @@
+-- Store showtime start and end as instants. The existing values are wall
+-- clock times in the server's zone, which the cast reads in the session's
+-- TimeZone, so run this with TimeZone set to the zone the app runs in.
+ALTER TABLE showtimes
+    ALTER COLUMN start_time TYPE TIMESTAMPTZ,
+    ALTER COLUMN end_time TYPE TIMESTAMPTZ;
@@ -0,0 +1,4 @@
+-- Store the end of seat holds as an instant so it compares correctly with
+-- CURRENT_TIMESTAMP and with the app's clock. As in 021, the existing wall
+-- clock values are read in the session's TimeZone.
+ALTER TABLE bookings ALTER COLUMN hold_expires_at TYPE TIMESTAMPTZ;
@@ -0,0 +1,5 @@
+-- Store login lock and failure times as instants so that the lock handed
+-- back to the app compares correctly with its clock
+ALTER TABLE login_throttles
+    ALTER COLUMN locked_until TYPE TIMESTAMPTZ,
+    ALTER COLUMN last_failed_at TYPE TIMESTAMPTZ;
@@ -0,0 +1,12 @@
+-- Store session and refresh token times as instants. A session's created_at
+-- now also bounds how long refreshing can keep it alive, so it has to
+-- compare correctly with expiry times the app writes. As in 021, the
+-- existing wall clock values are read in the session's TimeZone.
+ALTER TABLE tokens
+    ALTER COLUMN expires_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE refresh_tokens
+    ALTER COLUMN expires_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN used_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ;
@@ -0,0 +1,7 @@
+-- Store password reset token times as instants so that the expiry the app
+-- writes compares correctly with the database clock. As in 021, the
+-- existing wall clock values are read in the session's TimeZone.
+ALTER TABLE password_reset_tokens
+    ALTER COLUMN expires_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN used_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ;
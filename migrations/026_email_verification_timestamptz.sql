@@ -0,0 +1,11 @@
+-- Store email verification times as instants. The resend throttle compares
+-- when the last token was created with the app's clock, and token expiry
+-- is written by the app. As in 021, the existing wall clock values are read
+-- in the session's TimeZone.
+ALTER TABLE email_verification_tokens
+    ALTER COLUMN expires_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN used_at TYPE TIMESTAMPTZ,
+    ALTER COLUMN created_at TYPE TIMESTAMPTZ;
+
+ALTER TABLE users
+    ALTER COLUMN email_verified_at TYPE TIMESTAMPTZ;
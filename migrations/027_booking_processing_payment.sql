@@ -0,0 +1,13 @@
+-- Record which charge a booking awaiting asynchronous payment is waiting
+-- for, so a late failure of an earlier charge cannot fail a newer one
+ALTER TABLE bookings ADD COLUMN processing_payment_id INTEGER REFERENCES payments(id) ON DELETE RESTRICT;
+
+UPDATE bookings b
+SET processing_payment_id = (
+    SELECT p.id
+    FROM payments p
+    WHERE p.booking_id = b.id AND p.type = 'charge' AND p.status = 'pending'
+    ORDER BY p.created_at DESC
+    LIMIT 1
+)
+WHERE b.payment_status = 'processing';
@@ -0,0 +1,89 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 seed of RFC 6238 appendix B, "12345678901234567890",
+// in base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// TestCodeRFC6238 checks the SHA-1 test vectors of RFC 6238 appendix B. The
+// RFC lists 8 digit codes; the last 6 digits are the 6 digit code.
+func TestCodeRFC6238(t *testing.T) {
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, Step(time.Unix(tt.unix, 0)))
+		if err != nil {
+			t.Fatalf("Code(%d) error = %v", tt.unix, err)
+		}
+		if got != tt.want {
+			t.Errorf("Code(%d) = %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateSkewWindow(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	current := Step(now)
+
+	codeAt := func(step int64) string {
+		t.Helper()
+		code, err := Code(rfcSecret, step)
+		if err != nil {
+			t.Fatalf("Code(%d) error = %v", step, err)
+		}
+		return code
+	}
+
+	tests := []struct {
+		name   string
+		offset int64
+		skew   int
+		wantOK bool
+	}{
+		{"current step", 0, 1, true},
+		{"one step behind", -1, 1, true},
+		{"one step ahead", 1, 1, true},
+		{"two steps behind", -2, 1, false},
+		{"two steps ahead", 2, 1, false},
+		{"one step behind without skew", -1, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, codeAt(current+tt.offset), now, tt.skew)
+			if ok != tt.wantOK {
+				t.Fatalf("Validate() ok = %v, want %v", ok, tt.wantOK)
+			}
+			if ok && step != current+tt.offset {
+				t.Errorf("Validate() step = %d, want %d", step, current+tt.offset)
+			}
+		})
+	}
+}
+
+func TestValidateRejectsMalformedCodes(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+
+	for _, code := range []string{"", "00592", "0005924", "abcdef"} {
+		if _, ok := Validate(rfcSecret, code, now, 1); ok {
+			t.Errorf("Validate(%q) ok = true, want false", code)
+		}
+	}
+	if _, ok := Validate("not base32!", "005924", now, 1); ok {
+		t.Error("Validate() with an invalid secret ok = true, want false")
+	}
+}